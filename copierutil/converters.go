@@ -1,6 +1,7 @@
 package copierutil
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/jinzhu/copier"
@@ -126,3 +127,61 @@ func NewErrorHandlingGenericTypeConverterPair[A interface{}, B interface{}](srcT
 		},
 	}
 }
+
+// IDCodec ID编解码器，例如 id.IDEncoder
+type IDCodec interface {
+	Encode(id uint64) string
+	Decode(s string) (uint64, error)
+}
+
+// NewIDCodecConverterPair 创建整数ID与编码字符串之间的转换器（包括指针类型），
+// 实体保留整数ID，DTO 使用编码后的字符串：
+//
+//	copier.CopyWithOption(&dto, &entity, copier.Option{
+//		Converters: copierutil.NewIDCodecConverterPair[uint32](encoder.ForEntity("user")),
+//	})
+//
+// copier 按类型匹配转换器，同一次复制中所有 T 到 string 的字段都会被编码。
+func NewIDCodecConverterPair[T ~uint32 | ~uint64 | ~int64](codec IDCodec) []copier.TypeConverter {
+	encode := func(src T) (string, error) {
+		if src < 0 {
+			return "", fmt.Errorf("cannot encode negative id %d", src)
+		}
+		return codec.Encode(uint64(src)), nil
+	}
+	decode := func(src string) (T, error) {
+		v, err := codec.Decode(src)
+		if err != nil {
+			return 0, err
+		}
+		if T(v) < 0 || uint64(T(v)) != v {
+			return 0, fmt.Errorf("decoded id %d overflows %T", v, T(0))
+		}
+		return T(v), nil
+	}
+
+	converters := NewErrorHandlingGenericTypeConverterPair(T(0), "", encode, decode)
+
+	return append(converters, NewErrorHandlingGenericTypeConverterPair(new(T), new(string),
+		func(src *T) (*string, error) {
+			if src == nil {
+				return nil, nil
+			}
+			s, err := encode(*src)
+			if err != nil {
+				return nil, err
+			}
+			return &s, nil
+		},
+		func(src *string) (*T, error) {
+			if src == nil || *src == "" {
+				return nil, nil
+			}
+			v, err := decode(*src)
+			if err != nil {
+				return nil, err
+			}
+			return &v, nil
+		},
+	)...)
+}
@@ -1,9 +1,12 @@
 package copierutil
 
 import (
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jinzhu/copier"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -151,3 +154,41 @@ func TestNewTimeTimestamppbConverterPair(t *testing.T) {
 	assert.NoError(t, err)
 	assert.IsType(t, srcType, result)
 }
+
+type fakeIDCodec struct{}
+
+func (fakeIDCodec) Encode(id uint64) string {
+	return "id-" + strconv.FormatUint(id, 10)
+}
+
+func (fakeIDCodec) Decode(s string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimPrefix(s, "id-"), 10, 64)
+}
+
+func TestNewIDCodecConverterPair(t *testing.T) {
+	type entity struct {
+		ID       uint32
+		ParentID *uint32
+	}
+	type dto struct {
+		ID       string
+		ParentID *string
+	}
+
+	converters := NewIDCodecConverterPair[uint32](fakeIDCodec{})
+
+	var d dto
+	err := copier.CopyWithOption(&d, &entity{ID: 42, ParentID: trans.Ptr(uint32(7))}, copier.Option{Converters: converters})
+	assert.NoError(t, err)
+	assert.Equal(t, "id-42", d.ID)
+	assert.Equal(t, "id-7", *d.ParentID)
+
+	var e entity
+	err = copier.CopyWithOption(&e, &d, copier.Option{Converters: converters})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(42), e.ID)
+	assert.Equal(t, uint32(7), *e.ParentID)
+
+	err = copier.CopyWithOption(&e, &dto{ID: "id-99999999999"}, copier.Option{Converters: converters})
+	assert.Error(t, err)
+}
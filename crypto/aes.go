@@ -0,0 +1,193 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	cipherTextPrefix    = "gcm"
+	cipherTextSeparator = ":"
+
+	MinIndexKeySize = 32 // 盲索引密钥的最小长度
+)
+
+var (
+	ErrInvalidKey        = errors.New("invalid aes key, must be 16, 24 or 32 bytes")
+	ErrInvalidCipherText = errors.New("invalid cipher text")
+	ErrKeyNotFound       = errors.New("encryption key not found")
+	ErrInvalidIndexKey   = errors.New("invalid blind index key, must be at least 32 bytes")
+)
+
+// AESGCMEncrypt 使用 AES-GCM 加密，返回 nonce + 密文
+func AESGCMEncrypt(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// AESGCMDecrypt 使用 AES-GCM 解密 AESGCMEncrypt 的结果
+func AESGCMDecrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrInvalidCipherText
+	}
+
+	nonce, data := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, data, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// BlindIndex 计算确定性的盲索引（HMAC-SHA256），用于加密字段的等值查询
+func BlindIndex(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Keyring 带密钥ID的密钥环，使用当前密钥加密，按密文中的密钥ID解密，以支持密钥轮换。
+//
+// 密文格式：gcm:<密钥ID>:<base64(nonce + 密文)>
+type Keyring struct {
+	currentKeyID string
+	keys         map[string][]byte
+	indexKey     []byte
+}
+
+// NewKeyring 创建密钥环，currentKeyID 为加密使用的密钥，indexKey 为盲索引使用的密钥，至少 32 字节
+func NewKeyring(currentKeyID string, keys map[string][]byte, indexKey []byte) (*Keyring, error) {
+	if len(indexKey) < MinIndexKeySize {
+		return nil, ErrInvalidIndexKey
+	}
+	if currentKeyID == "" || strings.Contains(currentKeyID, cipherTextSeparator) {
+		return nil, fmt.Errorf("invalid key id: %q", currentKeyID)
+	}
+
+	copied := make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		if strings.Contains(id, cipherTextSeparator) {
+			return nil, fmt.Errorf("invalid key id: %q", id)
+		}
+		if _, err := newGCM(key); err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		copied[id] = append([]byte(nil), key...)
+	}
+
+	if _, ok := copied[currentKeyID]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, currentKeyID)
+	}
+
+	return &Keyring{
+		currentKeyID: currentKeyID,
+		keys:         copied,
+		indexKey:     append([]byte(nil), indexKey...),
+	}, nil
+}
+
+// CurrentKeyID 返回当前加密使用的密钥ID
+func (k *Keyring) CurrentKeyID() string {
+	return k.currentKeyID
+}
+
+// EncryptString 使用当前密钥加密
+func (k *Keyring) EncryptString(plaintext string) (string, error) {
+	data, err := AESGCMEncrypt(k.keys[k.currentKeyID], []byte(plaintext), []byte(k.currentKeyID))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		cipherTextPrefix,
+		k.currentKeyID,
+		base64.RawStdEncoding.EncodeToString(data),
+	}, cipherTextSeparator), nil
+}
+
+// DecryptString 按密文中的密钥ID解密
+func (k *Keyring) DecryptString(ciphertext string) (string, error) {
+	keyID, data, err := parseCipherText(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	key, ok := k.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+
+	plaintext, err := AESGCMDecrypt(key, data, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRotation 密文是否由非当前密钥加密，需要重新加密
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	keyID, _, err := parseCipherText(ciphertext)
+	return err == nil && keyID != k.currentKeyID
+}
+
+// BlindIndex 计算字段的盲索引，使用由索引密钥和字段名派生的密钥（HMAC(indexKey, field)），
+// 不同字段的相同明文得到不同的盲索引，避免跨字段关联
+func (k *Keyring) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(field))
+	return BlindIndex(mac.Sum(nil), value)
+}
+
+// IsEncrypted 是否为 Keyring 生成的密文
+func IsEncrypted(s string) bool {
+	_, _, err := parseCipherText(s)
+	return err == nil
+}
+
+func parseCipherText(ciphertext string) (string, []byte, error) {
+	parts := strings.Split(ciphertext, cipherTextSeparator)
+	if len(parts) != 3 || parts[0] != cipherTextPrefix || parts[1] == "" {
+		return "", nil, ErrInvalidCipherText
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, ErrInvalidCipherText
+	}
+
+	return parts[1], data, nil
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testKey1     = bytes.Repeat([]byte{1}, 32)
+	testKey2     = bytes.Repeat([]byte{2}, 16)
+	testIndexKey = bytes.Repeat([]byte{3}, 32)
+)
+
+func TestAESGCM(t *testing.T) {
+	ciphertext, err := AESGCMEncrypt(testKey1, []byte("13800138000"), nil)
+	assert.NoError(t, err)
+
+	plaintext, err := AESGCMDecrypt(testKey1, ciphertext, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "13800138000", string(plaintext))
+
+	_, err = AESGCMDecrypt(testKey2, ciphertext, nil)
+	assert.Error(t, err)
+
+	_, err = AESGCMEncrypt([]byte("short"), []byte("13800138000"), nil)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestKeyring(t *testing.T) {
+	oldKeyring, err := NewKeyring("k1", map[string][]byte{"k1": testKey1}, testIndexKey)
+	assert.NoError(t, err)
+
+	oldCiphertext, err := oldKeyring.EncryptString("13800138000")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(oldCiphertext))
+	assert.False(t, IsEncrypted("13800138000"))
+
+	// 相同明文的密文不同
+	another, err := oldKeyring.EncryptString("13800138000")
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldCiphertext, another)
+
+	// 轮换密钥后，旧密文仍可解密
+	keyring, err := NewKeyring("k2", map[string][]byte{"k1": testKey1, "k2": testKey2}, testIndexKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", keyring.CurrentKeyID())
+
+	plaintext, err := keyring.DecryptString(oldCiphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "13800138000", plaintext)
+	assert.True(t, keyring.NeedsRotation(oldCiphertext))
+
+	newCiphertext, err := keyring.EncryptString(plaintext)
+	assert.NoError(t, err)
+	assert.False(t, keyring.NeedsRotation(newCiphertext))
+
+	_, err = oldKeyring.DecryptString(newCiphertext)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = keyring.DecryptString("13800138000")
+	assert.ErrorIs(t, err, ErrInvalidCipherText)
+
+	// 盲索引是确定性的
+	assert.Equal(t, oldKeyring.BlindIndex("phone", "13800138000"), keyring.BlindIndex("phone", "13800138000"))
+	assert.NotEqual(t, keyring.BlindIndex("phone", "13800138000"), keyring.BlindIndex("phone", "13800138001"))
+
+	// 不同字段的相同明文得到不同的盲索引
+	assert.NotEqual(t, keyring.BlindIndex("phone", "13800138000"), keyring.BlindIndex("id_card", "13800138000"))
+}
+
+func TestNewKeyring(t *testing.T) {
+	_, err := NewKeyring("k1", map[string][]byte{"k2": testKey2}, testIndexKey)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = NewKeyring("k1", map[string][]byte{"k1": []byte("short")}, testIndexKey)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = NewKeyring("k:1", map[string][]byte{"k:1": testKey1}, testIndexKey)
+	assert.Error(t, err)
+
+	// 盲索引密钥过短时 HMAC 容易被暴力破解
+	_, err = NewKeyring("k1", map[string][]byte{"k1": testKey1}, nil)
+	assert.ErrorIs(t, err, ErrInvalidIndexKey)
+
+	_, err = NewKeyring("k1", map[string][]byte{"k1": testKey1}, []byte("index-key"))
+	assert.ErrorIs(t, err, ErrInvalidIndexKey)
+}
@@ -1,5 +1,11 @@
 package dingtalk
 
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
 type msgTypeType string
 
 const (
@@ -14,6 +20,19 @@ type DingTalk struct {
 	robotToken []string
 	secret     string
 	keyWord    string
+	appSecret  string // OutGoing 机器人的 AppSecret
+
+	httpClient *http.Client
+	baseURL    string
+	timeout    time.Duration
+	logger     *slog.Logger
+
+	rateLimit    int
+	rateBurst    int
+	limiter      *limiter
+	maxRetries   int
+	retryInitial time.Duration
+	retryMax     time.Duration
 }
 
 type textModel struct {
@@ -68,7 +87,8 @@ type FeedCardLinkModel struct {
 	PicURL     string `json:"picURL,omitempty"`
 }
 
-type outGoingModel struct {
+// OutGoingMessage OutGoing 机器人收到的回调消息
+type OutGoingMessage struct {
 	AtUsers []struct {
 		DingtalkID string `json:"dingtalkId"`
 	} `json:"atUsers"`
@@ -84,6 +104,7 @@ type outGoingModel struct {
 	SceneGroupCode            string `json:"sceneGroupCode"`
 	SenderID                  string `json:"senderId"`
 	SenderNick                string `json:"senderNick"`
+	SenderStaffID             string `json:"senderStaffId"` // 企业内部机器人才有
 	SessionWebhook            string `json:"sessionWebhook"`
 	SessionWebhookExpiredTime int64  `json:"sessionWebhookExpiredTime"`
 	Text                      struct {
@@ -91,4 +112,7 @@ type outGoingModel struct {
 	} `json:"text"`
 }
 
+// ExecFunc 命令的执行方法
+//
+// Deprecated: 使用 Router 和 HandlerFunc
 type ExecFunc func(args []string) []byte
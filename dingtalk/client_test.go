@@ -0,0 +1,193 @@
+package dingtalk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receivedMsg struct {
+	Query url.Values
+	Body  map[string]interface{}
+}
+
+// newTestServer 模拟钉钉机器人接口，handler 返回状态码和响应内容
+func newTestServer(t *testing.T, handler func(n int) (int, string)) (*httptest.Server, func() []receivedMsg) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var received []receivedMsg
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf, _ := io.ReadAll(r.Body)
+		msg := receivedMsg{Query: r.URL.Query()}
+		_ = json.Unmarshal(buf, &msg.Body)
+
+		mu.Lock()
+		received = append(received, msg)
+		n := len(received)
+		mu.Unlock()
+
+		code, body := handler(n)
+		w.WriteHeader(code)
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []receivedMsg {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedMsg(nil), received...)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil); !errors.Is(err, ErrNoToken) {
+		t.Errorf("expected ErrNoToken, but %v got", err)
+	}
+	if _, err := New([]string{""}); !errors.Is(err, ErrNoToken) {
+		t.Errorf("expected ErrNoToken, but %v got", err)
+	}
+	if _, err := New([]string{"token"}, WithBaseURL("://bad")); err == nil {
+		t.Errorf("expected invalid base url error")
+	}
+}
+
+func TestSendMessageContext(t *testing.T) {
+	srv, received := newTestServer(t, func(int) (int, string) {
+		return http.StatusOK, `{"errcode":0,"errmsg":"ok"}`
+	})
+
+	cli, err := New([]string{"token"}, WithBaseURL(srv.URL+"/robot/send"), WithSecret("secret"), WithKeyWord("."), WithHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cli.SendTextMessageContext(context.Background(), "hello"); err != nil {
+		t.Fatalf("SendTextMessageContext expected be nil, but %v got", err)
+	}
+
+	msgs := received()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message, but %d got", len(msgs))
+	}
+	q := msgs[0].Query
+	if q.Get("access_token") != "token" || q.Get("timestamp") == "" || q.Get("sign") == "" {
+		t.Errorf("unexpected query %v", q)
+	}
+	if content := msgs[0].Body["text"].(map[string]interface{})["content"]; content != "hello." {
+		t.Errorf("expected content hello., but %v got", content)
+	}
+}
+
+func TestSendMessageError(t *testing.T) {
+	srv, _ := newTestServer(t, func(n int) (int, string) {
+		if n == 1 {
+			return http.StatusOK, `{"errcode":310000,"errmsg":"keywords not in content"}`
+		}
+		time.Sleep(100 * time.Millisecond)
+		return http.StatusOK, `{"errcode":0}`
+	})
+
+	cli, err := New([]string{"token"}, WithBaseURL(srv.URL), WithTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cli.SendMarkDownMessage("title", "text"); err == nil {
+		t.Errorf("expected errcode error")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = cli.SendLinkMessageContext(ctx, "title", "text", "", ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, but %v got", err)
+	}
+}
+
+func TestSendMessageRetry(t *testing.T) {
+	srv, received := newTestServer(t, func(n int) (int, string) {
+		switch n {
+		case 1:
+			return http.StatusBadGateway, ``
+		case 2:
+			return http.StatusOK, `{"errcode":130101,"errmsg":"send too fast"}`
+		default:
+			return http.StatusOK, `{"errcode":0}`
+		}
+	})
+
+	cli, err := New([]string{"token"}, WithBaseURL(srv.URL), WithRateLimit(0, 0), WithRetry(2, time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = cli.SendTextMessage("retry"); err != nil {
+		t.Fatalf("SendTextMessage expected be nil, but %v got", err)
+	}
+	if n := len(received()); n != 3 {
+		t.Errorf("expected 3 requests, but %d got", n)
+	}
+
+	// 重试次数用完
+	srv, _ = newTestServer(t, func(int) (int, string) {
+		return http.StatusServiceUnavailable, ``
+	})
+	cli, _ = New([]string{"token"}, WithBaseURL(srv.URL), WithRateLimit(0, 0), WithRetry(0, time.Millisecond, time.Millisecond))
+	var sendErr *SendError
+	if err = cli.SendTextMessage("retry"); !errors.As(err, &sendErr) || sendErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected SendError, but %v got", err)
+	}
+
+	// 无法解析的响应可能已经发送成功，不重试
+	srv, received = newTestServer(t, func(int) (int, string) {
+		return http.StatusOK, `<html>`
+	})
+	cli, _ = New([]string{"token"}, WithBaseURL(srv.URL), WithRateLimit(0, 0), WithRetry(2, time.Millisecond, time.Millisecond))
+	if err = cli.SendTextMessage("retry"); err == nil {
+		t.Error("SendTextMessage expected error, but nil got")
+	}
+	if n := len(received()); n != 1 {
+		t.Errorf("expected 1 request, but %d got", n)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&SendError{StatusCode: http.StatusBadGateway}, true},
+		{&SendError{StatusCode: http.StatusOK, ErrCode: ErrCodeSendTooFast}, true},
+		{&SendError{StatusCode: http.StatusOK, ErrCode: 300001}, false},
+		{&url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, true},
+		{&url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host"}}}, false},
+		{&url.Error{Op: "Post", Err: &net.OpError{Op: "read", Err: errors.New("connection reset")}}, false},
+		{&url.Error{Op: "Post", Err: context.DeadlineExceeded}, false},
+		{&json.SyntaxError{}, false},
+	}
+	for _, c := range cases {
+		if got := retryable(c.err); got != c.want {
+			t.Errorf("retryable(%v) expected %v, but %v got", c.err, c.want, got)
+		}
+	}
+}
+
+func TestInitDingTalkWithoutLimits(t *testing.T) {
+	d := InitDingTalk([]string{"token"}, "key")
+	if d.maxRetries != 0 || d.limiter.interval != 0 {
+		t.Errorf("expected no retry and rate limit, but %d retries and %s interval got", d.maxRetries, d.limiter.interval)
+	}
+
+	d = InitDingTalkWithSecret("token", "secret")
+	if d.maxRetries != 0 || d.limiter.interval != 0 {
+		t.Errorf("expected no retry and rate limit, but %d retries and %s interval got", d.maxRetries, d.limiter.interval)
+	}
+}
@@ -1,6 +1,22 @@
 package dingtalk
 
-const dingTalkURL = "https://oapi.dingtalk.com/robot/send?"
+import "time"
+
+const (
+	DefaultBaseURL = "https://oapi.dingtalk.com/robot/send" // 自定义机器人发送消息的地址
+	DefaultTimeout = 2 * time.Second                        // 默认的发送超时时间
+
+	// 默认令牌桶容量 5、每分钟补充 15 个，任意一分钟内每个机器人最多发送 20 条，与钉钉的限流一致
+	DefaultRateLimit = 15
+	DefaultRateBurst = 5
+
+	DefaultMaxRetries   = 2                      // 默认的最大重试次数
+	DefaultRetryInitial = 500 * time.Millisecond // 默认的首次重试等待时间
+	DefaultRetryMax     = 5 * time.Second        // 默认的最大重试等待时间
+
+	ErrCodeSendTooFast = 130101 // 发送速度太快而限流
+)
+
 const dtmdFormat = "[%s](dtmd://dingtalkclient/sendMessage?content=%s)"
 const formatSpliter = "$$"
 
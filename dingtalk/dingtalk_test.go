@@ -156,6 +156,6 @@ func TestOutGoing(t *testing.T) {
 		return NewTextMsg("hello").Marshaler()
 	}
 	RegisterCommand("hello", outgoingFunc, 1, true)
-	http.Handle("/outgoing", &OutGoingHandler{})
+	http.Handle("/outgoing", &OutGoingHandler{Insecure: true})
 	_ = http.ListenAndServe(":8000", nil)
 }
@@ -37,7 +37,7 @@ func initDefaultHTTPClient() *http.Client {
 	return client
 }
 
-func doRequest(ctx context.Context, callMethod string, endPoint string, header map[string]string, body []byte) (*http.Response, error) {
+func doRequest(ctx context.Context, client *http.Client, callMethod string, endPoint string, header map[string]string, body []byte) (*http.Response, error) {
 
 	req, err := http.NewRequest(callMethod, endPoint, bytes.NewBuffer(body))
 	if err != nil {
@@ -49,8 +49,10 @@ func doRequest(ctx context.Context, callMethod string, endPoint string, header m
 		}
 	}
 	req = req.WithContext(ctx)
-	// use myHttpClient to send request
-	response, err := myHTTPClient.Do(req)
+	if client == nil {
+		client = myHTTPClient
+	}
+	response, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,164 @@
+package dingtalk
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket 令牌桶，tokens 可以为负数，表示已预约的等待
+type tokenBucket struct {
+	token  string
+	tokens float64
+	last   time.Time
+}
+
+// limiter 按机器人 token 限流，每次发送选择最早可用的 token
+type limiter struct {
+	mu       sync.Mutex
+	buckets  []*tokenBucket
+	burst    float64
+	interval time.Duration // 补充一个令牌的间隔
+	next     int           // 可用时间相同时轮流选择
+	now      func() time.Time
+}
+
+// newLimiter perMinute 小于等于 0 时不限流，只轮流选择 token
+func newLimiter(tokens []string, perMinute, burst int) *limiter {
+	l := &limiter{now: time.Now}
+	if perMinute > 0 {
+		l.interval = time.Minute / time.Duration(perMinute)
+		l.burst = float64(max(burst, 1))
+	}
+
+	for _, token := range tokens {
+		l.buckets = append(l.buckets, &tokenBucket{token: token, tokens: l.burst, last: l.now()})
+	}
+
+	return l
+}
+
+// acquire 取得一个可用的 token，需要等待时阻塞直到可用或 ctx 结束
+func (l *limiter) acquire(ctx context.Context) (string, error) {
+	l.mu.Lock()
+	now := l.now()
+
+	var (
+		picked *tokenBucket
+		wait   time.Duration
+	)
+	for i := range l.buckets {
+		b := l.buckets[(l.next+i)%len(l.buckets)]
+		w := l.refill(b, now)
+		if picked == nil || w < wait {
+			picked, wait = b, w
+		}
+	}
+	l.next = (l.next + 1) % len(l.buckets)
+	if l.interval > 0 {
+		picked.tokens--
+	}
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return picked.token, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return picked.token, nil
+	case <-ctx.Done():
+		// 归还预约的令牌
+		l.mu.Lock()
+		picked.tokens++
+		l.mu.Unlock()
+		return "", ctx.Err()
+	}
+}
+
+// drain 清空 token 的令牌，用于钉钉返回限流错误时暂停使用该 token
+func (l *limiter) drain(token string) {
+	if l.interval <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, b := range l.buckets {
+		if b.token == token {
+			l.refill(b, l.now())
+			b.tokens = min(b.tokens, 0)
+		}
+	}
+}
+
+// refill 按经过的时间补充令牌，返回取得一个令牌需要等待的时间，需要持有锁
+func (l *limiter) refill(b *tokenBucket, now time.Time) time.Duration {
+	if l.interval <= 0 {
+		return 0
+	}
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(l.burst, b.tokens+float64(elapsed)/float64(l.interval))
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(l.interval))
+}
+
+const maxKeyedBuckets = 10000 // 按 key 限流时最多保留的令牌桶数
+
+// keyedLimiter 按 key 限流，超过限流时直接拒绝，用于按发送者限流
+type keyedLimiter struct {
+	mu      sync.Mutex
+	l       *limiter
+	buckets map[string]*tokenBucket
+}
+
+func newKeyedLimiter(perMinute, burst int) *keyedLimiter {
+	return &keyedLimiter{
+		l:       newLimiter(nil, perMinute, burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow 取得 key 的一个令牌，没有可用的令牌时返回 false
+func (k *keyedLimiter) allow(key string) bool {
+	if k.l.interval <= 0 {
+		return true
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.l.now()
+	b, ok := k.buckets[key]
+	if !ok {
+		if len(k.buckets) >= maxKeyedBuckets {
+			k.prune(now)
+		}
+		b = &tokenBucket{token: key, tokens: k.l.burst, last: now}
+		k.buckets[key] = b
+	}
+
+	if k.l.refill(b, now) > 0 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune 删除已补满的令牌桶，需要持有锁
+func (k *keyedLimiter) prune(now time.Time) {
+	for key, b := range k.buckets {
+		if k.l.refill(b, now); b.tokens >= k.l.burst {
+			delete(k.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package dingtalk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newLimiter([]string{"a", "b"}, 60, 1)
+	l.now = func() time.Time { return now }
+	for _, b := range l.buckets {
+		b.last = now
+	}
+
+	ctx := context.Background()
+	var got []string
+	for i := 0; i < 2; i++ {
+		token, err := l.acquire(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, token)
+	}
+	if got[0] == got[1] {
+		t.Errorf("expected different tokens, but %v got", got)
+	}
+
+	// 两个 token 都没有令牌，需要等待 1s
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, but %v got", err)
+	}
+
+	// 预约已归还，1s 后 token 可用
+	now = now.Add(time.Second)
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// 限流的 token 暂停使用
+	now = now.Add(time.Second)
+	l.drain("a")
+	for i := 0; i < 2; i++ {
+		if token, _ := l.acquire(context.Background()); token != "b" {
+			t.Errorf("expected token b, but %s got", token)
+		}
+		now = now.Add(time.Second)
+		l.drain("a")
+	}
+}
+
+func TestLimiterDisabled(t *testing.T) {
+	l := newLimiter([]string{"a", "b"}, 0, 0)
+	var got []string
+	for i := 0; i < 4; i++ {
+		token, err := l.acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, token)
+	}
+	if want := []string{"a", "b", "a", "b"}; len(got) != 4 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Errorf("expected %v, but %v got", want, got)
+	}
+}
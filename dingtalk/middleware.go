@@ -0,0 +1,72 @@
+package dingtalk
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// LoggingMiddleware 记录命令的发送者、参数、耗时和错误
+func LoggingMiddleware(logger *slog.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(req *Request) (Message, error) {
+			start := time.Now()
+			reply, err := next(req)
+
+			attrs := []any{
+				"command", req.Command,
+				"args", req.Args,
+				"sender", req.SenderID(),
+				"nick", req.SenderNick(),
+				"conversation", req.ConversationID(),
+				"latency", time.Since(start),
+			}
+			if err != nil {
+				logger.WarnContext(req.Context, "dingtalk command failed", append(attrs, "err", err)...)
+			} else {
+				logger.InfoContext(req.Context, "dingtalk command", attrs...)
+			}
+			return reply, err
+		}
+	}
+}
+
+// RateLimitMiddleware 按发送者限流：令牌桶容量 burst，每分钟补充 perMinute 个，超过时回复 ErrTooManyRequests
+func RateLimitMiddleware(perMinute, burst int) Middleware {
+	limiter := newKeyedLimiter(perMinute, burst)
+	return func(next HandlerFunc) HandlerFunc {
+		return func(req *Request) (Message, error) {
+			if !limiter.allow(req.SenderID()) {
+				return nil, ErrTooManyRequests
+			}
+			return next(req)
+		}
+	}
+}
+
+// AllowSendersMiddleware 只允许指定的发送者执行所有命令，匹配 senderId 或 senderStaffId
+func AllowSendersMiddleware(ids ...string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(req *Request) (Message, error) {
+			if !slices.Contains(ids, req.SenderID()) && (req.Message.SenderStaffID == "" || !slices.Contains(ids, req.Message.SenderStaffID)) {
+				return nil, ErrPermissionDenied
+			}
+			return next(req)
+		}
+	}
+}
+
+// RecoverMiddleware 命令 panic 时记录日志并回复错误
+func RecoverMiddleware(logger *slog.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(req *Request) (reply Message, err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					logger.ErrorContext(req.Context, "dingtalk command panic", "command", req.Command, "panic", p)
+					reply, err = nil, ErrCommandPanic
+				}
+			}()
+			return next(req)
+		}
+	}
+}
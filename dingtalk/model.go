@@ -6,7 +6,8 @@ import (
 	"strings"
 )
 
-type iDingMsg interface {
+// Message 钉钉消息，由 NewTextMsg、NewMarkDownMsg 等创建
+type Message interface {
 	Marshaler() []byte
 }
 
@@ -49,6 +50,11 @@ func (t textMsg) Marshaler() []byte {
 	return b
 }
 
+func (t textMsg) repeated(n int) Message {
+	t.Text.Content += repeatedSuffix(n)
+	return t
+}
+
 func NewTextMsg(content string, opts ...atOption) *textMsg {
 	msg := &textMsg{MsgType: TEXT, Text: textModel{Content: content}}
 	for _, opt := range opts {
@@ -87,6 +93,11 @@ func (m markDownMsg) Marshaler() []byte {
 	return b
 }
 
+func (m markDownMsg) repeated(n int) Message {
+	m.Markdown.Text += repeatedSuffix(n)
+	return m
+}
+
 func NewDTMDMsg(title string, dtmdMap *dingMap, opts ...atOption) *markDownMsg {
 	text := ""
 	for _, v := range dtmdMap.l {
@@ -0,0 +1,134 @@
+package dingtalk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultOutGoingMaxAge = time.Hour // 回调时间戳与当前时间的最大误差，与钉钉的要求一致
+	maxOutGoingBodySize   = 1 << 20
+)
+
+var (
+	ErrMissingSignature = errors.New("dingtalk: missing outgoing timestamp or sign")
+	ErrInvalidSignature = errors.New("dingtalk: invalid outgoing sign")
+	ErrStaleTimestamp   = errors.New("dingtalk: outgoing timestamp expired")
+	ErrNoAppSecret      = errors.New("dingtalk: outgoing app secret not configured")
+)
+
+// OutGoingError 回调处理失败，Code 为应返回给钉钉的 HTTP 状态码
+type OutGoingError struct {
+	Code int
+	Err  error
+}
+
+func (e *OutGoingError) Error() string {
+	return fmt.Sprintf("outgoing %d: %v", e.Code, e.Err)
+}
+
+func (e *OutGoingError) Unwrap() error {
+	return e.Err
+}
+
+// WithAppSecret 设置 OutGoing 机器人的 AppSecret，用于校验回调的签名
+func WithAppSecret(secret string) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.appSecret = secret
+	})
+}
+
+// VerifyOutGoing 校验回调请求头中的 timestamp 和 sign：
+// sign = Base64(HmacSHA256(timestamp + "\n" + appSecret, appSecret))，timestamp 与当前时间相差不能超过 maxAge。
+func VerifyOutGoing(header http.Header, appSecret string, maxAge time.Duration) error {
+	if appSecret == "" {
+		return ErrNoAppSecret
+	}
+
+	timestamp, sign := header.Get("timestamp"), header.Get("sign")
+	if timestamp == "" || sign == "" {
+		return ErrMissingSignature
+	}
+
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultOutGoingMaxAge
+	}
+	if age := time.Since(time.UnixMilli(ms)); age > maxAge || age < -maxAge {
+		return ErrStaleTimestamp
+	}
+
+	expected := outGoingSign(timestamp, appSecret)
+	if !hmac.Equal([]byte(expected), []byte(sign)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func outGoingSign(timestamp, appSecret string) string {
+	h := hmac.New(sha256.New, []byte(appSecret))
+	h.Write([]byte(timestamp + "\n" + appSecret))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// parseOutGoing 校验签名并解析回调，失败时返回 *OutGoingError
+func parseOutGoing(r *http.Request, appSecret string, maxAge time.Duration, insecure bool) (OutGoingMessage, error) {
+	var msg OutGoingMessage
+
+	if r.Method != http.MethodPost {
+		return msg, &OutGoingError{Code: http.StatusMethodNotAllowed, Err: fmt.Errorf("method %s not allowed", r.Method)}
+	}
+
+	if !insecure {
+		if err := VerifyOutGoing(r.Header, appSecret, maxAge); err != nil {
+			code := http.StatusUnauthorized
+			if errors.Is(err, ErrNoAppSecret) {
+				code = http.StatusInternalServerError
+			}
+			return msg, &OutGoingError{Code: code, Err: err}
+		}
+	}
+
+	buf, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxOutGoingBodySize))
+	if err != nil {
+		code := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		return msg, &OutGoingError{Code: code, Err: err}
+	}
+	if err = json.Unmarshal(buf, &msg); err != nil {
+		return msg, &OutGoingError{Code: http.StatusBadRequest, Err: err}
+	}
+
+	return msg, nil
+}
+
+// OutGoingRequest 校验回调的签名和时间戳并解析消息，需要通过 WithAppSecret 设置 AppSecret。
+// 失败时返回 *OutGoingError，其中的 Code 为应返回的 HTTP 状态码。
+func (d *DingTalk) OutGoingRequest(r *http.Request) (OutGoingMessage, error) {
+	return parseOutGoing(r, d.appSecret, 0, false)
+}
+
+// writeOutGoingError 返回错误状态码
+func writeOutGoingError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	var outGoingErr *OutGoingError
+	if errors.As(err, &outGoingErr) {
+		code = outGoingErr.Code
+	}
+	http.Error(w, http.StatusText(code), code)
+}
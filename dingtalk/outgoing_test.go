@@ -0,0 +1,110 @@
+package dingtalk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAppSecret = "app-secret"
+
+func newOutGoingRequest(method, body string, ts time.Time, secret string) *http.Request {
+	r := httptest.NewRequest(method, "/outgoing", strings.NewReader(body))
+	if secret != "" {
+		timestamp := strconv.FormatInt(ts.UnixMilli(), 10)
+		r.Header.Set("timestamp", timestamp)
+		r.Header.Set("sign", outGoingSign(timestamp, secret))
+	}
+	return r
+}
+
+func TestVerifyOutGoing(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		header http.Header
+		secret string
+		err    error
+	}{
+		{name: "ok", header: newOutGoingRequest("POST", "", now, testAppSecret).Header, secret: testAppSecret},
+		{name: "no secret", header: newOutGoingRequest("POST", "", now, testAppSecret).Header, err: ErrNoAppSecret},
+		{name: "missing", header: http.Header{}, secret: testAppSecret, err: ErrMissingSignature},
+		{name: "wrong secret", header: newOutGoingRequest("POST", "", now, "other").Header, secret: testAppSecret, err: ErrInvalidSignature},
+		{name: "stale", header: newOutGoingRequest("POST", "", now.Add(-2*time.Hour), testAppSecret).Header, secret: testAppSecret, err: ErrStaleTimestamp},
+		{name: "future", header: newOutGoingRequest("POST", "", now.Add(2*time.Hour), testAppSecret).Header, secret: testAppSecret, err: ErrStaleTimestamp},
+		{name: "bad timestamp", header: http.Header{"Timestamp": {"x"}, "Sign": {"x"}}, secret: testAppSecret, err: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyOutGoing(tt.header, tt.secret, 0); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, but %v got", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestOutGoingHandler(t *testing.T) {
+	RegisterCommand("ping", func(args []string) []byte {
+		return NewTextMsg("pong").Marshaler()
+	}, 1, false)
+
+	h := &OutGoingHandler{AppSecret: testAppSecret}
+	body := `{"msgtype":"text","text":{"content":"ping"}}`
+	tests := []struct {
+		name string
+		req  *http.Request
+		h    *OutGoingHandler
+		code int
+	}{
+		{name: "ok", req: newOutGoingRequest("POST", body, time.Now(), testAppSecret), code: http.StatusOK},
+		{name: "method", req: newOutGoingRequest("GET", body, time.Now(), testAppSecret), code: http.StatusMethodNotAllowed},
+		{name: "unsigned", req: newOutGoingRequest("POST", body, time.Now(), ""), code: http.StatusUnauthorized},
+		{name: "forged", req: newOutGoingRequest("POST", body, time.Now(), "forged"), code: http.StatusUnauthorized},
+		{name: "bad json", req: newOutGoingRequest("POST", "{", time.Now(), testAppSecret), code: http.StatusBadRequest},
+		{name: "no secret", req: newOutGoingRequest("POST", body, time.Now(), testAppSecret), h: &OutGoingHandler{}, code: http.StatusInternalServerError},
+		{name: "insecure", req: newOutGoingRequest("POST", body, time.Now(), ""), h: &OutGoingHandler{Insecure: true}, code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := h
+			if tt.h != nil {
+				handler = tt.h
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tt.req)
+			if w.Code != tt.code {
+				t.Errorf("expected status %d, but %d got", tt.code, w.Code)
+			}
+			if tt.code == http.StatusOK && !strings.Contains(w.Body.String(), "pong") {
+				t.Errorf("unexpected body %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestOutGoingRequest(t *testing.T) {
+	cli, err := New([]string{"token"}, WithAppSecret(testAppSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := cli.OutGoingRequest(newOutGoingRequest("POST", `{"senderId":"u1","text":{"content":"hi"}}`, time.Now(), testAppSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.SenderID != "u1" || msg.Text.Content != "hi" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	_, err = cli.OutGoingRequest(newOutGoingRequest("POST", `{}`, time.Now(), "forged"))
+	var outGoingErr *OutGoingError
+	if !errors.As(err, &outGoingErr) || outGoingErr.Code != http.StatusUnauthorized || !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected 401 OutGoingError, but %v got", err)
+	}
+}
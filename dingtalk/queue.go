@@ -0,0 +1,201 @@
+package dingtalk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const DefaultQueueSize = 100 // 默认的异步发送队列长度
+
+var (
+	ErrQueueFull   = errors.New("dingtalk: send queue is full")
+	ErrQueueClosed = errors.New("dingtalk: send queue is closed")
+)
+
+// QueueOption Queue 的配置项
+type QueueOption interface {
+	apply(q *Queue)
+}
+
+type funcQueueOption struct {
+	f func(q *Queue)
+}
+
+func (fo *funcQueueOption) apply(q *Queue) {
+	fo.f(q)
+}
+
+func newFuncQueueOption(f func(q *Queue)) *funcQueueOption {
+	return &funcQueueOption{f: f}
+}
+
+// WithQueueSize 设置队列长度，队列满时 Send 返回 ErrQueueFull
+func WithQueueSize(size int) QueueOption {
+	return newFuncQueueOption(func(q *Queue) {
+		q.size = size
+	})
+}
+
+// WithQueueErrorHandler 设置发送失败的回调，默认记录日志
+func WithQueueErrorHandler(fn func(msg Message, err error)) QueueOption {
+	return newFuncQueueOption(func(q *Queue) {
+		q.onError = fn
+	})
+}
+
+// repeatable 可以合并重复消息的消息类型
+type repeatable interface {
+	repeated(n int) Message
+}
+
+type queueEntry struct {
+	msg   Message
+	key   string
+	count int
+}
+
+// Queue 异步发送队列，发送不阻塞调用方，适合告警。
+// 队列中尚未发送的相同消息会合并为一条，文本和 Markdown 消息会注明重复次数。
+type Queue struct {
+	d       *DingTalk
+	size    int
+	onError func(msg Message, err error)
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []*queueEntry
+	index   map[string]*queueEntry
+	closed  bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewQueue 创建异步发送队列并启动后台发送，使用完后需要调用 Close
+func (d *DingTalk) NewQueue(opts ...QueueOption) *Queue {
+	q := &Queue{
+		d:     d,
+		size:  DefaultQueueSize,
+		index: make(map[string]*queueEntry),
+		done:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt.apply(q)
+	}
+	if q.size < 1 {
+		q.size = 1
+	}
+	if q.onError == nil {
+		q.onError = func(msg Message, err error) {
+			d.logger.Error("send dingtalk message failed", "err", err, "msg", string(msg.Marshaler()))
+		}
+	}
+	q.cond = sync.NewCond(&q.mu)
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+
+	go q.run()
+
+	return q
+}
+
+// Send 将消息加入队列，不会追加关键词
+func (q *Queue) Send(msg Message) error {
+	key := string(msg.Marshaler())
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if e, ok := q.index[key]; ok {
+		e.count++
+		return nil
+	}
+	if len(q.pending) >= q.size {
+		return ErrQueueFull
+	}
+
+	e := &queueEntry{msg: msg, key: key, count: 1}
+	q.pending = append(q.pending, e)
+	q.index[key] = e
+	q.cond.Signal()
+
+	return nil
+}
+
+// SendTextMessage 将文本消息加入队列
+func (q *Queue) SendTextMessage(content string, opt ...atOption) error {
+	return q.Send(NewTextMsg(content+q.d.keyWord, opt...))
+}
+
+// SendMarkDownMessage 将 Markdown 消息加入队列
+func (q *Queue) SendMarkDownMessage(title, text string, opts ...atOption) error {
+	return q.Send(NewMarkDownMsg(title+q.d.keyWord, text, opts...))
+}
+
+// Len 返回队列中待发送的消息数
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending)
+}
+
+// Close 停止接收新消息并发送队列中剩余的消息，ctx 结束时放弃剩余的消息并返回 ctx 的错误
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-q.done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	defer q.cancel()
+
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.pending) == 0 || q.ctx.Err() != nil {
+			dropped := len(q.pending)
+			q.pending, q.index = nil, nil
+			q.mu.Unlock()
+			if dropped > 0 {
+				q.d.logger.Warn("dingtalk send queue closed, messages dropped", "dropped", dropped)
+			}
+			return
+		}
+
+		e := q.pending[0]
+		q.pending = q.pending[1:]
+		delete(q.index, e.key)
+		q.mu.Unlock()
+
+		msg := e.msg
+		if r, ok := msg.(repeatable); ok && e.count > 1 {
+			msg = r.repeated(e.count)
+		}
+		if err := q.d.sendMessage(q.ctx, msg); err != nil && q.ctx.Err() == nil {
+			q.onError(msg, err)
+		}
+	}
+}
+
+func repeatedSuffix(n int) string {
+	return fmt.Sprintf("\n\n(重复 %d 次)", n)
+}
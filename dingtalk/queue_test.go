@@ -0,0 +1,98 @@
+package dingtalk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	release := make(chan struct{})
+	srv, received := newTestServer(t, func(n int) (int, string) {
+		if n == 1 {
+			<-release
+		}
+		return http.StatusOK, `{"errcode":0}`
+	})
+
+	cli, err := New([]string{"token"}, WithBaseURL(srv.URL), WithRateLimit(0, 0), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := cli.NewQueue(WithQueueSize(2))
+	if err = q.SendTextMessage("first"); err != nil {
+		t.Fatal(err)
+	}
+	// 等待第一条消息开始发送
+	for len(received()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err = q.SendTextMessage("disk full"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = q.SendMarkDownMessage("title", "text"); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.SendTextMessage("other"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, but %v got", err)
+	}
+	if q.Len() != 2 {
+		t.Errorf("expected 2 pending messages, but %d got", q.Len())
+	}
+
+	close(release)
+	if err = q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.SendTextMessage("closed"); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("expected ErrQueueClosed, but %v got", err)
+	}
+
+	msgs := received()
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, but %d got", len(msgs))
+	}
+	if content := msgs[1].Body["text"].(map[string]interface{})["content"]; content != "disk full\n\n(重复 3 次)" {
+		t.Errorf("unexpected merged content %q", content)
+	}
+}
+
+func TestQueueCloseTimeout(t *testing.T) {
+	srv, received := newTestServer(t, func(int) (int, string) {
+		time.Sleep(50 * time.Millisecond)
+		return http.StatusOK, `{"errcode":0}`
+	})
+
+	cli, err := New([]string{"token"}, WithBaseURL(srv.URL), WithRateLimit(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var failed []error
+	q := cli.NewQueue(WithQueueErrorHandler(func(msg Message, err error) {
+		failed = append(failed, err)
+	}))
+	for _, content := range []string{"a", "b", "c"} {
+		if err = q.SendTextMessage(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = q.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, but %v got", err)
+	}
+	if n := len(received()); n != 1 {
+		t.Errorf("expected 1 request, but %d got", n)
+	}
+	if len(failed) != 0 {
+		t.Errorf("expected no failure callback after close, but %v got", failed)
+	}
+}
@@ -1,10 +1,17 @@
 package dingtalk
 
 import (
-	"encoding/json"
-	"io"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 )
 
 var cmdTable = make(map[string]*command)
@@ -15,6 +22,9 @@ type command struct {
 	arity    int  // 参数个数
 }
 
+// RegisterCommand 注册全局命令，由 OutGoingHandler 执行
+//
+// Deprecated: 使用 NewRouter 创建的 Router，支持中间件、发送者白名单、帮助命令和异步回复
 func RegisterCommand(name string, execFunc ExecFunc, arity int, isAdmin bool) {
 	cmdTable[name] = &command{
 		executor: execFunc,
@@ -31,7 +41,7 @@ func validateArity(arity int, cmdArgs []string) bool {
 	return argNum >= -arity
 }
 
-func execDingCommand(msg outGoingModel) []byte {
+func execDingCommand(msg OutGoingMessage) []byte {
 	content := msg.Text.Content
 	keyAndArgs := strings.Split(strings.TrimSpace(content), " ")
 	cmdName := strings.ToLower(keyAndArgs[0])
@@ -48,26 +58,570 @@ func execDingCommand(msg outGoingModel) []byte {
 	return cmd.executor(keyAndArgs[1:])
 }
 
-// MyHandler实现Handler接口
-
-type OutGoingHandler struct{}
+// OutGoingHandler 处理 OutGoing 机器人的回调，校验签名后执行注册的命令
+type OutGoingHandler struct {
+	AppSecret string        // OutGoing 机器人的 AppSecret，用于校验回调的签名
+	MaxAge    time.Duration // 回调时间戳的最大误差，默认为 DefaultOutGoingMaxAge
+	Insecure  bool          // 不校验签名，仅用于本地测试
+}
 
 func (h *OutGoingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	body := r.Body
-	var err error
-	var buf []byte
-	obj := outGoingModel{}
-	buf, err = io.ReadAll(body)
-	if err != nil {
-		return
-	}
-	err = json.Unmarshal(buf, &obj)
+	obj, err := parseOutGoing(r, h.AppSecret, h.MaxAge, h.Insecure)
 	if err != nil {
+		writeOutGoingError(w, err)
 		return
 	}
 	msg := execDingCommand(obj)
 	if msg == nil {
+		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
 	_, _ = w.Write(msg)
 }
+
+const (
+	HelpCommand            = "help"          // 自动注册的帮助命令
+	DefaultAsyncTimeout    = 5 * time.Minute // 异步命令的默认超时时间
+	conversationTypeSingle = "1"             // 单聊
+	sessionWebhookMargin   = 5 * time.Second // 会话 webhook 过期前预留的时间
+)
+
+var (
+	ErrUnknownCommand    = errors.New("unregistered command")
+	ErrWrongArgs         = errors.New("wrong number of arguments")
+	ErrPermissionDenied  = errors.New("you have no right to do this operation")
+	ErrTooManyRequests   = errors.New("too many requests, please try again later")
+	ErrUnclosedQuote     = errors.New("unclosed quote")
+	ErrSessionExpired    = errors.New("dingtalk: session webhook expired")
+	ErrNoSessionWebhook  = errors.New("dingtalk: no session webhook")
+	ErrDuplicateCommand  = errors.New("dingtalk: duplicate command")
+	ErrCommandPanic      = errors.New("internal error")
+	ErrRouterClosed      = errors.New("dingtalk: router is shutting down")
+	errEmptyCommandInput = errors.New("empty command")
+)
+
+// Request 命令请求
+type Request struct {
+	Context context.Context // 同步命令为 HTTP 请求的 ctx，异步命令为独立的 ctx，超时时间为 WithAsyncTimeout
+	Command string          // 命令名，小写
+	Args    []string        // 命令参数，支持引号包含空格，例如 deploy "my app" v1.2
+	Message OutGoingMessage // 原始回调消息
+
+	router *Router
+}
+
+// SenderID 发送者ID
+func (r *Request) SenderID() string {
+	return r.Message.SenderID
+}
+
+// SenderNick 发送者昵称
+func (r *Request) SenderNick() string {
+	return r.Message.SenderNick
+}
+
+// ConversationID 会话ID
+func (r *Request) ConversationID() string {
+	return r.Message.ConversationID
+}
+
+// IsSingleChat 是否为单聊
+func (r *Request) IsSingleChat() bool {
+	return r.Message.ConversationType == conversationTypeSingle
+}
+
+// Reply 通过回调中的 sessionWebhook 回复消息，可以在异步或耗时的命令中多次调用
+func (r *Request) Reply(msg Message) error {
+	return r.router.reply(r.Context, r.Message, msg)
+}
+
+// ReplyText 通过 sessionWebhook 回复文本消息
+func (r *Request) ReplyText(content string) error {
+	return r.Reply(NewTextMsg(content))
+}
+
+// HandlerFunc 命令的执行方法，返回的消息作为回复，返回 nil 时不回复；返回错误时回复 "ERR: 错误信息"
+type HandlerFunc func(req *Request) (Message, error)
+
+// Middleware 命令中间件，例如鉴权、日志、限流
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// CommandOption 命令的配置项
+type CommandOption interface {
+	apply(c *routeCommand)
+}
+
+type funcCommandOption struct {
+	f func(c *routeCommand)
+}
+
+func (fo *funcCommandOption) apply(c *routeCommand) {
+	fo.f(c)
+}
+
+func newFuncCommandOption(f func(c *routeCommand)) *funcCommandOption {
+	return &funcCommandOption{f: f}
+}
+
+// WithDescription 命令的说明，显示在帮助中
+func WithDescription(description string) CommandOption {
+	return newFuncCommandOption(func(c *routeCommand) {
+		c.description = description
+	})
+}
+
+// WithUsage 命令的参数说明，显示在帮助中，例如 "<app> [version]"
+func WithUsage(usage string) CommandOption {
+	return newFuncCommandOption(func(c *routeCommand) {
+		c.usage = usage
+	})
+}
+
+// WithArgs 参数个数的范围，max 小于 0 时不限制最大个数
+func WithArgs(min, max int) CommandOption {
+	return newFuncCommandOption(func(c *routeCommand) {
+		c.minArgs = min
+		c.maxArgs = max
+	})
+}
+
+// WithAllowedSenders 只允许指定的发送者执行，匹配 senderId 或 senderStaffId
+func WithAllowedSenders(ids ...string) CommandOption {
+	return newFuncCommandOption(func(c *routeCommand) {
+		c.allowedSenders = append(c.allowedSenders, ids...)
+	})
+}
+
+// WithAdminOnly 只允许群管理员执行，需要校验回调签名，否则 isAdmin 可以伪造
+func WithAdminOnly() CommandOption {
+	return newFuncCommandOption(func(c *routeCommand) {
+		c.adminOnly = true
+	})
+}
+
+// WithAsync 异步执行：立即响应回调，执行结果通过 sessionWebhook 回复，用于耗时超过钉钉回调超时时间的命令。
+// 全局中间件、权限和参数检查与命令一起在后台执行，错误同样通过 sessionWebhook 回复。
+// Router 调用 Shutdown 后拒绝新的异步命令，回复 ErrRouterClosed。
+func WithAsync() CommandOption {
+	return newFuncCommandOption(func(c *routeCommand) {
+		c.async = true
+	})
+}
+
+// WithHidden 不在帮助中显示
+func WithHidden() CommandOption {
+	return newFuncCommandOption(func(c *routeCommand) {
+		c.hidden = true
+	})
+}
+
+// WithCommandMiddleware 只作用于该命令的中间件，在 Router.Use 的中间件之后执行
+func WithCommandMiddleware(mws ...Middleware) CommandOption {
+	return newFuncCommandOption(func(c *routeCommand) {
+		c.middlewares = append(c.middlewares, mws...)
+	})
+}
+
+type routeCommand struct {
+	name           string
+	handler        HandlerFunc
+	description    string
+	usage          string
+	minArgs        int
+	maxArgs        int
+	allowedSenders []string
+	adminOnly      bool
+	async          bool
+	hidden         bool
+	middlewares    []Middleware
+}
+
+// allowed 发送者是否有权限执行
+func (c *routeCommand) allowed(msg OutGoingMessage) bool {
+	if c.adminOnly && !msg.IsAdmin {
+		return false
+	}
+	if len(c.allowedSenders) == 0 {
+		return true
+	}
+	return slices.Contains(c.allowedSenders, msg.SenderID) ||
+		(msg.SenderStaffID != "" && slices.Contains(c.allowedSenders, msg.SenderStaffID))
+}
+
+func (c *routeCommand) help() string {
+	line := c.name
+	if c.usage != "" {
+		line += " " + c.usage
+	}
+	if c.description != "" {
+		line += " - " + c.description
+	}
+	return line
+}
+
+// RouterOption Router 的配置项
+type RouterOption interface {
+	apply(rt *Router)
+}
+
+type funcRouterOption struct {
+	f func(rt *Router)
+}
+
+func (fo *funcRouterOption) apply(rt *Router) {
+	fo.f(rt)
+}
+
+func newFuncRouterOption(f func(rt *Router)) *funcRouterOption {
+	return &funcRouterOption{f: f}
+}
+
+// WithOutGoingMaxAge 回调时间戳的最大误差，默认为 DefaultOutGoingMaxAge
+func WithOutGoingMaxAge(maxAge time.Duration) RouterOption {
+	return newFuncRouterOption(func(rt *Router) {
+		rt.maxAge = maxAge
+	})
+}
+
+// WithInsecure 不校验回调签名，仅用于本地测试
+func WithInsecure() RouterOption {
+	return newFuncRouterOption(func(rt *Router) {
+		rt.insecure = true
+	})
+}
+
+// WithRouterHTTPClient 设置通过 sessionWebhook 回复使用的 HTTP 客户端
+func WithRouterHTTPClient(client *http.Client) RouterOption {
+	return newFuncRouterOption(func(rt *Router) {
+		rt.httpClient = client
+	})
+}
+
+// WithRouterLogger 设置日志
+func WithRouterLogger(logger *slog.Logger) RouterOption {
+	return newFuncRouterOption(func(rt *Router) {
+		rt.logger = logger
+	})
+}
+
+// WithAsyncTimeout 设置异步命令的超时时间
+func WithAsyncTimeout(timeout time.Duration) RouterOption {
+	return newFuncRouterOption(func(rt *Router) {
+		rt.asyncTimeout = timeout
+	})
+}
+
+// Router OutGoing 机器人的命令路由，实现了 http.Handler：
+//
+//	rt := dingtalk.NewRouter(appSecret)
+//	rt.Use(dingtalk.LoggingMiddleware(logger), dingtalk.RateLimitMiddleware(10, 3))
+//	rt.Handle("deploy", deploy, dingtalk.WithArgs(1, 2), dingtalk.WithUsage("<app> [version]"),
+//		dingtalk.WithAllowedSenders(ops...), dingtalk.WithAsync())
+//	http.Handle("/outgoing", rt)
+type Router struct {
+	appSecret    string
+	maxAge       time.Duration
+	insecure     bool
+	httpClient   *http.Client
+	logger       *slog.Logger
+	asyncTimeout time.Duration
+
+	mu          sync.RWMutex
+	commands    map[string]*routeCommand
+	middlewares []Middleware
+
+	asyncWg sync.WaitGroup
+	closed  bool // 已调用 Shutdown，不再接收异步命令，由 mu 保护
+}
+
+// NewRouter 创建命令路由，appSecret 为 OutGoing 机器人的 AppSecret，用于校验回调签名。
+// 自动注册 help 命令，列出发送者有权限执行的命令，可以注册同名命令覆盖。
+func NewRouter(appSecret string, opts ...RouterOption) *Router {
+	rt := &Router{
+		appSecret:    appSecret,
+		httpClient:   myHTTPClient,
+		logger:       slog.Default(),
+		asyncTimeout: DefaultAsyncTimeout,
+		commands:     make(map[string]*routeCommand),
+	}
+	for _, opt := range opts {
+		opt.apply(rt)
+	}
+	if rt.httpClient == nil {
+		rt.httpClient = myHTTPClient
+	}
+	if rt.logger == nil {
+		rt.logger = slog.Default()
+	}
+
+	rt.commands[HelpCommand] = &routeCommand{
+		name:        HelpCommand,
+		handler:     rt.help,
+		description: "show available commands",
+		usage:       "[command]",
+		maxArgs:     1,
+	}
+
+	return rt
+}
+
+// Use 添加作用于所有请求的中间件，包括未注册的命令和 help，按添加顺序执行；
+// 异步命令的中间件在后台执行，能记录命令实际的耗时和错误
+func (rt *Router) Use(mws ...Middleware) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.middlewares = append(rt.middlewares, mws...)
+}
+
+// Handle 注册命令，命令名不区分大小写，重复注册时返回 ErrDuplicateCommand（help 除外）
+func (rt *Router) Handle(name string, handler HandlerFunc, opts ...CommandOption) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("dingtalk: invalid command name %q", name)
+	}
+
+	c := &routeCommand{name: name, handler: handler, maxArgs: -1}
+	for _, opt := range opts {
+		opt.apply(c)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if _, ok := rt.commands[name]; ok && name != HelpCommand {
+		return fmt.Errorf("%w: %s", ErrDuplicateCommand, name)
+	}
+	rt.commands[name] = c
+
+	return nil
+}
+
+// ServeHTTP 校验回调签名，执行命令并将结果作为响应
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	msg, err := parseOutGoing(r, rt.appSecret, rt.maxAge, rt.insecure)
+	if err != nil {
+		writeOutGoingError(w, err)
+		return
+	}
+
+	reply := rt.Dispatch(r.Context(), msg)
+	if reply == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(reply.Marshaler())
+}
+
+// Dispatch 执行回调消息中的命令，返回回复的消息；用于自行校验回调（例如 DingTalk.OutGoingRequest）的场景
+func (rt *Router) Dispatch(ctx context.Context, msg OutGoingMessage) Message {
+	req := &Request{Context: ctx, Message: msg, router: rt}
+
+	args, err := parseArgs(msg.Text.Content)
+	if err == nil && len(args) == 0 {
+		err = errEmptyCommandInput
+	}
+	if err == nil {
+		req.Command = strings.ToLower(args[0])
+		req.Args = args[1:]
+	}
+
+	if err != nil {
+		return errorMessage(err)
+	}
+
+	rt.mu.RLock()
+	handler := rt.dispatch
+	for i := len(rt.middlewares) - 1; i >= 0; i-- {
+		handler = rt.middlewares[i](handler)
+	}
+	c, ok := rt.commands[req.Command]
+	rt.mu.RUnlock()
+
+	if ok && c.async {
+		if err = rt.runAsync(req, handler); err != nil {
+			return errorMessage(err)
+		}
+		return nil
+	}
+
+	reply, err := handler(req)
+	if err != nil {
+		return errorMessage(err)
+	}
+	return reply
+}
+
+// Shutdown 停止接收异步命令并等待执行中的异步命令完成，ctx 结束时返回 ctx 的错误
+func (rt *Router) Shutdown(ctx context.Context) error {
+	rt.mu.Lock()
+	rt.closed = true
+	rt.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		rt.asyncWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dispatch 查找命令，检查权限和参数后执行
+func (rt *Router) dispatch(req *Request) (Message, error) {
+	rt.mu.RLock()
+	c, ok := rt.commands[req.Command]
+	rt.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w '%s', send '%s' for available commands", ErrUnknownCommand, req.Command, HelpCommand)
+	}
+	if !c.allowed(req.Message) {
+		return nil, fmt.Errorf("'%s': %w", c.name, ErrPermissionDenied)
+	}
+	if len(req.Args) < c.minArgs || (c.maxArgs >= 0 && len(req.Args) > c.maxArgs) {
+		return nil, fmt.Errorf("%w for '%s' command, usage: %s", ErrWrongArgs, c.name, c.help())
+	}
+
+	handler := c.handler
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+	return handler(req)
+}
+
+// runAsync 在后台执行异步命令，全局中间件、权限和参数检查也在后台执行，结果通过 sessionWebhook 回复
+func (rt *Router) runAsync(req *Request, handler HandlerFunc) error {
+	rt.mu.Lock()
+	if rt.closed {
+		rt.mu.Unlock()
+		return ErrRouterClosed
+	}
+	rt.asyncWg.Add(1)
+	rt.mu.Unlock()
+
+	asyncReq := *req
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context), rt.asyncTimeout)
+	asyncReq.Context = ctx
+
+	go func() {
+		defer rt.asyncWg.Done()
+		defer cancel()
+		defer func() {
+			if p := recover(); p != nil {
+				rt.logger.ErrorContext(ctx, "dingtalk async command panic", "command", req.Command, "panic", p)
+			}
+		}()
+
+		reply, err := handler(&asyncReq)
+		if err != nil {
+			reply = errorMessage(err)
+		}
+		if reply == nil {
+			return
+		}
+		if err = asyncReq.Reply(reply); err != nil {
+			rt.logger.ErrorContext(ctx, "dingtalk async command reply failed", "command", req.Command, "err", err)
+		}
+	}()
+
+	return nil
+}
+
+// help 列出发送者有权限执行的命令，help <command> 显示命令的用法
+func (rt *Router) help(req *Request) (Message, error) {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+
+	if len(req.Args) == 1 {
+		c, ok := rt.commands[strings.ToLower(req.Args[0])]
+		if !ok || c.hidden || !c.allowed(req.Message) {
+			return nil, fmt.Errorf("%w '%s'", ErrUnknownCommand, req.Args[0])
+		}
+		return NewTextMsg(c.help()), nil
+	}
+
+	var lines []string
+	for _, c := range rt.commands {
+		if !c.hidden && c.allowed(req.Message) {
+			lines = append(lines, c.help())
+		}
+	}
+	sort.Strings(lines)
+
+	return NewTextMsg("available commands:\n" + strings.Join(lines, "\n")), nil
+}
+
+// reply 通过 sessionWebhook 回复消息
+func (rt *Router) reply(ctx context.Context, msg OutGoingMessage, reply Message) error {
+	if msg.SessionWebhook == "" {
+		return ErrNoSessionWebhook
+	}
+	if msg.SessionWebhookExpiredTime > 0 && time.Now().Add(sessionWebhookMargin).After(time.UnixMilli(msg.SessionWebhookExpiredTime)) {
+		return ErrSessionExpired
+	}
+	return postMessage(ctx, rt.httpClient, msg.SessionWebhook, reply.Marshaler())
+}
+
+func errorMessage(err error) Message {
+	return NewTextMsg("ERR: " + err.Error())
+}
+
+// parseArgs 按空白字符分割参数，单引号、双引号和中文双引号内的空白不分割，反斜杠转义下一个字符
+func parseArgs(content string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune // 当前引号的结束符，0 表示不在引号内
+		escaped bool
+	)
+
+	for _, r := range content {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == '“':
+			quote, inArg = '”', true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, ErrUnclosedQuote
+	}
+	if escaped {
+		current.WriteRune('\\')
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,288 @@
+package dingtalk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		content string
+		args    []string
+		err     error
+	}{
+		{content: " deploy  app\tv1 ", args: []string{"deploy", "app", "v1"}},
+		{content: `deploy "my app" 'v 1'`, args: []string{"deploy", "my app", "v 1"}},
+		{content: `say “你好 世界”`, args: []string{"say", "你好 世界"}},
+		{content: `say a\ b \"c\"`, args: []string{"say", "a b", `"c"`}},
+		{content: `say ""`, args: []string{"say", ""}},
+		{content: "say　全角空格", args: []string{"say", "全角空格"}},
+		{content: `say "open`, err: ErrUnclosedQuote},
+		{content: "  ", args: nil},
+	}
+
+	for _, tt := range tests {
+		args, err := parseArgs(tt.content)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: expected error %v, but %v got", tt.content, tt.err, err)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%q: expected %q, but %q got", tt.content, tt.args, args)
+		}
+	}
+}
+
+func textOf(t *testing.T, msg Message) string {
+	t.Helper()
+	if msg == nil {
+		return ""
+	}
+	var v struct {
+		Text struct {
+			Content string `json:"content"`
+		} `json:"text"`
+	}
+	if err := json.Unmarshal(msg.Marshaler(), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v.Text.Content
+}
+
+func outGoingText(content, sender string) OutGoingMessage {
+	msg := OutGoingMessage{SenderID: sender, ConversationID: "cid"}
+	msg.Text.Content = content
+	return msg
+}
+
+func TestRouterDispatch(t *testing.T) {
+	rt := NewRouter(testAppSecret)
+
+	var order []string
+	rt.Use(func(next HandlerFunc) HandlerFunc {
+		return func(req *Request) (Message, error) {
+			order = append(order, "global")
+			return next(req)
+		}
+	})
+
+	echo := func(req *Request) (Message, error) {
+		order = append(order, "handler")
+		return NewTextMsg(req.SenderID() + ":" + strings.Join(req.Args, "|")), nil
+	}
+	if err := rt.Handle("Echo", echo, WithArgs(1, 2), WithUsage("<a> [b]"), WithDescription("echo args"),
+		WithCommandMiddleware(func(next HandlerFunc) HandlerFunc {
+			return func(req *Request) (Message, error) {
+				order = append(order, "command")
+				return next(req)
+			}
+		})); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.Handle("echo", echo); !errors.Is(err, ErrDuplicateCommand) {
+		t.Errorf("expected ErrDuplicateCommand, but %v got", err)
+	}
+	_ = rt.Handle("ops", echo, WithAllowedSenders("u-ops"), WithDescription("ops only"))
+	_ = rt.Handle("admin", echo, WithAdminOnly())
+	_ = rt.Handle("secret", echo, WithHidden())
+	_ = rt.Handle("fail", func(req *Request) (Message, error) {
+		return nil, errors.New("boom")
+	})
+
+	ctx := context.Background()
+	tests := []struct {
+		content string
+		sender  string
+		reply   string
+	}{
+		{content: `ECHO "a b" c`, sender: "u1", reply: "u1:a b|c"},
+		{content: "echo", sender: "u1", reply: "ERR: wrong number of arguments for 'echo' command, usage: echo <a> [b] - echo args"},
+		{content: "nope", sender: "u1", reply: "ERR: unregistered command 'nope', send 'help' for available commands"},
+		{content: "ops", sender: "u1", reply: "ERR: 'ops': you have no right to do this operation"},
+		{content: "ops", sender: "u-ops", reply: "u-ops:"},
+		{content: "admin", sender: "u1", reply: "ERR: 'admin': you have no right to do this operation"},
+		{content: "fail", sender: "u1", reply: "ERR: boom"},
+		{content: `echo "x`, sender: "u1", reply: "ERR: unclosed quote"},
+		{content: "help", sender: "u1", reply: "available commands:\necho <a> [b] - echo args\nfail\nhelp [command] - show available commands"},
+		{content: "help", sender: "u-ops", reply: "available commands:\necho <a> [b] - echo args\nfail\nhelp [command] - show available commands\nops - ops only"},
+		{content: "help echo", sender: "u1", reply: "echo <a> [b] - echo args"},
+		{content: "help ops", sender: "u1", reply: "ERR: unregistered command 'ops'"},
+	}
+	for _, tt := range tests {
+		if got := textOf(t, rt.Dispatch(ctx, outGoingText(tt.content, tt.sender))); got != tt.reply {
+			t.Errorf("%q: expected %q, but %q got", tt.content, tt.reply, got)
+		}
+	}
+
+	admin := outGoingText("admin", "u2")
+	admin.IsAdmin = true
+	if got := textOf(t, rt.Dispatch(ctx, admin)); got != "u2:" {
+		t.Errorf("expected admin to run command, but %q got", got)
+	}
+
+	order = nil
+	rt.Dispatch(ctx, outGoingText("echo a", "u1"))
+	if want := []string{"global", "command", "handler"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected middleware order %v, but %v got", want, order)
+	}
+}
+
+func TestRouterMiddlewares(t *testing.T) {
+	rt := NewRouter(testAppSecret)
+	rt.Use(RecoverMiddleware(slog.New(slog.DiscardHandler)), AllowSendersMiddleware("u1", "staff2"), RateLimitMiddleware(1, 2))
+	_ = rt.Handle("ping", func(req *Request) (Message, error) {
+		return NewTextMsg("pong"), nil
+	})
+	_ = rt.Handle("panic", func(req *Request) (Message, error) {
+		panic("oops")
+	})
+
+	ctx := context.Background()
+	if got := textOf(t, rt.Dispatch(ctx, outGoingText("ping", "u3"))); got != "ERR: "+ErrPermissionDenied.Error() {
+		t.Errorf("unexpected reply %q", got)
+	}
+	staff := outGoingText("ping", "u3")
+	staff.SenderStaffID = "staff2"
+	if got := textOf(t, rt.Dispatch(ctx, staff)); got != "pong" {
+		t.Errorf("expected staff id to be allowed, but %q got", got)
+	}
+	if got := textOf(t, rt.Dispatch(ctx, outGoingText("panic", "u1"))); got != "ERR: "+ErrCommandPanic.Error() {
+		t.Errorf("unexpected reply %q", got)
+	}
+	// u1 已用掉 1 个令牌，容量为 2
+	if got := textOf(t, rt.Dispatch(ctx, outGoingText("ping", "u1"))); got != "pong" {
+		t.Errorf("unexpected reply %q", got)
+	}
+	if got := textOf(t, rt.Dispatch(ctx, outGoingText("ping", "u1"))); got != "ERR: "+ErrTooManyRequests.Error() {
+		t.Errorf("unexpected reply %q", got)
+	}
+}
+
+func TestRouterAsync(t *testing.T) {
+	srv, received := newTestServer(t, func(int) (int, string) {
+		return http.StatusOK, `{"errcode":0}`
+	})
+
+	rt := NewRouter(testAppSecret, WithRouterHTTPClient(srv.Client()))
+	started := make(chan struct{})
+	_ = rt.Handle("build", func(req *Request) (Message, error) {
+		close(started)
+		if err := req.ReplyText("building " + req.Args[0]); err != nil {
+			return nil, err
+		}
+		time.Sleep(10 * time.Millisecond)
+		return NewTextMsg("done"), nil
+	}, WithArgs(1, 1), WithAsync())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msg := outGoingText("build app", "u1")
+	msg.SessionWebhook = srv.URL + "/robot/sendBySession?session=s1"
+	msg.SessionWebhookExpiredTime = time.Now().Add(time.Hour).UnixMilli()
+	if reply := rt.Dispatch(ctx, msg); reply != nil {
+		t.Errorf("expected no sync reply, but %s got", reply.Marshaler())
+	}
+	<-started
+	cancel() // HTTP 请求结束不影响异步命令
+
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := received()
+	if len(msgs) != 2 {
+		t.Fatalf("expected 2 replies, but %d got", len(msgs))
+	}
+	var contents []string
+	for _, m := range msgs {
+		if m.Query.Get("session") != "s1" {
+			t.Errorf("unexpected query %v", m.Query)
+		}
+		contents = append(contents, m.Body["text"].(map[string]interface{})["content"].(string))
+	}
+	if want := []string{"building app", "done"}; !reflect.DeepEqual(contents, want) {
+		t.Errorf("expected %v, but %v got", want, contents)
+	}
+
+	expired := &Request{Context: context.Background(), Message: msg, router: rt}
+	expired.Message.SessionWebhookExpiredTime = time.Now().UnixMilli()
+	if err := expired.ReplyText("late"); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expected ErrSessionExpired, but %v got", err)
+	}
+}
+
+func TestRouterAsyncMiddlewares(t *testing.T) {
+	srv, received := newTestServer(t, func(int) (int, string) {
+		return http.StatusOK, `{"errcode":0}`
+	})
+
+	rt := NewRouter(testAppSecret, WithRouterHTTPClient(srv.Client()))
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	// 全局中间件在后台执行，能看到异步命令的错误
+	rt.Use(func(next HandlerFunc) HandlerFunc {
+		return func(req *Request) (Message, error) {
+			reply, err := next(req)
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			return reply, err
+		}
+	})
+	failed := errors.New("build failed")
+	_ = rt.Handle("build", func(req *Request) (Message, error) {
+		return nil, failed
+	}, WithAsync())
+
+	msg := outGoingText("build", "u1")
+	msg.SessionWebhook = srv.URL + "/robot/sendBySession?session=s1"
+	if reply := rt.Dispatch(context.Background(), msg); reply != nil {
+		t.Errorf("expected no sync reply, but %s got", reply.Marshaler())
+	}
+	if err := rt.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	if len(errs) != 1 || !errors.Is(errs[0], failed) {
+		t.Errorf("expected middleware to see %v, but %v got", failed, errs)
+	}
+	mu.Unlock()
+	if msgs := received(); len(msgs) != 1 {
+		t.Errorf("expected 1 reply, but %d got", len(msgs))
+	}
+
+	// Shutdown 后拒绝新的异步命令
+	if got := textOf(t, rt.Dispatch(context.Background(), msg)); got != "ERR: "+ErrRouterClosed.Error() {
+		t.Errorf("unexpected reply %q", got)
+	}
+}
+
+func TestRouterServeHTTP(t *testing.T) {
+	rt := NewRouter(testAppSecret)
+	_ = rt.Handle("ping", func(req *Request) (Message, error) {
+		return NewTextMsg("pong"), nil
+	})
+
+	body := `{"senderId":"u1","text":{"content":" ping"}}`
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, newOutGoingRequest("POST", body, time.Now(), testAppSecret))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "pong") {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, newOutGoingRequest("POST", body, time.Now(), "forged"))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, but %d got", w.Code)
+	}
+}
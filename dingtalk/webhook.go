@@ -6,77 +6,304 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"math/rand"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
 )
 
-func InitDingTalk(tokens []string, key string) *DingTalk {
+var ErrNoToken = errors.New("dingtalk: no robot token")
+
+// Option DingTalk 的配置项
+type Option interface {
+	apply(d *DingTalk)
+}
+
+type funcOption struct {
+	f func(d *DingTalk)
+}
+
+func (fo *funcOption) apply(d *DingTalk) {
+	fo.f(d)
+}
+
+func newFuncOption(f func(d *DingTalk)) *funcOption {
+	return &funcOption{f: f}
+}
+
+// WithSecret 加签机器人的密钥
+func WithSecret(secret string) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.secret = secret
+	})
+}
+
+// WithKeyWord 自定义关键词，会追加到消息标题或内容后面
+func WithKeyWord(key string) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.keyWord = key
+	})
+}
+
+// WithHTTPClient 设置发送消息使用的 HTTP 客户端
+func WithHTTPClient(client *http.Client) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.httpClient = client
+	})
+}
+
+// WithBaseURL 设置发送消息的地址，默认为 DefaultBaseURL，测试时可以指向 httptest 服务
+func WithBaseURL(baseURL string) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.baseURL = baseURL
+	})
+}
+
+// WithTimeout 设置单条消息的发送超时时间，小于等于 0 时只受 ctx 控制
+func WithTimeout(timeout time.Duration) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.timeout = timeout
+	})
+}
+
+// WithLogger 设置日志
+func WithLogger(logger *slog.Logger) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.logger = logger
+	})
+}
+
+// WithRateLimit 设置每个机器人 token 的限流：令牌桶容量 burst，每分钟补充 perMinute 个，perMinute 小于等于 0 时不限流。
+// 超过限流时发送会等待，直到有可用的令牌或 ctx 结束。
+func WithRateLimit(perMinute, burst int) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.rateLimit = perMinute
+		d.rateBurst = burst
+	})
+}
+
+// WithRetry 设置 HTTP 5xx、限流错误（130101）和建立连接失败的重试次数，以及指数退避的初始、最大等待时间。
+// 请求已经发出后的超时、响应解析失败等错误不重试，避免重复发送。
+func WithRetry(maxRetries int, initial, max time.Duration) Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.maxRetries = maxRetries
+		d.retryInitial = initial
+		d.retryMax = max
+	})
+}
+
+// New 创建钉钉机器人，单个机器人有单位时间内消息条数的限制，可以传入多个 token
+func New(tokens []string, opts ...Option) (*DingTalk, error) {
 	if len(tokens) == 0 {
-		panic("no token")
+		return nil, ErrNoToken
+	}
+	for _, token := range tokens {
+		if token == "" {
+			return nil, ErrNoToken
+		}
+	}
+
+	d := &DingTalk{
+		robotToken:   tokens,
+		httpClient:   myHTTPClient,
+		baseURL:      DefaultBaseURL,
+		timeout:      DefaultTimeout,
+		logger:       slog.Default(),
+		rateLimit:    DefaultRateLimit,
+		rateBurst:    DefaultRateBurst,
+		maxRetries:   DefaultMaxRetries,
+		retryInitial: DefaultRetryInitial,
+		retryMax:     DefaultRetryMax,
 	}
-	return &DingTalk{
-		robotToken: tokens,
-		keyWord:    key,
+	for _, opt := range opts {
+		opt.apply(d)
 	}
+
+	if _, err := url.Parse(d.baseURL); err != nil {
+		return nil, fmt.Errorf("dingtalk: invalid base url: %w", err)
+	}
+	if d.httpClient == nil {
+		d.httpClient = myHTTPClient
+	}
+	if d.logger == nil {
+		d.logger = slog.Default()
+	}
+	d.limiter = newLimiter(d.robotToken, d.rateLimit, d.rateBurst)
+
+	return d, nil
 }
 
+// InitDingTalk key 创建钉钉机器人需要设置的关键词，与旧版本一致，不限流也不重试
+//
+// Deprecated: 使用 New(tokens, WithKeyWord(key))，参数错误时返回 error 而不是 panic
+func InitDingTalk(tokens []string, key string) *DingTalk {
+	d, err := New(tokens, WithKeyWord(key), withoutLimits())
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// InitDingTalkWithSecret 加签方式创建钉钉机器人，与旧版本一致，不限流也不重试
+//
+// Deprecated: 使用 New([]string{token}, WithSecret(secret))，参数错误时返回 error 而不是 panic
 func InitDingTalkWithSecret(tokens string, secret string) *DingTalk {
-	if len(tokens) == 0 || secret == "" {
-		panic("no token")
+	if secret == "" {
+		panic("no secret")
 	}
-	return &DingTalk{
-		robotToken: []string{tokens},
-		secret:     secret,
+	d, err := New([]string{tokens}, WithSecret(secret), withoutLimits())
+	if err != nil {
+		panic(err)
 	}
+	return d
 }
 
-func (d *DingTalk) sendMessage(msg iDingMsg) error {
-	var (
-		ctx    context.Context
-		cancel context.CancelFunc
-		uri    string
-		resp   *http.Response
-		err    error
-	)
-	ctx, cancel = context.WithTimeout(context.Background(), time.Second*2)
-	defer cancel()
+// withoutLimits 关闭限流和重试，旧的构造函数使用，避免已有的调用方在限流时长时间阻塞
+func withoutLimits() Option {
+	return newFuncOption(func(d *DingTalk) {
+		d.rateLimit = 0
+		d.maxRetries = 0
+	})
+}
+
+// SendError 钉钉返回的发送失败
+type SendError struct {
+	StatusCode int // HTTP 状态码
+	ErrCode    int // 钉钉的错误码
+	ErrMsg     string
+}
+
+func (e *SendError) Error() string {
+	if e.StatusCode != http.StatusOK {
+		return fmt.Sprintf("send msg err. http code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("send msg err. err code: %d, err msg: %s", e.ErrCode, e.ErrMsg)
+}
 
-	value := url.Values{}
-	value.Set("access_token", d.robotToken[rand.Intn(len(d.robotToken))])
+// Temporary 是否为可以重试的错误
+func (e *SendError) Temporary() bool {
+	return e.StatusCode >= 500 || e.ErrCode == ErrCodeSendTooFast
+}
+
+// SendMessageContext 发送消息，消息由 NewTextMsg、NewMarkDownMsg 等创建，不会追加关键词
+func (d *DingTalk) SendMessageContext(ctx context.Context, msg Message) error {
+	return d.sendMessage(ctx, msg)
+}
+
+// sendMessage 按限流选择 token 发送，可重试的错误按指数退避重试
+func (d *DingTalk) sendMessage(ctx context.Context, msg Message) error {
+	body := msg.Marshaler()
+
+	for attempt := 0; ; attempt++ {
+		token, err := d.limiter.acquire(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = d.sendOnce(ctx, token, body)
+		if err == nil {
+			d.logger.DebugContext(ctx, "dingtalk message sent", "msg", string(body))
+			return nil
+		}
+
+		var sendErr *SendError
+		if errors.As(err, &sendErr) && sendErr.ErrCode == ErrCodeSendTooFast {
+			d.limiter.drain(token)
+		}
+		if attempt >= d.maxRetries || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		wait := d.backoff(attempt + 1)
+		d.logger.WarnContext(ctx, "send dingtalk message failed, retrying", "err", err, "attempt", attempt+1, "wait", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// retryable 是否可以重试：钉钉返回的临时错误，或者请求发出之前建立连接失败。
+// 请求发出后的超时、响应解析失败等无法确定消息是否已经发送，不重试；域名解析失败重试也无法恢复，不重试。
+func retryable(err error) bool {
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.Temporary()
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return false
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func (d *DingTalk) sendOnce(ctx context.Context, token string, body []byte) error {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	uri, err := url.Parse(d.baseURL)
+	if err != nil {
+		return err
+	}
+	value := uri.Query()
+	value.Set("access_token", token)
 	if d.secret != "" {
 		t := time.Now().UnixNano() / 1e6
 		value.Set("timestamp", fmt.Sprintf("%d", t))
 		value.Set("sign", d.sign(t, d.secret))
 
 	}
-	uri = dingTalkURL + value.Encode()
+	uri.RawQuery = value.Encode()
+	return postMessage(ctx, d.httpClient, uri.String(), body)
+}
+
+// postMessage 发送消息并检查钉钉返回的错误码，失败时返回 *SendError
+func postMessage(ctx context.Context, client *http.Client, uri string, body []byte) error {
 	header := map[string]string{
 		"Content-type": "application/json",
 	}
-	resp, err = doRequest(ctx, "POST", uri, header, msg.Marshaler())
-
+	resp, err := doRequest(ctx, client, "POST", uri, header, body)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("send msg err. http code: %d, token: %s, msg: %s", resp.StatusCode, d.robotToken, msg.Marshaler())
+		return &SendError{StatusCode: resp.StatusCode}
 	}
-	body, _ := io.ReadAll(resp.Body)
+	respBody, _ := io.ReadAll(resp.Body)
 	var respMsg responseMsg
-	err = json.Unmarshal(body, &respMsg)
+	err = json.Unmarshal(respBody, &respMsg)
 	if err != nil {
 		return err
 	}
 	if respMsg.ErrCode != 0 {
-		return fmt.Errorf("send msg err. err msg: %s", respMsg.ErrMsg)
+		return &SendError{StatusCode: resp.StatusCode, ErrCode: respMsg.ErrCode, ErrMsg: respMsg.ErrMsg}
 	}
 	return nil
 }
 
+func (d *DingTalk) backoff(attempt int) time.Duration {
+	wait := d.retryInitial << (attempt - 1)
+	if wait <= 0 || wait > d.retryMax {
+		wait = d.retryMax
+	}
+	return wait
+}
+
 func (d *DingTalk) sign(t int64, secret string) string {
 	strToHash := fmt.Sprintf("%d\n%s", t, secret)
 	hmac256 := hmac.New(sha256.New, []byte(secret))
@@ -85,7 +312,10 @@ func (d *DingTalk) sign(t int64, secret string) string {
 	return base64.StdEncoding.EncodeToString(data)
 }
 
-func (d *DingTalk) OutGoing(r io.Reader) (outGoingMsg outGoingModel, err error) {
+// OutGoing 解析回调消息，不校验签名
+//
+// Deprecated: 使用 OutGoingRequest 校验回调的签名和时间戳
+func (d *DingTalk) OutGoing(r io.Reader) (outGoingMsg OutGoingMessage, err error) {
 	buf, err := io.ReadAll(r)
 	if err != nil {
 		return
@@ -95,52 +325,84 @@ func (d *DingTalk) OutGoing(r io.Reader) (outGoingMsg outGoingModel, err error)
 }
 
 func (d *DingTalk) SendTextMessage(content string, opt ...atOption) error {
+	return d.SendTextMessageContext(context.Background(), content, opt...)
+}
+
+func (d *DingTalk) SendTextMessageContext(ctx context.Context, content string, opt ...atOption) error {
 	content = content + d.keyWord
-	return d.sendMessage(NewTextMsg(content, opt...))
+	return d.sendMessage(ctx, NewTextMsg(content, opt...))
 }
 
 func (d *DingTalk) SendMarkDownMessage(title, text string, opts ...atOption) error {
+	return d.SendMarkDownMessageContext(context.Background(), title, text, opts...)
+}
+
+func (d *DingTalk) SendMarkDownMessageContext(ctx context.Context, title, text string, opts ...atOption) error {
 	title = title + d.keyWord
-	return d.sendMessage(NewMarkDownMsg(title, text, opts...))
+	return d.sendMessage(ctx, NewMarkDownMsg(title, text, opts...))
 }
 
 // SendDTMDMessage 利用dtmd发送点击消息
 func (d *DingTalk) SendDTMDMessage(title string, dtmdMap *dingMap, opt ...atOption) error {
+	return d.SendDTMDMessageContext(context.Background(), title, dtmdMap, opt...)
+}
+
+func (d *DingTalk) SendDTMDMessageContext(ctx context.Context, title string, dtmdMap *dingMap, opt ...atOption) error {
 	title = title + d.keyWord
-	return d.sendMessage(NewDTMDMsg(title, dtmdMap, opt...))
+	return d.sendMessage(ctx, NewDTMDMsg(title, dtmdMap, opt...))
 }
 
 func (d *DingTalk) SendMarkDownMessageBySlice(title string, textList []string, opts ...atOption) error {
+	return d.SendMarkDownMessageBySliceContext(context.Background(), title, textList, opts...)
+}
+
+func (d *DingTalk) SendMarkDownMessageBySliceContext(ctx context.Context, title string, textList []string, opts ...atOption) error {
 	title = title + d.keyWord
 	text := ""
 	for _, t := range textList {
 		text = text + "\n" + t
 	}
-	return d.sendMessage(NewMarkDownMsg(title, text, opts...))
+	return d.sendMessage(ctx, NewMarkDownMsg(title, text, opts...))
 }
 
 func (d *DingTalk) SendLinkMessage(title, text, picUrl, msgUrl string) error {
+	return d.SendLinkMessageContext(context.Background(), title, text, picUrl, msgUrl)
+}
+
+func (d *DingTalk) SendLinkMessageContext(ctx context.Context, title, text, picUrl, msgUrl string) error {
 	title = title + d.keyWord
-	return d.sendMessage(NewLinkMsg(title, text, picUrl, msgUrl))
+	return d.sendMessage(ctx, NewLinkMsg(title, text, picUrl, msgUrl))
 }
 
 func (d *DingTalk) SendActionCardMessage(title, text string, opts ...actionCardOption) error {
+	return d.SendActionCardMessageContext(context.Background(), title, text, opts...)
+}
+
+func (d *DingTalk) SendActionCardMessageContext(ctx context.Context, title, text string, opts ...actionCardOption) error {
 	title = title + d.keyWord
-	return d.sendMessage(NewActionCardMsg(title, text, opts...))
+	return d.sendMessage(ctx, NewActionCardMsg(title, text, opts...))
 }
 
 func (d *DingTalk) SendActionCardMessageBySlice(title string, textList []string, opts ...actionCardOption) error {
+	return d.SendActionCardMessageBySliceContext(context.Background(), title, textList, opts...)
+}
+
+func (d *DingTalk) SendActionCardMessageBySliceContext(ctx context.Context, title string, textList []string, opts ...actionCardOption) error {
 	title = title + d.keyWord
 	text := ""
 	for _, t := range textList {
 		text = text + "\n" + t
 	}
-	return d.sendMessage(NewActionCardMsg(title, text, opts...))
+	return d.sendMessage(ctx, NewActionCardMsg(title, text, opts...))
 }
 
 func (d *DingTalk) SendFeedCardMessage(feedCard []FeedCardLinkModel) error {
+	return d.SendFeedCardMessageContext(context.Background(), feedCard)
+}
+
+func (d *DingTalk) SendFeedCardMessageContext(ctx context.Context, feedCard []FeedCardLinkModel) error {
 	if len(feedCard) > 0 {
 		feedCard[0].Title = feedCard[0].Title + d.keyWord
 	}
-	return d.sendMessage(NewFeedCardMsg(feedCard))
+	return d.sendMessage(ctx, NewFeedCardMsg(feedCard))
 }
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrCacheMiss = errors.New("cache miss")
+
+// Backend 缓存后端，按标签批量失效
+type Backend interface {
+	// Get 读取缓存，未命中时返回 ErrCacheMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set 写入缓存，并将其关联到标签
+	Set(ctx context.Context, key string, value []byte, tags []string, ttl time.Duration) error
+
+	// InvalidateTags 失效所有关联到这些标签的缓存
+	InvalidateTags(ctx context.Context, tags ...string) error
+}
+
+type ctxOptionsKey struct{}
+
+type ctxOptions struct {
+	skip bool
+	tags []string
+	ttl  time.Duration
+}
+
+func optionsFromContext(ctx context.Context) ctxOptions {
+	if opts, ok := ctx.Value(ctxOptionsKey{}).(ctxOptions); ok {
+		return opts
+	}
+	return ctxOptions{}
+}
+
+// Skip 跳过缓存，直接查询数据库
+func Skip(ctx context.Context) context.Context {
+	opts := optionsFromContext(ctx)
+	opts.skip = true
+	return context.WithValue(ctx, ctxOptionsKey{}, opts)
+}
+
+// WithTags 为查询追加缓存标签，默认标签是从SQL中解析出的表名
+func WithTags(ctx context.Context, tags ...string) context.Context {
+	opts := optionsFromContext(ctx)
+	opts.tags = append(append([]string{}, opts.tags...), tags...)
+	return context.WithValue(ctx, ctxOptionsKey{}, opts)
+}
+
+// WithTTL 设置查询结果的缓存时间，覆盖驱动的默认值
+func WithTTL(ctx context.Context, ttl time.Duration) context.Context {
+	opts := optionsFromContext(ctx)
+	opts.ttl = ttl
+	return context.WithValue(ctx, ctxOptionsKey{}, opts)
+}
@@ -0,0 +1,272 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	stdsql "database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/alec404/go-libs/entgo/txhook"
+)
+
+const (
+	DefaultTTL       = time.Minute // 默认缓存时间
+	DefaultKeyPrefix = "entcache:" // 默认缓存键前缀
+)
+
+// 确保 Driver 实现了 dialect.Driver 接口
+var _ dialect.Driver = (*Driver)(nil)
+
+// Driver 带查询结果缓存的驱动，只缓存事务外的 SELECT 查询。
+// 通过驱动执行的写语句会失效涉及的表的缓存，事务中的写语句在提交成功后失效；
+// 自定义标签（WithTags）的缓存通过 InvalidateHook 失效。
+type Driver struct {
+	dialect.Driver
+
+	backend   Backend
+	ttl       time.Duration
+	keyPrefix string
+}
+
+type Option func(d *Driver)
+
+// WithDefaultTTL 设置默认缓存时间
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(d *Driver) {
+		d.ttl = ttl
+	}
+}
+
+// WithKeyPrefix 设置缓存键前缀
+func WithKeyPrefix(prefix string) Option {
+	return func(d *Driver) {
+		d.keyPrefix = prefix
+	}
+}
+
+// NewDriver 创建带缓存的驱动，用法：ent.NewClient(ent.Driver(cache.NewDriver(drv, backend)))
+func NewDriver(drv dialect.Driver, backend Backend, opts ...Option) *Driver {
+	d := &Driver{
+		Driver:    txhook.NewDriver(drv),
+		backend:   backend,
+		ttl:       DefaultTTL,
+		keyPrefix: DefaultKeyPrefix,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Backend 返回缓存后端
+func (d *Driver) Backend() Backend {
+	return d.backend
+}
+
+// Exec 执行语句，成功后失效涉及的表的缓存
+func (d *Driver) Exec(ctx context.Context, query string, args, v any) error {
+	if err := d.Driver.Exec(ctx, query, args, v); err != nil {
+		return err
+	}
+
+	d.invalidate(ctx, ExtractTables(query))
+	return nil
+}
+
+// Query 查询数据，命中缓存时不访问数据库；写语句（例如 INSERT ... RETURNING）不缓存，成功后失效涉及的表的缓存
+func (d *Driver) Query(ctx context.Context, query string, args, v any) error {
+	if IsWriteQuery(query) {
+		if err := d.Driver.Query(ctx, query, args, v); err != nil {
+			return err
+		}
+
+		d.invalidate(ctx, ExtractTables(query))
+		return nil
+	}
+
+	rows, ok := v.(*sql.Rows)
+	if !ok {
+		return d.Driver.Query(ctx, query, args, v)
+	}
+
+	opts := optionsFromContext(ctx)
+	if opts.skip {
+		return d.Driver.Query(ctx, query, args, v)
+	}
+
+	key, err := d.cacheKey(query, args)
+	if err != nil {
+		return d.Driver.Query(ctx, query, args, v)
+	}
+
+	if data, err := d.backend.Get(ctx, key); err == nil {
+		if e, err := unmarshalEntry(data); err == nil {
+			rows.ColumnScanner = newReplayRows(e)
+			return nil
+		} else {
+			log.Errorf("decode query cache failed: %s", err.Error())
+		}
+	} else if !errors.Is(err, ErrCacheMiss) {
+		log.Errorf("get query cache failed: %s", err.Error())
+	}
+
+	var dbRows sql.Rows
+	if err = d.Driver.Query(ctx, query, args, &dbRows); err != nil {
+		return err
+	}
+
+	e, err := readEntry(&dbRows)
+	if err != nil {
+		return err
+	}
+	rows.ColumnScanner = newReplayRows(e)
+
+	ttl := d.ttl
+	if opts.ttl > 0 {
+		ttl = opts.ttl
+	}
+	tags := append(ExtractTables(query), opts.tags...)
+
+	if data, err := e.marshal(); err != nil {
+		log.Errorf("encode query cache failed: %s", err.Error())
+	} else if err = d.backend.Set(ctx, key, data, tags, ttl); err != nil {
+		log.Errorf("set query cache failed: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Tx 开启事务，事务中的查询不使用缓存，写语句涉及的表在提交成功后失效
+func (d *Driver) Tx(ctx context.Context) (dialect.Tx, error) {
+	tx, err := d.Driver.Tx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return d.wrapTx(tx), nil
+}
+
+// BeginTx 以指定的隔离级别开启事务，参见 Tx
+func (d *Driver) BeginTx(ctx context.Context, opts *stdsql.TxOptions) (dialect.Tx, error) {
+	tx, err := d.Driver.(*txhook.Driver).BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return d.wrapTx(tx), nil
+}
+
+func (d *Driver) wrapTx(tx dialect.Tx) dialect.Tx {
+	t := &cacheTx{Tx: tx.(*txhook.Tx), d: d, tables: make(map[string]struct{})}
+	t.Tx.OnCommit(t.invalidate)
+	return t
+}
+
+func (d *Driver) invalidate(ctx context.Context, tables []string) {
+	if len(tables) == 0 {
+		return
+	}
+	if err := d.backend.InvalidateTags(ctx, tables...); err != nil {
+		log.Errorf("invalidate query cache failed: %s", err.Error())
+	}
+}
+
+// cacheTx 记录事务中写语句涉及的表，提交成功后失效
+type cacheTx struct {
+	*txhook.Tx
+	d *Driver
+
+	mu     sync.Mutex
+	tables map[string]struct{}
+}
+
+func (t *cacheTx) Exec(ctx context.Context, query string, args, v any) error {
+	if err := t.Tx.Exec(ctx, query, args, v); err != nil {
+		return err
+	}
+	t.record(query)
+	return nil
+}
+
+func (t *cacheTx) Query(ctx context.Context, query string, args, v any) error {
+	if err := t.Tx.Query(ctx, query, args, v); err != nil {
+		return err
+	}
+	if IsWriteQuery(query) {
+		t.record(query)
+	}
+	return nil
+}
+
+func (t *cacheTx) record(query string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, table := range ExtractTables(query) {
+		t.tables[table] = struct{}{}
+	}
+}
+
+func (t *cacheTx) invalidate() {
+	t.mu.Lock()
+	tables := make([]string, 0, len(t.tables))
+	for table := range t.tables {
+		tables = append(tables, table)
+	}
+	t.mu.Unlock()
+
+	t.d.invalidate(context.Background(), tables)
+}
+
+// cacheKey 以归一化的SQL和参数计算缓存键
+func (d *Driver) cacheKey(query string, args any) (string, error) {
+	argsData, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(NormalizeQuery(query)))
+	h.Write([]byte{0})
+	h.Write(argsData)
+
+	return d.keyPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var (
+	tableRegexp      = regexp.MustCompile("(?i)\\b(?:FROM|JOIN|INTO|UPDATE|TRUNCATE(?:\\s+TABLE)?)\\s+(?:[`\"]?\\w+[`\"]?\\.)?[`\"]?(\\w+)[`\"]?")
+	writeQueryRegexp = regexp.MustCompile(`(?i)^\s*(?:INSERT|UPDATE|DELETE|REPLACE|MERGE|TRUNCATE)\b`)
+)
+
+// NormalizeQuery 归一化SQL，合并多余的空白字符
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// IsWriteQuery 是否为写语句
+func IsWriteQuery(query string) bool {
+	return writeQueryRegexp.MatchString(query)
+}
+
+// ExtractTables 从SQL中解析出涉及的表名，带有 schema 前缀（例如 "public"."users"）时只保留表名
+func ExtractTables(query string) []string {
+	var tables []string
+	seen := make(map[string]struct{})
+	for _, match := range tableRegexp.FindAllStringSubmatch(query, -1) {
+		table := match[1]
+		if _, ok := seen[table]; ok {
+			continue
+		}
+		seen[table] = struct{}{}
+		tables = append(tables, table)
+	}
+	return tables
+}
@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDriver struct {
+	queries int
+	execs   int
+	entry   *entry
+}
+
+func (d *fakeDriver) Exec(context.Context, string, any, any) error {
+	d.execs++
+	return nil
+}
+
+func (d *fakeDriver) Query(_ context.Context, _ string, _, v any) error {
+	d.queries++
+	v.(*sql.Rows).ColumnScanner = newReplayRows(d.entry)
+	return nil
+}
+
+func (d *fakeDriver) Tx(context.Context) (dialect.Tx, error) {
+	return dialect.NopTx(d), nil
+}
+
+func (d *fakeDriver) Close() error { return nil }
+
+func (d *fakeDriver) Dialect() string { return dialect.MySQL }
+
+func scanUsers(t *testing.T, drv dialect.Driver, ctx context.Context) ([]string, []uint32) {
+	var rows sql.Rows
+	err := drv.Query(ctx, "SELECT `id`, `name`\n  FROM `users` WHERE `id` > ?", []any{0}, &rows)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var names []string
+	var ids []uint32
+	for rows.Next() {
+		var id uint32
+		var name sql.NullString
+		require.NoError(t, rows.Scan(&id, &name))
+		ids = append(ids, id)
+		names = append(names, name.String)
+	}
+	return names, ids
+}
+
+func TestDriverQuery(t *testing.T) {
+	fake := &fakeDriver{entry: &entry{
+		Columns: []string{"id", "name"},
+		Values: [][]any{
+			{int64(1), []byte("tom")},
+			{int64(2), "jimmy"},
+		},
+	}}
+	backend := NewLRU(10)
+	drv := NewDriver(fake, backend)
+
+	names, ids := scanUsers(t, drv, context.Background())
+	require.Equal(t, []string{"tom", "jimmy"}, names)
+	require.Equal(t, []uint32{1, 2}, ids)
+	require.Equal(t, 1, fake.queries)
+
+	names, ids = scanUsers(t, drv, context.Background())
+	require.Equal(t, []string{"tom", "jimmy"}, names)
+	require.Equal(t, []uint32{1, 2}, ids)
+	require.Equal(t, 1, fake.queries)
+
+	_, _ = scanUsers(t, drv, Skip(context.Background()))
+	require.Equal(t, 2, fake.queries)
+
+	require.NoError(t, backend.InvalidateTags(context.Background(), "users"))
+	require.Equal(t, 0, backend.Len())
+
+	_, _ = scanUsers(t, drv, context.Background())
+	require.Equal(t, 3, fake.queries)
+}
+
+func TestDriverWrite(t *testing.T) {
+	fake := &fakeDriver{entry: &entry{Columns: []string{"id", "name"}, Values: [][]any{{int64(1), "tom"}}}}
+	backend := NewLRU(10)
+	drv := NewDriver(fake, backend)
+	ctx := context.Background()
+
+	_, _ = scanUsers(t, drv, ctx)
+	require.Equal(t, 1, backend.Len())
+
+	// 写语句直接访问数据库并失效缓存
+	require.NoError(t, drv.Exec(ctx, "UPDATE `users` SET `name` = ?", []any{"tom"}, nil))
+	require.Equal(t, 1, fake.execs)
+	require.Equal(t, 0, backend.Len())
+
+	_, _ = scanUsers(t, drv, ctx)
+	require.Equal(t, 1, backend.Len())
+
+	var rows sql.Rows
+	require.NoError(t, drv.Query(ctx, `INSERT INTO "users" ("name") VALUES ($1) RETURNING "id"`, []any{"tom"}, &rows))
+	require.NoError(t, rows.Close())
+	require.NoError(t, drv.Query(ctx, `INSERT INTO "users" ("name") VALUES ($1) RETURNING "id"`, []any{"tom"}, &rows))
+	require.NoError(t, rows.Close())
+	require.Equal(t, 4, fake.queries)
+	require.Equal(t, 0, backend.Len())
+}
+
+func TestDriverTx(t *testing.T) {
+	fake := &fakeDriver{entry: &entry{Columns: []string{"id", "name"}, Values: [][]any{{int64(1), "tom"}}}}
+	backend := NewLRU(10)
+	drv := NewDriver(fake, backend)
+	ctx := context.Background()
+
+	_, _ = scanUsers(t, drv, ctx)
+
+	// 回滚时不失效
+	tx, err := drv.Tx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Exec(ctx, "DELETE FROM `users`", []any{}, nil))
+	require.NoError(t, tx.Rollback())
+	require.Equal(t, 1, backend.Len())
+
+	// 提交之后才失效
+	tx, err = drv.Tx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Exec(ctx, "DELETE FROM `users`", []any{}, nil))
+	require.Equal(t, 1, backend.Len())
+	require.NoError(t, tx.Commit())
+	require.Equal(t, 0, backend.Len())
+}
+
+type fakeMutation struct {
+	ent.Mutation
+}
+
+func (fakeMutation) Type() string { return "User" }
+
+func TestInvalidateHook(t *testing.T) {
+	fake := &fakeDriver{entry: &entry{Columns: []string{"id", "name"}, Values: [][]any{{int64(1), "tom"}}}}
+	backend := NewLRU(10)
+	drv := NewDriver(fake, backend)
+	ctx := WithTags(context.Background(), "user_detail")
+
+	tx, err := drv.Tx(context.Background())
+	require.NoError(t, err)
+
+	hook := InvalidateHook(backend, "user_detail")
+	mutate := func(tx dialect.ExecQuerier) {
+		_, err := hook(ent.MutateFunc(func(ctx context.Context, _ ent.Mutation) (ent.Value, error) {
+			return nil, tx.Exec(ctx, "UPDATE `profiles` SET `name` = ?", []any{"tom"}, nil)
+		})).Mutate(context.Background(), fakeMutation{})
+		require.NoError(t, err)
+	}
+
+	// 事务中变更时提交后失效
+	_, _ = scanUsers(t, drv, ctx)
+	mutate(tx)
+	require.Equal(t, 1, backend.Len())
+	require.NoError(t, tx.Commit())
+	require.Equal(t, 0, backend.Len())
+
+	// 不在事务中时立即失效
+	_, _ = scanUsers(t, drv, ctx)
+	mutate(drv)
+	require.Equal(t, 0, backend.Len())
+}
+
+func TestDriverCacheKey(t *testing.T) {
+	drv := NewDriver(&fakeDriver{}, NewLRU(10))
+
+	key1, err := drv.cacheKey("SELECT *  FROM `users`\nWHERE `id` = ?", []any{1})
+	require.NoError(t, err)
+	key2, err := drv.cacheKey("SELECT * FROM `users` WHERE `id` = ?", []any{1})
+	require.NoError(t, err)
+	key3, err := drv.cacheKey("SELECT * FROM `users` WHERE `id` = ?", []any{2})
+	require.NoError(t, err)
+
+	require.Equal(t, key1, key2)
+	require.NotEqual(t, key1, key3)
+}
+
+func TestExtractTables(t *testing.T) {
+	require.Equal(t,
+		[]string{"users", "user_roles"},
+		ExtractTables("SELECT * FROM `users` JOIN `user_roles` AS `t1` ON `users`.`id` = `t1`.`user_id`"),
+	)
+	require.Equal(t,
+		[]string{"users"},
+		ExtractTables(`SELECT COUNT(*) FROM "users" WHERE "users"."id" IN (SELECT "id" FROM "users")`),
+	)
+	require.Equal(t, []string{"users"}, ExtractTables("TRUNCATE TABLE `users`"))
+
+	// 带有 schema 前缀时只保留表名
+	require.Equal(t,
+		[]string{"users", "user_roles"},
+		ExtractTables(`SELECT * FROM "public"."users" JOIN "public"."user_roles" ON "users"."id" = "user_roles"."user_id"`),
+	)
+	require.Equal(t, []string{"users"}, ExtractTables("UPDATE public.users SET name = $1"))
+	require.Equal(t, []string{"users"}, ExtractTables("INSERT INTO `app`.`users` (`name`) VALUES (?)"))
+}
+
+func TestIsWriteQuery(t *testing.T) {
+	require.True(t, IsWriteQuery(`INSERT INTO "users" ("name") VALUES ($1) RETURNING "id"`))
+	require.True(t, IsWriteQuery("\n update `users` SET `name` = ?"))
+	require.False(t, IsWriteQuery("SELECT * FROM `users` FOR UPDATE"))
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+
+	"entgo.io/ent"
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/alec404/go-libs/entgo"
+	"github.com/alec404/go-libs/entgo/txhook"
+)
+
+// InvalidateHook 变更成功后失效标签的缓存，未指定标签时按 ent 的默认规则由类型名推导表名，
+// 用法：client.User.Use(cache.InvalidateHook(backend, user.Table, "user_profile"))
+//
+// 在事务中变更时，缓存在事务提交成功后失效，回滚时不失效，需要驱动使用 NewDriver 或 txhook.NewDriver 包装。
+func InvalidateHook(backend Backend, tags ...string) ent.Hook {
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			ctx, scope := txhook.NewScope(ctx)
+
+			v, err := next.Mutate(ctx, m)
+			if err != nil {
+				return v, err
+			}
+
+			invalidateTags := tags
+			if len(invalidateTags) == 0 {
+				invalidateTags = []string{entgo.DefaultTableName(m.Type())}
+			}
+
+			scope.OnCommit(func() {
+				if err := backend.InvalidateTags(context.WithoutCancel(ctx), invalidateTags...); err != nil {
+					log.Errorf("invalidate query cache failed: %s", err.Error())
+				}
+			})
+
+			return v, nil
+		})
+	}
+}
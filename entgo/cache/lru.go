@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const DefaultLRUSize = 1024 // 默认最大缓存条数
+
+// 确保 LRU 实现了 Backend 接口
+var _ Backend = (*LRU)(nil)
+
+type lruItem struct {
+	key      string
+	value    []byte
+	tags     []string
+	expireAt time.Time
+}
+
+// LRU 进程内的LRU缓存后端
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+	tags  map[string]map[string]struct{}
+}
+
+// NewLRU 创建LRU缓存后端，size 为最大缓存条数
+func NewLRU(size int) *LRU {
+	if size <= 0 {
+		size = DefaultLRUSize
+	}
+	return &LRU{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		tags:  make(map[string]map[string]struct{}),
+	}
+}
+
+func (c *LRU) Get(_ context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	item := elem.Value.(*lruItem)
+	if !item.expireAt.IsZero() && time.Now().After(item.expireAt) {
+		c.removeElement(elem)
+		return nil, ErrCacheMiss
+	}
+
+	c.ll.MoveToFront(elem)
+	return item.value, nil
+}
+
+func (c *LRU) Set(_ context.Context, key string, value []byte, tags []string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+
+	item := &lruItem{key: key, value: value, tags: tags}
+	if ttl > 0 {
+		item.expireAt = time.Now().Add(ttl)
+	}
+	c.items[key] = c.ll.PushFront(item)
+
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for c.ll.Len() > c.size {
+		c.removeElement(c.ll.Back())
+	}
+
+	return nil
+}
+
+func (c *LRU) InvalidateTags(_ context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			if elem, ok := c.items[key]; ok {
+				c.removeElement(elem)
+			}
+		}
+		delete(c.tags, tag)
+	}
+
+	return nil
+}
+
+// Len 返回当前缓存条数
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRU) removeElement(elem *list.Element) {
+	item := c.ll.Remove(elem).(*lruItem)
+	delete(c.items, item.key)
+	for _, tag := range item.tags {
+		if keys, ok := c.tags[tag]; ok {
+			delete(keys, item.key)
+			if len(keys) == 0 {
+				delete(c.tags, tag)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Evict", func(t *testing.T) {
+		c := NewLRU(2)
+		require.NoError(t, c.Set(ctx, "a", []byte("1"), nil, 0))
+		require.NoError(t, c.Set(ctx, "b", []byte("2"), nil, 0))
+
+		_, err := c.Get(ctx, "a")
+		require.NoError(t, err)
+
+		require.NoError(t, c.Set(ctx, "c", []byte("3"), nil, 0))
+		require.Equal(t, 2, c.Len())
+
+		_, err = c.Get(ctx, "b")
+		require.ErrorIs(t, err, ErrCacheMiss)
+
+		v, err := c.Get(ctx, "a")
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), v)
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		c := NewLRU(2)
+		require.NoError(t, c.Set(ctx, "a", []byte("1"), nil, time.Millisecond))
+		time.Sleep(5 * time.Millisecond)
+
+		_, err := c.Get(ctx, "a")
+		require.ErrorIs(t, err, ErrCacheMiss)
+		require.Equal(t, 0, c.Len())
+	})
+
+	t.Run("InvalidateTags", func(t *testing.T) {
+		c := NewLRU(10)
+		require.NoError(t, c.Set(ctx, "a", []byte("1"), []string{"users"}, 0))
+		require.NoError(t, c.Set(ctx, "b", []byte("2"), []string{"users", "roles"}, 0))
+		require.NoError(t, c.Set(ctx, "c", []byte("3"), []string{"roles"}, 0))
+
+		require.NoError(t, c.InvalidateTags(ctx, "users"))
+		require.Equal(t, 1, c.Len())
+
+		_, err := c.Get(ctx, "c")
+		require.NoError(t, err)
+
+		require.NoError(t, c.InvalidateTags(ctx, "roles"))
+		require.Equal(t, 0, c.Len())
+		require.Empty(t, c.tags)
+	})
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// RedisClient 缓存所需的Redis命令，可用 go-redis 等客户端简单包装实现
+type RedisClient interface {
+	// Get 读取键值，键不存在时返回 ErrCacheMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// Set 写入键值并设置过期时间，ttl 为 0 时不过期
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// SAdd 向集合添加成员
+	SAdd(ctx context.Context, key string, members ...string) error
+
+	// Expire 设置键的过期时间
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+
+	// SMembers 读取集合的所有成员
+	SMembers(ctx context.Context, key string) ([]string, error)
+
+	// Del 删除键
+	Del(ctx context.Context, keys ...string) error
+}
+
+// 确保 Redis 实现了 Backend 接口
+var _ Backend = (*Redis)(nil)
+
+const (
+	DefaultRedisTagPrefix = "entcache:tag:" // 默认标签集合的键前缀
+	DefaultRedisTagTTL    = time.Hour       // 默认标签集合的最短过期时间
+)
+
+// Redis 基于Redis的缓存后端，每个标签对应一个保存缓存键的集合
+type Redis struct {
+	client    RedisClient
+	tagPrefix string
+	tagTTL    time.Duration
+}
+
+type RedisOption func(r *Redis)
+
+// WithRedisTagTTL 设置标签集合的最短过期时间，需要不小于使用的最长缓存时间。
+// 每次写入缓存时标签集合的过期时间刷新为 max(缓存时间, tagTTL)，避免集合中残留已过期的缓存键。
+func WithRedisTagTTL(ttl time.Duration) RedisOption {
+	return func(r *Redis) {
+		r.tagTTL = ttl
+	}
+}
+
+// NewRedis 创建Redis缓存后端
+func NewRedis(client RedisClient, opts ...RedisOption) *Redis {
+	r := &Redis{
+		client:    client,
+		tagPrefix: DefaultRedisTagPrefix,
+		tagTTL:    DefaultRedisTagTTL,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	return r.client.Get(ctx, key)
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, tags []string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		tagKey := r.tagPrefix + tag
+		if err := r.client.SAdd(ctx, tagKey, key); err != nil {
+			return err
+		}
+		// 不过期的缓存需要标签集合一直保留
+		if ttl > 0 {
+			if err := r.client.Expire(ctx, tagKey, max(ttl, r.tagTTL)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Redis) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		tagKey := r.tagPrefix + tag
+
+		keys, err := r.client.SMembers(ctx, tagKey)
+		if err != nil {
+			return err
+		}
+
+		if err = r.client.Del(ctx, append(keys, tagKey)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
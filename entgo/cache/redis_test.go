@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRedisClient struct {
+	values  map[string][]byte
+	sets    map[string][]string
+	expires map[string]time.Duration
+}
+
+func newFakeRedisClient() *fakeRedisClient {
+	return &fakeRedisClient{
+		values:  make(map[string][]byte),
+		sets:    make(map[string][]string),
+		expires: make(map[string]time.Duration),
+	}
+}
+
+func (c *fakeRedisClient) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := c.values[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return v, nil
+}
+
+func (c *fakeRedisClient) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.values[key] = value
+	c.expires[key] = ttl
+	return nil
+}
+
+func (c *fakeRedisClient) SAdd(_ context.Context, key string, members ...string) error {
+	c.sets[key] = append(c.sets[key], members...)
+	return nil
+}
+
+func (c *fakeRedisClient) Expire(_ context.Context, key string, ttl time.Duration) error {
+	c.expires[key] = ttl
+	return nil
+}
+
+func (c *fakeRedisClient) SMembers(_ context.Context, key string) ([]string, error) {
+	return c.sets[key], nil
+}
+
+func (c *fakeRedisClient) Del(_ context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(c.values, key)
+		delete(c.sets, key)
+		delete(c.expires, key)
+	}
+	return nil
+}
+
+func TestRedis(t *testing.T) {
+	client := newFakeRedisClient()
+	r := NewRedis(client, WithRedisTagTTL(10*time.Minute))
+	ctx := context.Background()
+
+	require.NoError(t, r.Set(ctx, "k1", []byte("v1"), []string{"users"}, time.Minute))
+	require.NoError(t, r.Set(ctx, "k2", []byte("v2"), []string{"users", "roles"}, time.Hour))
+	require.Equal(t, []string{"k1", "k2"}, client.sets[DefaultRedisTagPrefix+"users"])
+
+	// 标签集合的过期时间不小于缓存时间
+	require.Equal(t, time.Hour, client.expires[DefaultRedisTagPrefix+"users"])
+	require.Equal(t, time.Hour, client.expires[DefaultRedisTagPrefix+"roles"])
+
+	require.NoError(t, r.Set(ctx, "k3", []byte("v3"), []string{"depts"}, time.Minute))
+	require.Equal(t, 10*time.Minute, client.expires[DefaultRedisTagPrefix+"depts"])
+
+	v, err := r.Get(ctx, "k2")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v2"), v)
+
+	require.NoError(t, r.InvalidateTags(ctx, "users"))
+	_, err = r.Get(ctx, "k1")
+	require.ErrorIs(t, err, ErrCacheMiss)
+	_, err = r.Get(ctx, "k2")
+	require.ErrorIs(t, err, ErrCacheMiss)
+	_, err = r.Get(ctx, "k3")
+	require.NoError(t, err)
+}
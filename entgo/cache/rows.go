@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"bytes"
+	stdsql "database/sql"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"entgo.io/ent/dialect/sql"
+)
+
+func init() {
+	gob.Register(time.Time{})
+}
+
+// entry 缓存的查询结果
+type entry struct {
+	Columns []string
+	Values  [][]any
+}
+
+func (e *entry) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalEntry(data []byte) (*entry, error) {
+	var e entry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// readEntry 读出所有行并关闭结果集
+func readEntry(rows *sql.Rows) (e *entry, err error) {
+	defer func() {
+		if cerr := rows.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	e = &entry{Columns: columns}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		e.Values = append(e.Values, values)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// replayRows 用缓存的结果回放 sql.ColumnScanner
+type replayRows struct {
+	entry  *entry
+	cursor int
+	closed bool
+}
+
+var _ sql.ColumnScanner = (*replayRows)(nil)
+
+func newReplayRows(e *entry) *replayRows {
+	return &replayRows{entry: e, cursor: -1}
+}
+
+func (r *replayRows) Close() error {
+	r.closed = true
+	return nil
+}
+
+func (r *replayRows) ColumnTypes() ([]*stdsql.ColumnType, error) {
+	return nil, errors.New("cache: column types are not available for cached rows")
+}
+
+func (r *replayRows) Columns() ([]string, error) {
+	if r.closed {
+		return nil, errors.New("cache: rows are closed")
+	}
+	return r.entry.Columns, nil
+}
+
+func (r *replayRows) Err() error {
+	return nil
+}
+
+func (r *replayRows) Next() bool {
+	if r.closed || r.cursor+1 >= len(r.entry.Values) {
+		return false
+	}
+	r.cursor++
+	return true
+}
+
+func (r *replayRows) NextResultSet() bool {
+	return false
+}
+
+func (r *replayRows) Scan(dest ...any) error {
+	if r.closed {
+		return errors.New("cache: rows are closed")
+	}
+	if r.cursor < 0 || r.cursor >= len(r.entry.Values) {
+		return errors.New("cache: Scan called without calling Next")
+	}
+
+	values := r.entry.Values[r.cursor]
+	if len(dest) != len(values) {
+		return fmt.Errorf("cache: expected %d destination arguments in Scan, not %d", len(values), len(dest))
+	}
+	for i := range dest {
+		if err := assign(dest[i], values[i]); err != nil {
+			return fmt.Errorf("cache: scan column %q: %w", r.entry.Columns[i], err)
+		}
+	}
+
+	return nil
+}
+
+// assign 将驱动返回的值赋给目标，规则与 database/sql 的 Scan 保持一致
+func assign(dest, src any) error {
+	switch d := dest.(type) {
+	case stdsql.Scanner:
+		return d.Scan(cloneBytes(src))
+	case *any:
+		*d = cloneBytes(src)
+		return nil
+	}
+
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Pointer || dv.IsNil() {
+		return errors.New("destination not a pointer")
+	}
+
+	return assignValue(dv.Elem(), src)
+}
+
+func assignValue(dv reflect.Value, src any) error {
+	if src == nil {
+		switch dv.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+			dv.Set(reflect.Zero(dv.Type()))
+			return nil
+		}
+		return fmt.Errorf("converting NULL to %s is unsupported", dv.Kind())
+	}
+
+	if dv.Kind() == reflect.Pointer {
+		v := reflect.New(dv.Type().Elem())
+		if err := assign(v.Interface(), src); err != nil {
+			return err
+		}
+		dv.Set(v)
+		return nil
+	}
+
+	sv := reflect.ValueOf(cloneBytes(src))
+	if sv.Type().AssignableTo(dv.Type()) {
+		dv.Set(sv)
+		return nil
+	}
+
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	default:
+		s = fmt.Sprint(src)
+	}
+
+	switch dv.Kind() {
+	case reflect.String:
+		dv.SetString(s)
+		return nil
+
+	case reflect.Slice:
+		if dv.Type().Elem().Kind() == reflect.Uint8 {
+			dv.SetBytes([]byte(s))
+			return nil
+		}
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		dv.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, dv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dv.SetInt(i)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, dv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dv.SetUint(u)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, dv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dv.SetFloat(f)
+		return nil
+	}
+
+	return fmt.Errorf("unsupported Scan, storing driver.Value type %T into type %s", src, dv.Type())
+}
+
+// cloneBytes 复制字节切片，防止调用方修改缓存中的数据
+func cloneBytes(src any) any {
+	if b, ok := src.([]byte); ok {
+		return bytes.Clone(b)
+	}
+	return src
+}
@@ -202,3 +202,10 @@ func QueryAllChildrenIds[T EntClientInterface](ctx context.Context, entClient *E
 
 	return childIDs, nil
 }
+
+// NewDeptChildrenResolver 创建基于CTE递归查询的子部门查询器，可用于数据权限的本部门及以下数据范围
+func NewDeptChildrenResolver[T EntClientInterface](entClient *EntClient[T], tableName string) func(ctx context.Context, deptID uint32) ([]uint32, error) {
+	return func(ctx context.Context, deptID uint32) ([]uint32, error) {
+		return QueryAllChildrenIds(ctx, entClient, tableName, deptID)
+	}
+}
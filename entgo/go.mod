@@ -5,20 +5,23 @@ go 1.25.4
 // replace github.com/alec404/go-libs/id => ../id
 
 require (
+	ariga.io/atlas v1.0.0
 	entgo.io/contrib v0.7.0
 	entgo.io/ent v0.14.5
 	github.com/XSAM/otelsql v0.41.0
 	github.com/alec404/go-libs v0.0.1
 	github.com/alec404/go-libs/id v0.0.1
 	github.com/go-kratos/kratos/v2 v2.9.2
+	github.com/go-openapi/inflect v0.21.5
 	github.com/google/uuid v1.6.0
+	github.com/mattn/go-sqlite3 v1.14.28
+	github.com/oklog/ulid/v2 v2.1.2
 	github.com/stretchr/testify v1.11.1
 	go.opentelemetry.io/otel v1.39.0
 	google.golang.org/protobuf v1.36.11
 )
 
 require (
-	ariga.io/atlas v1.0.0 // indirect
 	github.com/agext/levenshtein v1.2.3 // indirect
 	github.com/apparentlymart/go-textseg/v15 v15.0.0 // indirect
 	github.com/bmatcuk/doublestar v1.3.4 // indirect
@@ -28,7 +31,6 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/go-logr/logr v1.4.3 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
-	github.com/go-openapi/inflect v0.21.5 // indirect
 	github.com/go-viper/mapstructure/v2 v2.5.0 // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
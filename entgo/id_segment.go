@@ -0,0 +1,125 @@
+package entgo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+
+	"github.com/alec404/go-libs/id"
+)
+
+const DefaultIDSegmentTable = "id_segments" // 默认的号段表
+
+// SegmentStore 基于数据库表的号段存储，实现 id.SegmentStore。
+// 表结构（MySQL）：
+//
+//	CREATE TABLE id_segments (
+//	    biz_tag    VARCHAR(128) PRIMARY KEY,
+//	    max_id     BIGINT NOT NULL, -- 已分配的最大ID
+//	    updated_at BIGINT NOT NULL  -- 更新时间，毫秒时间戳
+//	);
+//
+// 业务标识不存在时自动创建，ID从 1 开始；需要从指定值开始时预先插入 max_id。
+type SegmentStore[T EntClientInterface] struct {
+	entClient *EntClient[T]
+	table     string
+}
+
+// NewSegmentStore 创建数据库号段存储，tableName 为空时使用 DefaultIDSegmentTable
+func NewSegmentStore[T EntClientInterface](entClient *EntClient[T], tableName string) *SegmentStore[T] {
+	if tableName == "" {
+		tableName = DefaultIDSegmentTable
+	}
+	return &SegmentStore[T]{entClient: entClient, table: tableName}
+}
+
+// NewSegmentAllocator 创建使用数据库号段的ID分配器
+func NewSegmentAllocator[T EntClientInterface](entClient *EntClient[T], bizTag string, opts ...id.SegmentOption) *id.SegmentAllocator {
+	return id.NewSegmentAllocator(NewSegmentStore(entClient, ""), bizTag, opts...)
+}
+
+// Reserve 将 max_id 增加 step，返回 (旧 max_id, 新 max_id] 范围内的号段
+func (s *SegmentStore[T]) Reserve(ctx context.Context, bizTag string, step int64) (id.Segment, error) {
+	tx, err := s.entClient.Driver().Tx(ctx)
+	if err != nil {
+		return id.Segment{}, err
+	}
+
+	maxID, err := s.reserve(ctx, tx, bizTag, step)
+	if err != nil {
+		return id.Segment{}, Rollback(tx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return id.Segment{}, err
+	}
+
+	return id.Segment{Start: maxID - step + 1, End: maxID + 1}, nil
+}
+
+func (s *SegmentStore[T]) reserve(ctx context.Context, tx dialect.Tx, bizTag string, step int64) (int64, error) {
+	builder := entSql.Dialect(s.entClient.Driver().Dialect())
+
+	for {
+		now := time.Now().UnixMilli()
+
+		query, args := builder.Update(s.table).
+			Add("max_id", step).
+			Set("updated_at", now).
+			Where(entSql.EQ("biz_tag", bizTag)).
+			Query()
+		affected, err := execAffected(ctx, tx, query, args)
+		if err != nil {
+			return 0, fmt.Errorf("update id segment failed: %w", err)
+		}
+		if affected == 1 {
+			return s.loadMaxID(ctx, tx, bizTag)
+		}
+
+		query, args = builder.Insert(s.table).
+			Columns("biz_tag", "max_id", "updated_at").
+			Values(bizTag, step, now).
+			OnConflict(entSql.ConflictColumns("biz_tag"), entSql.DoNothing()).
+			Query()
+		if affected, err = execAffected(ctx, tx, query, args); err != nil {
+			return 0, fmt.Errorf("insert id segment failed: %w", err)
+		}
+		if affected == 1 {
+			return step, nil
+		}
+		// 其他节点同时创建了相同的业务标识，重新更新
+	}
+}
+
+func (s *SegmentStore[T]) loadMaxID(ctx context.Context, tx dialect.Tx, bizTag string) (int64, error) {
+	query, args := entSql.Dialect(s.entClient.Driver().Dialect()).
+		Select("max_id").
+		From(entSql.Table(s.table)).
+		Where(entSql.EQ("biz_tag", bizTag)).
+		Query()
+
+	rows := &entSql.Rows{}
+	if err := tx.Query(ctx, query, args, rows); err != nil {
+		return 0, fmt.Errorf("query id segment failed: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, fmt.Errorf("id segment %q not found", bizTag)
+	}
+
+	var maxID int64
+	if err := rows.Scan(&maxID); err != nil {
+		return 0, fmt.Errorf("scan id segment failed: %w", err)
+	}
+	return maxID, nil
+}
+
+// 确保 SegmentStore 实现了 id.SegmentStore 接口
+var _ id.SegmentStore = (*SegmentStore[EntClientInterface])(nil)
@@ -0,0 +1,182 @@
+package mixin
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+
+	"github.com/alec404/go-libs/crypto"
+)
+
+const BlindIndexSuffix = "_bidx" // 盲索引字段的后缀
+
+var ErrKeyringNotSet = errors.New("encryption keyring is not set")
+
+var defaultKeyring atomic.Pointer[crypto.Keyring]
+
+// SetEncryptionKeyring 设置加密字段默认使用的密钥环，需要在使用 ent 客户端之前调用
+func SetEncryptionKeyring(keyring *crypto.Keyring) {
+	defaultKeyring.Store(keyring)
+}
+
+// EncryptionKeyring 返回加密字段默认使用的密钥环
+func EncryptionKeyring() (*crypto.Keyring, error) {
+	keyring := defaultKeyring.Load()
+	if keyring == nil {
+		return nil, ErrKeyringNotSet
+	}
+	return keyring, nil
+}
+
+// BlindIndexValue 使用默认密钥环计算字段的盲索引，用于加密字段的等值查询，field 为加密字段名，
+// 未设置密钥环时返回 ErrKeyringNotSet：
+//
+//	bidx, err := mixin.BlindIndexValue("phone", phone)
+//	if err != nil {
+//		return err
+//	}
+//	client.User.Query().Where(user.PhoneBidxEQ(bidx))
+func BlindIndexValue(field, value string) (string, error) {
+	keyring, err := EncryptionKeyring()
+	if err != nil {
+		return "", err
+	}
+	return keyring.BlindIndex(field, value), nil
+}
+
+// EncryptedValueScanner 字段加解密的 ValueScanner，写入时使用 AES-GCM 加密，读取时解密。
+// keyring 为空时使用 SetEncryptionKeyring 设置的密钥环。
+// 空字符串不加密；读取到未加密的旧数据时原样返回，便于逐步迁移。
+//
+// 用法：field.String("phone").ValueScanner(mixin.EncryptedValueScanner(nil))
+func EncryptedValueScanner(keyring *crypto.Keyring) field.ValueScannerFunc[string, *sql.NullString] {
+	getKeyring := func() (*crypto.Keyring, error) {
+		if keyring != nil {
+			return keyring, nil
+		}
+		return EncryptionKeyring()
+	}
+
+	return field.ValueScannerFunc[string, *sql.NullString]{
+		V: func(s string) (driver.Value, error) {
+			if s == "" {
+				return s, nil
+			}
+			k, err := getKeyring()
+			if err != nil {
+				return nil, err
+			}
+			return k.EncryptString(s)
+		},
+		S: func(ns *sql.NullString) (string, error) {
+			if !ns.Valid || ns.String == "" || !crypto.IsEncrypted(ns.String) {
+				return ns.String, nil
+			}
+			k, err := getKeyring()
+			if err != nil {
+				return "", err
+			}
+			return k.DecryptString(ns.String)
+		},
+	}
+}
+
+// 确保 Encrypted 实现了 ent.Mixin 接口
+var _ ent.Mixin = (*Encrypted)(nil)
+
+// Encrypted 加密字段，可选附带一个盲索引字段（字段名 + _bidx），用于等值查询。
+// 盲索引使用按字段名派生的密钥计算，不同字段的相同明文得到不同的盲索引。
+type Encrypted struct {
+	mixin.Schema
+
+	Name       string          // 字段名
+	Comment    string          // 字段注释
+	BlindIndex bool            // 是否生成盲索引字段
+	Keyring    *crypto.Keyring // 为空时使用 SetEncryptionKeyring 设置的密钥环
+}
+
+func (m Encrypted) blindIndexName() string {
+	return m.Name + BlindIndexSuffix
+}
+
+func (m Encrypted) Fields() []ent.Field {
+	fields := []ent.Field{
+		field.String(m.Name).
+			Comment(m.Comment).
+			ValueScanner(EncryptedValueScanner(m.Keyring)).
+			Optional().
+			Sensitive(),
+	}
+
+	if m.BlindIndex {
+		fields = append(fields,
+			field.String(m.blindIndexName()).
+				Comment(m.Comment+"盲索引").
+				MaxLen(64).
+				Optional(),
+		)
+	}
+
+	return fields
+}
+
+// Indexes of the Encrypted mixin.
+func (m Encrypted) Indexes() []ent.Index {
+	if !m.BlindIndex {
+		return nil
+	}
+	return []ent.Index{
+		index.Fields(m.blindIndexName()),
+	}
+}
+
+// Hooks of the Encrypted mixin.
+func (m Encrypted) Hooks() []ent.Hook {
+	if !m.BlindIndex {
+		return nil
+	}
+
+	return []ent.Hook{
+		func(next ent.Mutator) ent.Mutator {
+			return ent.MutateFunc(func(ctx context.Context, mu ent.Mutation) (ent.Value, error) {
+				if err := m.setBlindIndex(mu); err != nil {
+					return nil, err
+				}
+				return next.Mutate(ctx, mu)
+			})
+		},
+	}
+}
+
+// setBlindIndex 根据明文同步盲索引字段
+func (m Encrypted) setBlindIndex(mu ent.Mutation) error {
+	if mu.FieldCleared(m.Name) {
+		return mu.ClearField(m.blindIndexName())
+	}
+
+	value, ok := mu.Field(m.Name)
+	if !ok {
+		return nil
+	}
+	s, _ := value.(string)
+	if s == "" {
+		return mu.SetField(m.blindIndexName(), "")
+	}
+
+	keyring := m.Keyring
+	if keyring == nil {
+		var err error
+		if keyring, err = EncryptionKeyring(); err != nil {
+			return err
+		}
+	}
+
+	return mu.SetField(m.blindIndexName(), keyring.BlindIndex(m.Name, s))
+}
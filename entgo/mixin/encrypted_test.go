@@ -0,0 +1,123 @@
+package mixin
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+
+	"entgo.io/ent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alec404/go-libs/crypto"
+)
+
+func newTestKeyring(t *testing.T) *crypto.Keyring {
+	t.Helper()
+
+	keyring, err := crypto.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestEncryptedValueScanner(t *testing.T) {
+	keyring := newTestKeyring(t)
+	vs := EncryptedValueScanner(keyring)
+
+	// 写入时加密，读取时解密
+	v, err := vs.Value("13800138000")
+	require.NoError(t, err)
+	ciphertext, ok := v.(string)
+	require.True(t, ok)
+	assert.True(t, crypto.IsEncrypted(ciphertext))
+
+	ns := &sql.NullString{}
+	require.NoError(t, ns.Scan(ciphertext))
+	plaintext, err := vs.FromValue(ns)
+	require.NoError(t, err)
+	assert.Equal(t, "13800138000", plaintext)
+
+	// 未加密的旧数据原样返回
+	plaintext, err = vs.FromValue(&sql.NullString{String: "13800138001", Valid: true})
+	require.NoError(t, err)
+	assert.Equal(t, "13800138001", plaintext)
+
+	// 空字符串和 NULL 不加密
+	v, err = vs.Value("")
+	require.NoError(t, err)
+	assert.Equal(t, "", v)
+
+	plaintext, err = vs.FromValue(&sql.NullString{})
+	require.NoError(t, err)
+	assert.Equal(t, "", plaintext)
+}
+
+func TestEncryptedValueScannerDefaultKeyring(t *testing.T) {
+	t.Cleanup(func() { SetEncryptionKeyring(nil) })
+
+	vs := EncryptedValueScanner(nil)
+
+	SetEncryptionKeyring(nil)
+	_, err := vs.Value("13800138000")
+	assert.ErrorIs(t, err, ErrKeyringNotSet)
+
+	_, err = BlindIndexValue("phone", "13800138000")
+	assert.ErrorIs(t, err, ErrKeyringNotSet)
+
+	keyring := newTestKeyring(t)
+	SetEncryptionKeyring(keyring)
+
+	v, err := vs.Value("13800138000")
+	require.NoError(t, err)
+	plaintext, err := keyring.DecryptString(v.(string))
+	require.NoError(t, err)
+	assert.Equal(t, "13800138000", plaintext)
+
+	bidx, err := BlindIndexValue("phone", "13800138000")
+	require.NoError(t, err)
+	assert.Equal(t, keyring.BlindIndex("phone", "13800138000"), bidx)
+}
+
+func TestEncryptedHooks(t *testing.T) {
+	keyring := newTestKeyring(t)
+	m := Encrypted{Name: "phone", BlindIndex: true, Keyring: keyring}
+
+	assert.Len(t, m.Fields(), 2)
+	assert.Len(t, m.Indexes(), 1)
+	assert.Empty(t, Encrypted{Name: "phone"}.Hooks())
+
+	// 设置明文时填充盲索引
+	mu := newFieldMutation(map[string]ent.Value{"phone": "13800138000"})
+	require.NoError(t, runHooks(context.Background(), m.Hooks(), mu))
+	assert.Equal(t, keyring.BlindIndex("phone", "13800138000"), mu.fields["phone_bidx"])
+
+	// 不同字段的相同明文得到不同的盲索引
+	mu = newFieldMutation(map[string]ent.Value{"id_card": "13800138000"})
+	require.NoError(t, runHooks(context.Background(), Encrypted{Name: "id_card", BlindIndex: true, Keyring: keyring}.Hooks(), mu))
+	assert.NotEqual(t, mu.fields["id_card_bidx"], keyring.BlindIndex("phone", "13800138000"))
+	assert.Equal(t, keyring.BlindIndex("id_card", "13800138000"), mu.fields["id_card_bidx"])
+
+	// 空字符串的盲索引为空
+	mu = newFieldMutation(map[string]ent.Value{"phone": ""})
+	require.NoError(t, runHooks(context.Background(), m.Hooks(), mu))
+	assert.Equal(t, "", mu.fields["phone_bidx"])
+
+	// 清空字段时同时清空盲索引
+	mu = newFieldMutation(map[string]ent.Value{})
+	mu.cleared["phone"] = true
+	require.NoError(t, runHooks(context.Background(), m.Hooks(), mu))
+	assert.True(t, mu.FieldCleared("phone_bidx"))
+
+	// 未修改字段时不处理
+	mu = newFieldMutation(map[string]ent.Value{})
+	require.NoError(t, runHooks(context.Background(), m.Hooks(), mu))
+	assert.NotContains(t, mu.fields, "phone_bidx")
+
+	// 未设置密钥环时返回错误，不写入空的盲索引
+	SetEncryptionKeyring(nil)
+	mu = newFieldMutation(map[string]ent.Value{"phone": "13800138000"})
+	err := runHooks(context.Background(), Encrypted{Name: "phone", BlindIndex: true}.Hooks(), mu)
+	assert.ErrorIs(t, err, ErrKeyringNotSet)
+	assert.NotContains(t, mu.fields, "phone_bidx")
+}
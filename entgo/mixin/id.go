@@ -0,0 +1,169 @@
+package mixin
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/mixin"
+	"github.com/google/uuid"
+
+	"github.com/alec404/go-libs/id"
+)
+
+// IDGenerator 主键生成器
+type IDGenerator[T any] interface {
+	NextID(ctx context.Context) (T, error)
+}
+
+// IDGeneratorFunc 函数形式的主键生成器，也可用于在测试中注入确定的ID
+type IDGeneratorFunc[T any] func(ctx context.Context) (T, error)
+
+func (f IDGeneratorFunc[T]) NextID(ctx context.Context) (T, error) {
+	return f(ctx)
+}
+
+// SnowflakeIDGenerator 使用指定工作节点ID的雪花算法生成主键
+func SnowflakeIDGenerator(workerID int64) IDGenerator[int64] {
+	return IDGeneratorFunc[int64](func(context.Context) (int64, error) {
+		return id.NewSnowflakeID(workerID)
+	})
+}
+
+// LeasedSnowflakeIDGenerator 使用租约分配的工作节点ID生成主键，租约丢失后创建失败
+func LeasedSnowflakeIDGenerator(node *id.LeasedSnowflakeNode) IDGenerator[int64] {
+	return IDGeneratorFunc[int64](func(context.Context) (int64, error) {
+		return node.NextID()
+	})
+}
+
+// SonyflakeIDGenerator 使用索尼雪花算法生成主键
+func SonyflakeIDGenerator() IDGenerator[uint64] {
+	return IDGeneratorFunc[uint64](func(context.Context) (uint64, error) {
+		return id.NewSonyflakeID()
+	})
+}
+
+// UUIDv7Generator 使用 UUIDv7 生成主键
+func UUIDv7Generator() IDGenerator[uuid.UUID] {
+	return IDGeneratorFunc[uuid.UUID](func(context.Context) (uuid.UUID, error) {
+		return id.NewUUIDv7()
+	})
+}
+
+// SegmentIDGenerator 使用号段分配器生成主键
+func SegmentIDGenerator(allocator *id.SegmentAllocator) IDGenerator[int64] {
+	return IDGeneratorFunc[int64](allocator.NextID)
+}
+
+// IDType ID 支持的主键类型，不支持的类型在编译时报错
+type IDType interface {
+	int64 | uint64 | uint32 | string | uuid.UUID | id.BinaryUUID
+}
+
+// 确保 ID 实现了 ent.Mixin 接口
+var _ ent.Mixin = (*ID[int64])(nil)
+
+// ID 通用主键，创建时由 Generator 生成，已手动设置ID时不覆盖。
+// 支持的 Go 类型见 IDType：int64、uint64、uint32、string、uuid.UUID 和 id.BinaryUUID。
+//
+//	func (User) Mixin() []ent.Mixin {
+//		return []ent.Mixin{
+//			mixin.ID[int64]{Generator: mixin.SnowflakeIDGenerator(1)},
+//		}
+//	}
+type ID[T IDType] struct {
+	mixin.Schema
+
+	Generator  IDGenerator[T]    // 主键生成器
+	SchemaType map[string]string // 各数据库的列类型，为空时使用 ent 的默认类型
+	Comment    string            // 字段注释，默认为 id
+}
+
+func (m ID[T]) Fields() []ent.Field {
+	comment := m.Comment
+	if comment == "" {
+		comment = "id"
+	}
+
+	var zero T
+	switch any(zero).(type) {
+	case int64:
+		return []ent.Field{
+			field.Int64("id").
+				Comment(comment).
+				Positive().
+				Immutable().
+				StructTag(`json:"id,omitempty"`).
+				SchemaType(m.SchemaType),
+		}
+	case uint64:
+		return []ent.Field{
+			field.Uint64("id").
+				Comment(comment).
+				Positive().
+				Immutable().
+				StructTag(`json:"id,omitempty"`).
+				SchemaType(m.SchemaType),
+		}
+	case uint32:
+		return []ent.Field{
+			field.Uint32("id").
+				Comment(comment).
+				Positive().
+				Immutable().
+				StructTag(`json:"id,omitempty"`).
+				SchemaType(m.SchemaType),
+		}
+	case string:
+		return []ent.Field{
+			field.String("id").
+				Comment(comment).
+				NotEmpty().
+				Immutable().
+				StructTag(`json:"id,omitempty"`).
+				SchemaType(m.SchemaType),
+		}
+	default: // uuid.UUID、id.BinaryUUID
+		return []ent.Field{
+			field.UUID("id", any(zero).(driver.Valuer)).
+				Comment(comment).
+				Immutable().
+				StructTag(`json:"id,omitempty"`).
+				SchemaType(m.SchemaType),
+		}
+	}
+}
+
+// Hooks of the ID mixin.
+func (m ID[T]) Hooks() []ent.Hook {
+	return []ent.Hook{
+		func(next ent.Mutator) ent.Mutator {
+			return ent.MutateFunc(func(ctx context.Context, mu ent.Mutation) (ent.Value, error) {
+				if !mu.Op().Is(ent.OpCreate) || m.Generator == nil {
+					return next.Mutate(ctx, mu)
+				}
+
+				im, ok := mu.(interface {
+					ID() (T, bool)
+					SetID(T)
+				})
+				if !ok {
+					return nil, fmt.Errorf("mixin.ID: unexpected mutation type %T", mu)
+				}
+
+				if _, exists := im.ID(); !exists {
+					v, err := m.Generator.NextID(ctx)
+					if err != nil {
+						return nil, fmt.Errorf("generate id failed: %w", err)
+					}
+					im.SetID(v)
+				}
+
+				return next.Mutate(ctx, mu)
+			})
+		},
+	}
+}
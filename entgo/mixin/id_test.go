@@ -0,0 +1,109 @@
+package mixin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alec404/go-libs/id"
+)
+
+// idMutation 模拟生成代码中带 ID 和 SetID 方法的 Mutation
+type idMutation[T IDType] struct {
+	ent.Mutation
+	op     ent.Op
+	id     T
+	exists bool
+}
+
+func (m *idMutation[T]) Op() ent.Op { return m.op }
+
+func (m *idMutation[T]) ID() (T, bool) { return m.id, m.exists }
+
+func (m *idMutation[T]) SetID(v T) {
+	m.id, m.exists = v, true
+}
+
+// testIDMixin 校验字段类型，并使用固定的生成器执行创建钩子
+func testIDMixin[T IDType](t *testing.T, typ field.Type, generated, manual T) {
+	t.Helper()
+
+	calls := 0
+	m := ID[T]{Generator: IDGeneratorFunc[T](func(context.Context) (T, error) {
+		calls++
+		return generated, nil
+	})}
+
+	fields := m.Fields()
+	require.Len(t, fields, 1)
+	desc := fields[0].Descriptor()
+	assert.Equal(t, "id", desc.Name)
+	assert.Equal(t, typ, desc.Info.Type)
+	assert.True(t, desc.Immutable)
+
+	// 创建时生成ID
+	mu := &idMutation[T]{op: ent.OpCreate}
+	require.NoError(t, runHooks(context.Background(), m.Hooks(), mu))
+	assert.Equal(t, generated, mu.id)
+	assert.Equal(t, 1, calls)
+
+	// 已设置ID时不覆盖，也不调用生成器
+	mu = &idMutation[T]{op: ent.OpCreate, id: manual, exists: true}
+	require.NoError(t, runHooks(context.Background(), m.Hooks(), mu))
+	assert.Equal(t, manual, mu.id)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIDMixin(t *testing.T) {
+	t.Run("int64", func(t *testing.T) {
+		testIDMixin[int64](t, field.TypeInt64, 42, 7)
+	})
+	t.Run("uint64", func(t *testing.T) {
+		testIDMixin[uint64](t, field.TypeUint64, 42, 7)
+	})
+	t.Run("uint32", func(t *testing.T) {
+		testIDMixin[uint32](t, field.TypeUint32, 42, 7)
+	})
+	t.Run("string", func(t *testing.T) {
+		testIDMixin(t, field.TypeString, "generated", "manual")
+	})
+	t.Run("uuid.UUID", func(t *testing.T) {
+		testIDMixin(t, field.TypeUUID, uuid.MustParse("01975a3e-0000-7000-8000-000000000001"), uuid.New())
+	})
+	t.Run("id.BinaryUUID", func(t *testing.T) {
+		testIDMixin(t, field.TypeUUID, id.BinaryUUID(uuid.MustParse("01975a3e-0000-7000-8000-000000000001")), id.BinaryUUID(uuid.New()))
+	})
+}
+
+func TestIDMixinHooks(t *testing.T) {
+	failed := errors.New("generator down")
+	m := ID[int64]{Generator: IDGeneratorFunc[int64](func(context.Context) (int64, error) {
+		return 0, failed
+	})}
+
+	// 生成失败时不执行变更
+	err := runHooks(context.Background(), m.Hooks(), &idMutation[int64]{op: ent.OpCreate})
+	assert.ErrorIs(t, err, failed)
+
+	// 非创建操作不生成ID
+	mu := &idMutation[int64]{op: ent.OpUpdateOne}
+	require.NoError(t, runHooks(context.Background(), m.Hooks(), mu))
+	assert.False(t, mu.exists)
+
+	// 未设置生成器时交给数据库或 ent 的默认值
+	mu = &idMutation[int64]{op: ent.OpCreate}
+	require.NoError(t, runHooks(context.Background(), ID[int64]{}.Hooks(), mu))
+	assert.False(t, mu.exists)
+
+	// Mutation 不支持 ID 和 SetID 时报错
+	err = runHooks(context.Background(), ID[string]{Generator: IDGeneratorFunc[string](func(context.Context) (string, error) {
+		return "x", nil
+	})}.Hooks(), &idMutation[int64]{op: ent.OpCreate})
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package mixin
+
+import (
+	"context"
+
+	"entgo.io/ent"
+)
+
+// fieldMutation 只实现字段读写的 ent.Mutation
+type fieldMutation struct {
+	ent.Mutation
+	fields  map[string]ent.Value
+	cleared map[string]bool
+}
+
+func newFieldMutation(fields map[string]ent.Value) *fieldMutation {
+	return &fieldMutation{fields: fields, cleared: make(map[string]bool)}
+}
+
+func (m *fieldMutation) Field(name string) (ent.Value, bool) {
+	v, ok := m.fields[name]
+	return v, ok
+}
+
+func (m *fieldMutation) SetField(name string, value ent.Value) error {
+	m.fields[name] = value
+	return nil
+}
+
+func (m *fieldMutation) ClearField(name string) error {
+	delete(m.fields, name)
+	m.cleared[name] = true
+	return nil
+}
+
+func (m *fieldMutation) FieldCleared(name string) bool {
+	return m.cleared[name]
+}
+
+func runHooks(ctx context.Context, hooks []ent.Hook, m ent.Mutation) error {
+	var mutator ent.Mutator = ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return nil, nil
+	})
+	for i := len(hooks) - 1; i >= 0; i-- {
+		mutator = hooks[i](mutator)
+	}
+	_, err := mutator.Mutate(ctx, m)
+	return err
+}
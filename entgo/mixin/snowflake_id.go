@@ -13,6 +13,9 @@ import (
 // 确保 SnowflackId 实现了 ent.Mixin 接口
 var _ ent.Mixin = (*SnowflackId)(nil)
 
+// SnowflackId 使用索尼雪花算法生成的主键。
+//
+// Deprecated: 使用 ID[uint64]{Generator: SonyflakeIDGenerator()}，可以更换生成器并在测试中注入确定的ID。
 type SnowflackId struct {
 	mixin.Schema
 }
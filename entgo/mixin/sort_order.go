@@ -9,6 +9,8 @@ import (
 // 确保 SortOrder 实现了 ent.Mixin 接口
 var _ ent.Mixin = (*SortOrder)(nil)
 
+// SortOrder 排序字段，拖拽调整顺序可使用 entgo.MoveBefore、entgo.MoveAfter、entgo.MoveToPosition，
+// 使用 entgo.NormalizeSortOrder 重新编号。
 type SortOrder struct {
 	mixin.Schema
 }
@@ -0,0 +1,333 @@
+package mixin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/schema"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/index"
+	"entgo.io/ent/schema/mixin"
+
+	"ariga.io/atlas/sql/migrate"
+)
+
+const (
+	DefaultStatusField   = "status"            // 默认的状态字段
+	StatusChangedAtField = "status_changed_at" // 状态变更时间字段
+	StatusChangedByField = "status_changed_by" // 状态变更者字段
+)
+
+var ErrIllegalStatusTransition = errors.New("illegal status transition")
+
+// StatusTransitionError 非法的状态迁移
+type StatusTransitionError struct {
+	Field string
+	From  string // 批量更新时为空
+	To    string
+	ID    any // 批量更新时第一条不能合法迁移的记录ID
+}
+
+func (e *StatusTransitionError) Error() string {
+	if e.ID != nil {
+		return fmt.Sprintf("%s: %s of row %v cannot change to %q", ErrIllegalStatusTransition.Error(), e.Field, e.ID, e.To)
+	}
+	return fmt.Sprintf("%s: %s cannot change from %q to %q", ErrIllegalStatusTransition.Error(), e.Field, e.From, e.To)
+}
+
+func (e *StatusTransitionError) Unwrap() error {
+	return ErrIllegalStatusTransition
+}
+
+// 确保 StatusMachine 实现了 ent.Mixin 接口
+var _ ent.Mixin = (*StatusMachine)(nil)
+
+// StatusMachine 状态机，只允许按照 Transitions 中定义的规则迁移状态。
+//
+// 单条更新时读取旧状态并校验，非法迁移返回 *StatusTransitionError；
+// 批量更新时先查询一条不能合法迁移的记录（LIMIT 1），存在时返回 *StatusTransitionError，不做任何更新。
+// 校验与更新之间状态被并发修改的记录会被追加的条件排除，需要严格一致时在事务中加锁查询后再更新。
+type StatusMachine struct {
+	mixin.Schema
+
+	FieldName      string                                   // 状态字段名，默认为 status
+	EnumTypeName   string                                   // PostgreSQL 枚举类型名，设置后需配合 ApplyHook 自动创建类型
+	States         []string                                 // 所有状态
+	Initial        string                                   // 初始状态，默认为第一个状态
+	Transitions    map[string][]string                      // 允许的状态迁移，键为源状态，值为目标状态
+	TrackChangedAt bool                                     // 是否记录状态变更时间
+	TrackChangedBy bool                                     // 是否记录状态变更者
+	OperatorID     func(ctx context.Context) (uint32, bool) // 从上下文中获取当前操作者ID
+}
+
+func (m StatusMachine) fieldName() string {
+	if m.FieldName == "" {
+		return DefaultStatusField
+	}
+	return m.FieldName
+}
+
+func (m StatusMachine) initial() string {
+	if m.Initial == "" && len(m.States) > 0 {
+		return m.States[0]
+	}
+	return m.Initial
+}
+
+func (m StatusMachine) Fields() []ent.Field {
+	status := field.Enum(m.fieldName()).
+		Comment("状态").
+		Values(m.States...).
+		Default(m.initial())
+	if m.EnumTypeName != "" {
+		status = status.SchemaType(map[string]string{
+			dialect.Postgres: m.EnumTypeName,
+		})
+	}
+
+	fields := []ent.Field{status}
+
+	if m.TrackChangedAt {
+		fields = append(fields,
+			field.Time(StatusChangedAtField).
+				Comment("状态变更时间").
+				SchemaType(map[string]string{
+					dialect.MySQL: "DATETIME",
+				}).
+				Optional().
+				Nillable(),
+		)
+	}
+
+	if m.TrackChangedBy {
+		fields = append(fields,
+			field.Uint32(StatusChangedByField).
+				Comment("状态变更者ID").
+				Optional().
+				Nillable(),
+		)
+	}
+
+	return fields
+}
+
+// Indexes of the StatusMachine mixin.
+func (m StatusMachine) Indexes() []ent.Index {
+	return []ent.Index{
+		index.Fields(m.fieldName()),
+	}
+}
+
+// Hooks of the StatusMachine mixin.
+func (m StatusMachine) Hooks() []ent.Hook {
+	return []ent.Hook{
+		func(next ent.Mutator) ent.Mutator {
+			return ent.MutateFunc(func(ctx context.Context, mu ent.Mutation) (ent.Value, error) {
+				if err := m.checkTransition(ctx, mu); err != nil {
+					return nil, err
+				}
+				return next.Mutate(ctx, mu)
+			})
+		},
+	}
+}
+
+// CanTransition 是否允许从 from 迁移到 to，状态不变时总是允许
+func (m StatusMachine) CanTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, v := range m.Transitions[from] {
+		if v == to {
+			return true
+		}
+	}
+	return false
+}
+
+// sourcesOf 返回所有可以迁移到 to 的状态，包含 to 本身
+func (m StatusMachine) sourcesOf(to string) []string {
+	var sources []string
+	for _, from := range m.States {
+		if m.CanTransition(from, to) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+func (m StatusMachine) checkTransition(ctx context.Context, mu ent.Mutation) error {
+	value, ok := mu.Field(m.fieldName())
+	if !ok {
+		return nil
+	}
+	to, ok := enumString(value)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case mu.Op().Is(ent.OpCreate):
+		return m.stamp(ctx, mu)
+
+	case mu.Op().Is(ent.OpUpdateOne):
+		old, err := mu.OldField(ctx, m.fieldName())
+		if err != nil {
+			return err
+		}
+		from, _ := enumString(old)
+		if from == to {
+			return nil
+		}
+		if !m.CanTransition(from, to) {
+			return &StatusTransitionError{Field: m.fieldName(), From: from, To: to}
+		}
+		return m.stamp(ctx, mu)
+
+	case mu.Op().Is(ent.OpUpdate):
+		wp, ok := mu.(interface {
+			WhereP(...func(*entSql.Selector))
+		})
+		if !ok {
+			return fmt.Errorf("mutation %T does not support status transition check", mu)
+		}
+
+		sources := make([]any, 0, len(m.States))
+		for _, v := range m.sourcesOf(to) {
+			sources = append(sources, v)
+		}
+
+		// 校验时查询一条不能迁移的记录，之后的查询和更新只匹配能够迁移的记录
+		checking := true
+		wp.WhereP(func(s *entSql.Selector) {
+			if checking {
+				s.Where(entSql.NotIn(s.C(m.fieldName()), sources...)).Limit(1)
+				return
+			}
+			s.Where(entSql.In(s.C(m.fieldName()), sources...))
+		})
+
+		id, err := firstID(ctx, mu)
+		checking = false
+		if err != nil {
+			return err
+		}
+		if id != nil {
+			return &StatusTransitionError{Field: m.fieldName(), To: to, ID: id}
+		}
+		return m.stamp(ctx, mu)
+	}
+
+	return nil
+}
+
+// firstID 按变更的条件查询第一条记录的ID，没有匹配的记录时返回 nil；
+// 生成代码中 IDs 方法的返回类型随主键类型变化，需要通过反射调用
+func firstID(ctx context.Context, mu ent.Mutation) (any, error) {
+	method := reflect.ValueOf(mu).MethodByName("IDs")
+	if !method.IsValid() || method.Type().NumIn() != 1 || method.Type().NumOut() != 2 ||
+		method.Type().Out(0).Kind() != reflect.Slice {
+		return nil, fmt.Errorf("mutation %T does not support status transition check", mu)
+	}
+
+	out := method.Call([]reflect.Value{reflect.ValueOf(ctx)})
+	if err, _ := out[1].Interface().(error); err != nil {
+		return nil, err
+	}
+	if out[0].Len() == 0 {
+		return nil, nil
+	}
+	return out[0].Index(0).Interface(), nil
+}
+
+// stamp 记录状态变更时间与变更者
+func (m StatusMachine) stamp(ctx context.Context, mu ent.Mutation) error {
+	if m.TrackChangedAt {
+		if err := mu.SetField(StatusChangedAtField, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if m.TrackChangedBy && m.OperatorID != nil {
+		if operatorID, ok := m.OperatorID(ctx); ok {
+			if err := mu.SetField(StatusChangedByField, operatorID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ApplyHook 迁移前自动创建 PostgreSQL 枚举类型，仅在 PostgreSQL 下使用：
+// client.Schema.Create(ctx, schema.WithApplyHook(statusMixin.ApplyHook()))
+func (m StatusMachine) ApplyHook() schema.ApplyHook {
+	return PostgresEnumTypeHook(m.EnumTypeName, m.States...)
+}
+
+// PostgresEnumTypeHook 迁移前自动创建 PostgreSQL 枚举类型，类型已存在时追加缺少的值。
+// 迁移在事务中执行，追加枚举值需要 PostgreSQL 12 及以上版本。
+func PostgresEnumTypeHook(typeName string, values ...string) schema.ApplyHook {
+	return func(next schema.Applier) schema.Applier {
+		return schema.ApplyFunc(func(ctx context.Context, conn dialect.ExecQuerier, plan *migrate.Plan) error {
+			if typeName != "" && len(values) > 0 {
+				for _, query := range PostgresEnumTypeSQL(typeName, values...) {
+					if err := conn.Exec(ctx, query, []any{}, nil); err != nil {
+						return fmt.Errorf("create enum type %s failed: %w", typeName, err)
+					}
+				}
+			}
+			return next.Apply(ctx, conn, plan)
+		})
+	}
+}
+
+// PostgresEnumTypeSQL 生成创建 PostgreSQL 枚举类型的SQL
+func PostgresEnumTypeSQL(typeName string, values ...string) []string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, quoteLiteral(v))
+	}
+
+	queries := []string{
+		fmt.Sprintf(
+			"DO $$ BEGIN CREATE TYPE %s AS ENUM (%s); EXCEPTION WHEN duplicate_object THEN NULL; END $$;",
+			quoteIdent(typeName), strings.Join(quoted, ", "),
+		),
+	}
+	for _, v := range quoted {
+		queries = append(queries, fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s;", quoteIdent(typeName), v))
+	}
+
+	return queries
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// enumString 将生成代码中的枚举值（或其指针）转换为字符串
+func enumString(v ent.Value) (string, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return "", false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.String {
+		return "", false
+	}
+	return rv.String(), true
+}
@@ -0,0 +1,193 @@
+package mixin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+	"entgo.io/ent/dialect/sql/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"ariga.io/atlas/sql/migrate"
+)
+
+// orderStatus 模拟生成代码中的枚举类型
+type orderStatus string
+
+type operatorKey struct{}
+
+var testStatusMachine = StatusMachine{
+	States: []string{"pending", "paid", "shipped", "closed"},
+	Transitions: map[string][]string{
+		"pending": {"paid", "closed"},
+		"paid":    {"shipped", "closed"},
+	},
+	TrackChangedAt: true,
+	TrackChangedBy: true,
+	OperatorID: func(ctx context.Context) (uint32, bool) {
+		id, ok := ctx.Value(operatorKey{}).(uint32)
+		return id, ok
+	},
+}
+
+// statusMutation 模拟生成代码中的 Mutation，rows 为匹配的记录的状态
+type statusMutation struct {
+	fieldMutation
+	op         ent.Op
+	old        orderStatus
+	rows       []string
+	predicates []func(*entSql.Selector)
+	queries    []string
+}
+
+func newStatusMutation(op ent.Op, to orderStatus) *statusMutation {
+	return &statusMutation{
+		fieldMutation: *newFieldMutation(map[string]ent.Value{"status": to}),
+		op:            op,
+	}
+}
+
+func (m *statusMutation) Op() ent.Op { return m.op }
+
+func (m *statusMutation) OldField(_ context.Context, name string) (ent.Value, error) {
+	return m.old, nil
+}
+
+func (m *statusMutation) WhereP(ps ...func(*entSql.Selector)) {
+	m.predicates = append(m.predicates, ps...)
+}
+
+// IDs 按追加的条件过滤 rows，返回下标作为ID，记录执行的查询
+func (m *statusMutation) IDs(context.Context) ([]int, error) {
+	s := entSql.Dialect(dialect.Postgres).Select("*").From(entSql.Table("orders"))
+	for _, p := range m.predicates {
+		p(s)
+	}
+	query, args := s.Query()
+	m.queries = append(m.queries, query)
+
+	notIn := strings.Contains(query, "NOT IN")
+	var ids []int
+	for i, status := range m.rows {
+		if len(m.predicates) == 0 || contains(args, status) != notIn {
+			ids = append(ids, i)
+		}
+	}
+	if strings.HasSuffix(query, "LIMIT 1") && len(ids) > 1 {
+		ids = ids[:1]
+	}
+	return ids, nil
+}
+
+func contains(args []any, v string) bool {
+	for _, arg := range args {
+		if arg == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStatusMachineUpdateOne(t *testing.T) {
+	ctx := context.WithValue(context.Background(), operatorKey{}, uint32(7))
+
+	mu := newStatusMutation(ent.OpUpdateOne, "paid")
+	mu.old = "pending"
+	require.NoError(t, runHooks(ctx, testStatusMachine.Hooks(), mu))
+	assert.WithinDuration(t, time.Now(), mu.fields[StatusChangedAtField].(time.Time), time.Second)
+	assert.Equal(t, uint32(7), mu.fields[StatusChangedByField])
+
+	// 非法迁移
+	mu = newStatusMutation(ent.OpUpdateOne, "pending")
+	mu.old = "shipped"
+	err := runHooks(ctx, testStatusMachine.Hooks(), mu)
+	var transitionErr *StatusTransitionError
+	require.ErrorAs(t, err, &transitionErr)
+	assert.ErrorIs(t, err, ErrIllegalStatusTransition)
+	assert.Equal(t, StatusTransitionError{Field: "status", From: "shipped", To: "pending"}, *transitionErr)
+	assert.NotContains(t, mu.fields, StatusChangedAtField)
+
+	// 状态不变时不记录变更
+	mu = newStatusMutation(ent.OpUpdateOne, "closed")
+	mu.old = "closed"
+	require.NoError(t, runHooks(ctx, testStatusMachine.Hooks(), mu))
+	assert.NotContains(t, mu.fields, StatusChangedAtField)
+}
+
+func TestStatusMachineUpdate(t *testing.T) {
+	mu := newStatusMutation(ent.OpUpdate, "closed")
+	mu.rows = []string{"pending", "paid", "closed"}
+	require.NoError(t, runHooks(context.Background(), testStatusMachine.Hooks(), mu))
+	assert.Contains(t, mu.fields, StatusChangedAtField)
+	assert.NotContains(t, mu.fields, StatusChangedByField) // 上下文中没有操作者
+
+	// 校验只执行一次 LIMIT 1 的查询
+	assert.Equal(t, []string{`SELECT * FROM "orders" WHERE "orders"."status" NOT IN ($1, $2, $3) LIMIT 1`}, mu.queries)
+
+	// 校验后追加的条件只匹配能够迁移的记录
+	s := entSql.Dialect(dialect.Postgres).Select("*").From(entSql.Table("orders"))
+	mu.predicates[0](s)
+	query, args := s.Query()
+	assert.Equal(t, `SELECT * FROM "orders" WHERE "orders"."status" IN ($1, $2, $3)`, query)
+	assert.Equal(t, []any{"pending", "paid", "closed"}, args)
+
+	// 存在不能迁移的记录时不更新
+	mu = newStatusMutation(ent.OpUpdate, "shipped")
+	mu.rows = []string{"paid", "pending", "closed", "shipped"}
+	err := runHooks(context.Background(), testStatusMachine.Hooks(), mu)
+	var transitionErr *StatusTransitionError
+	require.ErrorAs(t, err, &transitionErr)
+	assert.Equal(t, StatusTransitionError{Field: "status", To: "shipped", ID: 1}, *transitionErr)
+	assert.NotContains(t, mu.fields, StatusChangedAtField)
+}
+
+func TestStatusMachineCreate(t *testing.T) {
+	mu := newStatusMutation(ent.OpCreate, "pending")
+	require.NoError(t, runHooks(context.Background(), testStatusMachine.Hooks(), mu))
+	assert.Contains(t, mu.fields, StatusChangedAtField)
+
+	// 未修改状态时不处理
+	mu = newStatusMutation(ent.OpUpdateOne, "")
+	delete(mu.fields, "status")
+	require.NoError(t, runHooks(context.Background(), testStatusMachine.Hooks(), mu))
+	assert.NotContains(t, mu.fields, StatusChangedAtField)
+}
+
+type execRecorder struct {
+	dialect.ExecQuerier
+	queries []string
+}
+
+func (r *execRecorder) Exec(_ context.Context, query string, _, _ any) error {
+	r.queries = append(r.queries, query)
+	return nil
+}
+
+func TestPostgresEnumTypeHook(t *testing.T) {
+	applied := false
+	next := schema.ApplyFunc(func(context.Context, dialect.ExecQuerier, *migrate.Plan) error {
+		applied = true
+		return nil
+	})
+
+	conn := &execRecorder{}
+	err := PostgresEnumTypeHook("order_status", "pending", "it's")(next).Apply(context.Background(), conn, &migrate.Plan{})
+	require.NoError(t, err)
+	assert.True(t, applied)
+	assert.Equal(t, []string{
+		`DO $$ BEGIN CREATE TYPE "order_status" AS ENUM ('pending', 'it''s'); EXCEPTION WHEN duplicate_object THEN NULL; END $$;`,
+		`ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'pending';`,
+		`ALTER TYPE "order_status" ADD VALUE IF NOT EXISTS 'it''s';`,
+	}, conn.queries)
+
+	// 未设置类型名时跳过
+	conn = &execRecorder{}
+	err = StatusMachine{States: []string{"pending"}}.ApplyHook()(next).Apply(context.Background(), conn, &migrate.Plan{})
+	require.NoError(t, err)
+	assert.Empty(t, conn.queries)
+}
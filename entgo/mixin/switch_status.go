@@ -24,6 +24,8 @@ func (SwitchStatus) Fields() []ent.Field {
 		    'OFF',
 		    'ON'
 		    );
+
+		也可以在迁移时使用 PostgresEnumTypeHook("switch_status", "OFF", "ON") 自动创建。
 		*/
 		field.Enum("status").
 			Comment("状态").
@@ -0,0 +1,72 @@
+package mixin
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/schema/field"
+	"entgo.io/ent/schema/mixin"
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+
+	"github.com/alec404/go-libs/id"
+)
+
+// 确保 UUIDv7Id 实现了 ent.Mixin 接口
+var _ ent.Mixin = (*UUIDv7Id)(nil)
+
+// UUIDv7Id 以 UUIDv7 作为主键，按时间递增，避免随机 UUIDv4 造成的 B-tree 索引碎片。
+// PostgreSQL 使用原生 uuid 类型；MySQL 建议使用 BinaryUUIDv7Id。
+type UUIDv7Id struct{ mixin.Schema }
+
+func (UUIDv7Id) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID("id", uuid.UUID{}).
+			Comment("主键ID (UUIDv7)").
+			Default(id.GenerateUUIDv7).
+			Immutable().
+			SchemaType(map[string]string{
+				dialect.MySQL:    "char(36)",
+				dialect.Postgres: "uuid",
+			}),
+	}
+}
+
+// 确保 BinaryUUIDv7Id 实现了 ent.Mixin 接口
+var _ ent.Mixin = (*BinaryUUIDv7Id)(nil)
+
+// BinaryUUIDv7Id 以 16 字节二进制存储的 UUIDv7 作为主键，MySQL 使用 binary(16)
+type BinaryUUIDv7Id struct{ mixin.Schema }
+
+func (BinaryUUIDv7Id) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID("id", id.BinaryUUID{}).
+			Comment("主键ID (UUIDv7)").
+			Default(id.GenerateBinaryUUIDv7).
+			Immutable().
+			SchemaType(map[string]string{
+				dialect.MySQL:    "binary(16)",
+				dialect.Postgres: "bytea",
+				dialect.SQLite:   "blob",
+			}),
+	}
+}
+
+// 确保 ULIDId 实现了 ent.Mixin 接口
+var _ ent.Mixin = (*ULIDId)(nil)
+
+// ULIDId 以 ULID 作为主键，以 16 字节二进制存储，字符串形式为 26 位 Crockford Base32
+type ULIDId struct{ mixin.Schema }
+
+func (ULIDId) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID("id", ulid.ULID{}).
+			Comment("主键ID (ULID)").
+			Default(id.GenerateULID).
+			Immutable().
+			SchemaType(map[string]string{
+				dialect.MySQL:    "binary(16)",
+				dialect.Postgres: "bytea",
+				dialect.SQLite:   "blob",
+			}),
+	}
+}
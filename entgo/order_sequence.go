@@ -0,0 +1,133 @@
+package entgo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+
+	"github.com/alec404/go-libs/id"
+)
+
+const DefaultOrderSequenceTable = "order_sequences" // 默认的订单号序号表
+
+// OrderSequence 基于数据库表的订单号序号，实现 id.OrderSequence，多节点共享。
+// 表结构（MySQL）：
+//
+//	CREATE TABLE order_sequences (
+//	    seq_key   VARCHAR(128) PRIMARY KEY,
+//	    seq_value BIGINT NOT NULL,
+//	    expire_at BIGINT NOT NULL, -- 过期时间，毫秒时间戳
+//	    INDEX idx_order_sequences_expire_at (expire_at)
+//	);
+type OrderSequence[T EntClientInterface] struct {
+	entClient *EntClient[T]
+	table     string
+}
+
+// NewOrderSequence 创建数据库订单号序号，tableName 为空时使用 DefaultOrderSequenceTable
+func NewOrderSequence[T EntClientInterface](entClient *EntClient[T], tableName string) *OrderSequence[T] {
+	if tableName == "" {
+		tableName = DefaultOrderSequenceTable
+	}
+	return &OrderSequence[T]{entClient: entClient, table: tableName}
+}
+
+// Next 返回 key 的下一个序号，新建 key 时顺带清理已过期的 key
+func (s *OrderSequence[T]) Next(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	tx, err := s.entClient.Driver().Tx(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := s.next(ctx, tx, key, ttl)
+	if err != nil {
+		return 0, Rollback(tx, err)
+	}
+
+	return value, tx.Commit()
+}
+
+func (s *OrderSequence[T]) next(ctx context.Context, tx dialect.Tx, key string, ttl time.Duration) (int64, error) {
+	builder := entSql.Dialect(s.entClient.Driver().Dialect())
+
+	for {
+		// 先自增，行锁保证随后读取到的是本事务自增后的值
+		query, args := builder.Update(s.table).
+			Add("seq_value", 1).
+			Where(entSql.EQ("seq_key", key)).
+			Query()
+		affected, err := execAffected(ctx, tx, query, args)
+		if err != nil {
+			return 0, fmt.Errorf("increase order sequence failed: %w", err)
+		}
+		if affected == 1 {
+			return s.load(ctx, tx, key)
+		}
+
+		query, args = builder.Insert(s.table).
+			Columns("seq_key", "seq_value", "expire_at").
+			Values(key, 1, time.Now().Add(ttl).UnixMilli()).
+			OnConflict(entSql.ConflictColumns("seq_key"), entSql.DoNothing()).
+			Query()
+		if affected, err = execAffected(ctx, tx, query, args); err != nil {
+			return 0, fmt.Errorf("insert order sequence failed: %w", err)
+		}
+		if affected == 1 {
+			return 1, s.purge(ctx, tx)
+		}
+		// 其他节点同时插入了相同的 key，重新自增
+	}
+}
+
+func (s *OrderSequence[T]) load(ctx context.Context, tx dialect.Tx, key string) (int64, error) {
+	query, args := entSql.Dialect(s.entClient.Driver().Dialect()).
+		Select("seq_value").
+		From(entSql.Table(s.table)).
+		Where(entSql.EQ("seq_key", key)).
+		Query()
+
+	rows := &entSql.Rows{}
+	if err := tx.Query(ctx, query, args, rows); err != nil {
+		return 0, fmt.Errorf("query order sequence failed: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, sql.ErrNoRows
+	}
+
+	var value int64
+	if err := rows.Scan(&value); err != nil {
+		return 0, fmt.Errorf("scan order sequence failed: %w", err)
+	}
+	return value, nil
+}
+
+func (s *OrderSequence[T]) purge(ctx context.Context, tx dialect.Tx) error {
+	query, args := entSql.Dialect(s.entClient.Driver().Dialect()).
+		Delete(s.table).
+		Where(entSql.LT("expire_at", time.Now().UnixMilli())).
+		Query()
+	if err := tx.Exec(ctx, query, args, nil); err != nil {
+		return fmt.Errorf("purge order sequences failed: %w", err)
+	}
+	return nil
+}
+
+func execAffected(ctx context.Context, exec dialect.ExecQuerier, query string, args []any) (int64, error) {
+	var res sql.Result
+	if err := exec.Exec(ctx, query, args, &res); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// 确保 OrderSequence 实现了 id.OrderSequence 接口
+var _ id.OrderSequence = (*OrderSequence[EntClientInterface])(nil)
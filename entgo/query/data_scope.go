@@ -0,0 +1,183 @@
+package entgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"entgo.io/ent/dialect/sql"
+)
+
+// DataScope 数据权限范围
+type DataScope int32
+
+const (
+	DataScopeAll             DataScope = iota + 1 // 全部数据
+	DataScopeCustom                               // 自定义部门数据
+	DataScopeDept                                 // 本部门数据
+	DataScopeDeptAndChildren                      // 本部门及以下数据
+	DataScopeSelf                                 // 仅本人数据
+)
+
+const (
+	DefaultDataScopeDeptField    = "dept_id"    // 默认的部门字段
+	DefaultDataScopeCreatorField = "created_by" // 默认的创建者字段
+)
+
+var (
+	ErrDataScopeNotFound        = errors.New("data scope policy not found in context")
+	ErrDataScopeResolverMissing = errors.New("dept children resolver is required for dept and children data scope")
+)
+
+// DataScopePolicy 数据权限策略
+type DataScopePolicy struct {
+	Scope   DataScope // 数据权限范围
+	UserID  uint32    // 当前用户ID，仅本人数据时使用
+	DeptID  uint32    // 当前用户所属部门ID
+	DeptIDs []uint32  // 自定义部门ID列表，自定义部门数据时使用
+}
+
+type dataScopeContextKey struct{}
+
+// NewDataScopeContext 将数据权限策略放入上下文
+func NewDataScopeContext(ctx context.Context, policy *DataScopePolicy) context.Context {
+	return context.WithValue(ctx, dataScopeContextKey{}, policy)
+}
+
+// DataScopeFromContext 从上下文中取出数据权限策略
+func DataScopeFromContext(ctx context.Context) (*DataScopePolicy, bool) {
+	policy, ok := ctx.Value(dataScopeContextKey{}).(*DataScopePolicy)
+	return policy, ok && policy != nil
+}
+
+// DeptChildrenResolver 查询部门的所有子孙部门ID
+type DeptChildrenResolver func(ctx context.Context, deptID uint32) ([]uint32, error)
+
+type dataScopeOptions struct {
+	deptField        string
+	creatorField     string
+	childrenResolver DeptChildrenResolver
+}
+
+type DataScopeOption func(opt *dataScopeOptions)
+
+// WithDataScopeDeptField 设置部门字段名
+func WithDataScopeDeptField(field string) DataScopeOption {
+	return func(opt *dataScopeOptions) {
+		opt.deptField = field
+	}
+}
+
+// WithDataScopeCreatorField 设置创建者字段名
+func WithDataScopeCreatorField(field string) DataScopeOption {
+	return func(opt *dataScopeOptions) {
+		opt.creatorField = field
+	}
+}
+
+// WithDeptChildrenResolver 设置子部门查询器，本部门及以下数据时必须设置
+func WithDeptChildrenResolver(resolver DeptChildrenResolver) DataScopeOption {
+	return func(opt *dataScopeOptions) {
+		opt.childrenResolver = resolver
+	}
+}
+
+// BuildDataScopeSelector 根据上下文中的数据权限策略构建过滤选择器
+func BuildDataScopeSelector(ctx context.Context, opts ...DataScopeOption) (error, func(s *sql.Selector)) {
+	policy, ok := DataScopeFromContext(ctx)
+	if !ok {
+		return ErrDataScopeNotFound, nil
+	}
+
+	o := &dataScopeOptions{
+		deptField:    DefaultDataScopeDeptField,
+		creatorField: DefaultDataScopeCreatorField,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	switch policy.Scope {
+	case DataScopeAll:
+		return nil, nil
+
+	case DataScopeCustom:
+		return nil, buildDeptInSelector(o.deptField, policy.DeptIDs)
+
+	case DataScopeDept:
+		return nil, func(s *sql.Selector) {
+			s.Where(sql.EQ(s.C(o.deptField), policy.DeptID))
+		}
+
+	case DataScopeDeptAndChildren:
+		if o.childrenResolver == nil {
+			return ErrDataScopeResolverMissing, nil
+		}
+
+		childIDs, err := o.childrenResolver(ctx, policy.DeptID)
+		if err != nil {
+			return err, nil
+		}
+
+		deptIDs := make([]uint32, 0, len(childIDs)+1)
+		deptIDs = append(deptIDs, policy.DeptID)
+		deptIDs = append(deptIDs, childIDs...)
+
+		return nil, buildDeptInSelector(o.deptField, deptIDs)
+
+	case DataScopeSelf:
+		return nil, func(s *sql.Selector) {
+			s.Where(sql.EQ(s.C(o.creatorField), policy.UserID))
+		}
+
+	default:
+		return fmt.Errorf("unsupported data scope: %d", policy.Scope), nil
+	}
+}
+
+// buildDeptInSelector 构建部门ID的IN过滤，列表为空时不返回任何数据
+func buildDeptInSelector(field string, deptIDs []uint32) func(s *sql.Selector) {
+	return func(s *sql.Selector) {
+		if len(deptIDs) == 0 {
+			s.Where(sql.False())
+			return
+		}
+
+		values := make([]any, 0, len(deptIDs))
+		for _, v := range deptIDs {
+			values = append(values, v)
+		}
+		s.Where(sql.In(s.C(field), values...))
+	}
+}
+
+// BuildQuerySelectorWithDataScope 构建带数据权限的分页过滤查询器
+func BuildQuerySelectorWithDataScope(
+	ctx context.Context,
+	andFilterJsonString, orFilterJsonString string,
+	page, pageSize int32, noPaging bool,
+	orderBys []string, defaultOrderField string,
+	selectFields []string,
+	opts ...DataScopeOption,
+) (err error, whereSelectors []func(s *sql.Selector), querySelectors []func(s *sql.Selector)) {
+	var scopeSelector func(s *sql.Selector)
+	if err, scopeSelector = BuildDataScopeSelector(ctx, opts...); err != nil {
+		return err, nil, nil
+	}
+
+	if err, whereSelectors, querySelectors = BuildQuerySelector(
+		andFilterJsonString, orFilterJsonString,
+		page, pageSize, noPaging,
+		orderBys, defaultOrderField,
+		selectFields,
+	); err != nil {
+		return err, nil, nil
+	}
+
+	if scopeSelector != nil {
+		whereSelectors = append([]func(s *sql.Selector){scopeSelector}, whereSelectors...)
+		querySelectors = append([]func(s *sql.Selector){scopeSelector}, querySelectors...)
+	}
+
+	return
+}
@@ -0,0 +1,146 @@
+package entgo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/dialect/sql"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDataScopeSelector(t *testing.T) {
+	t.Run("NoPolicy", func(t *testing.T) {
+		err, selector := BuildDataScopeSelector(context.Background())
+		require.ErrorIs(t, err, ErrDataScopeNotFound)
+		require.Nil(t, selector)
+	})
+
+	t.Run("All", func(t *testing.T) {
+		ctx := NewDataScopeContext(context.Background(), &DataScopePolicy{Scope: DataScopeAll})
+		err, selector := BuildDataScopeSelector(ctx)
+		require.Nil(t, err)
+		require.Nil(t, selector)
+	})
+
+	t.Run("MySQL_Custom", func(t *testing.T) {
+		ctx := NewDataScopeContext(context.Background(), &DataScopePolicy{
+			Scope:   DataScopeCustom,
+			DeptIDs: []uint32{1, 2},
+		})
+		err, selector := BuildDataScopeSelector(ctx)
+		require.Nil(t, err)
+
+		s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+		selector(s)
+		query, args := s.Query()
+		require.Equal(t, "SELECT * FROM `users` WHERE `users`.`dept_id` IN (?, ?)", query)
+		require.Equal(t, []any{uint32(1), uint32(2)}, args)
+	})
+
+	t.Run("PostgreSQL_CustomEmpty", func(t *testing.T) {
+		ctx := NewDataScopeContext(context.Background(), &DataScopePolicy{Scope: DataScopeCustom})
+		err, selector := BuildDataScopeSelector(ctx)
+		require.Nil(t, err)
+
+		s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+		selector(s)
+		query, args := s.Query()
+		require.Equal(t, `SELECT * FROM "users" WHERE FALSE`, query)
+		require.Empty(t, args)
+	})
+
+	t.Run("PostgreSQL_Dept", func(t *testing.T) {
+		ctx := NewDataScopeContext(context.Background(), &DataScopePolicy{
+			Scope:  DataScopeDept,
+			DeptID: 10,
+		})
+		err, selector := BuildDataScopeSelector(ctx, WithDataScopeDeptField("org_id"))
+		require.Nil(t, err)
+
+		s := sql.Dialect(dialect.Postgres).Select("*").From(sql.Table("users"))
+		selector(s)
+		query, args := s.Query()
+		require.Equal(t, `SELECT * FROM "users" WHERE "users"."org_id" = $1`, query)
+		require.Equal(t, []any{uint32(10)}, args)
+	})
+
+	t.Run("MySQL_DeptAndChildren", func(t *testing.T) {
+		ctx := NewDataScopeContext(context.Background(), &DataScopePolicy{
+			Scope:  DataScopeDeptAndChildren,
+			DeptID: 10,
+		})
+
+		err, _ := BuildDataScopeSelector(ctx)
+		require.ErrorIs(t, err, ErrDataScopeResolverMissing)
+
+		resolver := func(_ context.Context, deptID uint32) ([]uint32, error) {
+			require.Equal(t, uint32(10), deptID)
+			return []uint32{11, 12}, nil
+		}
+		err, selector := BuildDataScopeSelector(ctx, WithDeptChildrenResolver(resolver))
+		require.Nil(t, err)
+
+		s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+		selector(s)
+		query, args := s.Query()
+		require.Equal(t, "SELECT * FROM `users` WHERE `users`.`dept_id` IN (?, ?, ?)", query)
+		require.Equal(t, []any{uint32(10), uint32(11), uint32(12)}, args)
+
+		resolverErr := errors.New("resolver failed")
+		err, selector = BuildDataScopeSelector(ctx, WithDeptChildrenResolver(func(context.Context, uint32) ([]uint32, error) {
+			return nil, resolverErr
+		}))
+		require.ErrorIs(t, err, resolverErr)
+		require.Nil(t, selector)
+	})
+
+	t.Run("MySQL_Self", func(t *testing.T) {
+		ctx := NewDataScopeContext(context.Background(), &DataScopePolicy{
+			Scope:  DataScopeSelf,
+			UserID: 7,
+		})
+		err, selector := BuildDataScopeSelector(ctx, WithDataScopeCreatorField("creator_id"))
+		require.Nil(t, err)
+
+		s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+		selector(s)
+		query, args := s.Query()
+		require.Equal(t, "SELECT * FROM `users` WHERE `users`.`creator_id` = ?", query)
+		require.Equal(t, []any{uint32(7)}, args)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		ctx := NewDataScopeContext(context.Background(), &DataScopePolicy{Scope: 99})
+		err, selector := BuildDataScopeSelector(ctx)
+		require.NotNil(t, err)
+		require.Nil(t, selector)
+	})
+}
+
+func TestBuildQuerySelectorWithDataScope(t *testing.T) {
+	ctx := NewDataScopeContext(context.Background(), &DataScopePolicy{
+		Scope:  DataScopeSelf,
+		UserID: 7,
+	})
+
+	err, whereSelectors, querySelectors := BuildQuerySelectorWithDataScope(ctx,
+		`{"status":"ON"}`, "",
+		1, 10, false,
+		nil, "id",
+		nil,
+	)
+	require.Nil(t, err)
+	require.Len(t, whereSelectors, 2)
+	require.Len(t, querySelectors, 4)
+
+	s := sql.Dialect(dialect.MySQL).Select("*").From(sql.Table("users"))
+	for _, fn := range whereSelectors {
+		fn(s)
+	}
+	query, args := s.Query()
+	require.Equal(t, "SELECT * FROM `users` WHERE `users`.`created_by` = ? AND `users`.`status` = ?", query)
+	require.Equal(t, []any{uint32(7), "ON"}, args)
+}
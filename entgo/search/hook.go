@@ -0,0 +1,163 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"entgo.io/ent"
+	"github.com/go-kratos/kratos/v2/log"
+
+	"github.com/alec404/go-libs/entgo"
+	"github.com/alec404/go-libs/entgo/txhook"
+)
+
+// Syncer 搜索索引的同步队列，esquery.BulkIndexer 实现了该接口
+type Syncer interface {
+	Index(index, id string, doc interface{}) error
+	Delete(index, id string) error
+}
+
+// Loader 按ID加载变更后的实体，用于批量更新后同步
+type Loader[ID comparable] func(ctx context.Context, ids []ID) (map[ID]any, error)
+
+// SyncOption SyncHook 的配置项
+type SyncOption[ID comparable] func(o *syncOptions[ID])
+
+type syncOptions[ID comparable] struct {
+	index  string
+	loader Loader[ID]
+}
+
+// WithSyncIndex 设置索引名，默认按 ent 的规则由类型名推导，例如：UserGroup -> user_groups
+func WithSyncIndex[ID comparable](index string) SyncOption[ID] {
+	return func(o *syncOptions[ID]) {
+		o.index = index
+	}
+}
+
+// WithSyncLoader 设置实体加载函数，批量更新（Update 多条）需要通过它重新加载实体，未设置时批量更新不会同步
+func WithSyncLoader[ID comparable](loader Loader[ID]) SyncOption[ID] {
+	return func(o *syncOptions[ID]) {
+		o.loader = loader
+	}
+}
+
+// SyncHook 变更成功后将实体的写入或删除操作加入同步队列，ID 为实体主键的类型。
+// 文档为实体的 JSON（不含 edges），用法：client.User.Use(search.SyncHook[int](indexer))
+//
+// 在事务中变更时，操作在事务提交成功后入队，回滚时不入队，需要驱动使用 txhook.NewDriver 包装。
+func SyncHook[ID comparable](syncer Syncer, opts ...SyncOption[ID]) ent.Hook {
+	o := &syncOptions[ID]{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next ent.Mutator) ent.Mutator {
+		return ent.MutateFunc(func(ctx context.Context, m ent.Mutation) (ent.Value, error) {
+			index := o.index
+			if index == "" {
+				index = entgo.DefaultTableName(m.Type())
+			}
+
+			// 批量更新和删除需要在变更之前查询受影响的ID
+			var ids []ID
+			if m.Op().Is(ent.OpUpdate | ent.OpDelete | ent.OpDeleteOne) {
+				im, ok := m.(interface {
+					IDs(ctx context.Context) ([]ID, error)
+				})
+				if !ok {
+					return nil, fmt.Errorf("search.SyncHook: unexpected mutation type %T", m)
+				}
+
+				var err error
+				if ids, err = im.IDs(ctx); err != nil {
+					return nil, err
+				}
+			}
+
+			ctx, scope := txhook.NewScope(ctx)
+
+			v, err := next.Mutate(ctx, m)
+			if err != nil {
+				return v, err
+			}
+
+			scope.OnCommit(func() {
+				syncMutation(context.WithoutCancel(ctx), syncer, o, index, m, ids, v)
+			})
+
+			return v, nil
+		})
+	}
+}
+
+// syncMutation 将变更的实体加入同步队列
+func syncMutation[ID comparable](ctx context.Context, syncer Syncer, o *syncOptions[ID], index string, m ent.Mutation, ids []ID, v ent.Value) {
+	switch {
+	case m.Op().Is(ent.OpCreate | ent.OpUpdateOne):
+		im, ok := m.(interface {
+			ID() (ID, bool)
+		})
+		if !ok {
+			return
+		}
+		if id, exists := im.ID(); exists {
+			syncIndex(syncer, index, id, v)
+		}
+
+	case m.Op().Is(ent.OpDelete | ent.OpDeleteOne):
+		for _, id := range ids {
+			if err := syncer.Delete(index, fmt.Sprint(id)); err != nil {
+				log.Errorf("queue search delete failed: %s", err.Error())
+			}
+		}
+
+	case m.Op().Is(ent.OpUpdate):
+		if o.loader == nil || len(ids) == 0 {
+			return
+		}
+		entities, err := o.loader(ctx, ids)
+		if err != nil {
+			log.Errorf("load entities for search sync failed: %s", err.Error())
+			return
+		}
+		for _, id := range ids {
+			if entity, ok := entities[id]; ok {
+				syncIndex(syncer, index, id, entity)
+			}
+		}
+	}
+}
+
+// syncIndex 将实体转为文档并加入写入队列，同步失败不影响变更结果
+func syncIndex[ID comparable](syncer Syncer, index string, id ID, entity any) {
+	doc, err := entityDocument(entity)
+	if err != nil {
+		log.Errorf("encode search document failed: %s", err.Error())
+		return
+	}
+	if err = syncer.Index(index, fmt.Sprint(id), doc); err != nil {
+		log.Errorf("queue search index failed: %s", err.Error())
+	}
+}
+
+// entityDocument 将实体转为文档，去掉 ent 生成的 edges 字段
+func entityDocument(entity any) (map[string]any, error) {
+	b, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+
+	// 保留数字的原始精度，避免 int64 字段转为 float64
+	doc := make(map[string]any)
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err = decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "edges")
+
+	return doc, nil
+}
@@ -0,0 +1,137 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alec404/go-libs/entgo/txhook"
+)
+
+type syncAction struct {
+	Op    string
+	Index string
+	ID    string
+	Doc   interface{}
+}
+
+type fakeSyncer struct {
+	actions []syncAction
+}
+
+func (s *fakeSyncer) Index(index, id string, doc interface{}) error {
+	s.actions = append(s.actions, syncAction{Op: "index", Index: index, ID: id, Doc: doc})
+	return nil
+}
+
+func (s *fakeSyncer) Delete(index, id string) error {
+	s.actions = append(s.actions, syncAction{Op: "delete", Index: index, ID: id})
+	return nil
+}
+
+type testEntity struct {
+	ID    int64    `json:"id,omitempty"`
+	Name  string   `json:"name,omitempty"`
+	Edges struct{} `json:"edges"`
+}
+
+type fakeMutation struct {
+	ent.Mutation
+	op  ent.Op
+	id  int64
+	ids []int64
+}
+
+func (m *fakeMutation) Op() ent.Op        { return m.op }
+func (m *fakeMutation) Type() string      { return "UserGroup" }
+func (m *fakeMutation) ID() (int64, bool) { return m.id, m.id != 0 }
+func (m *fakeMutation) IDs(context.Context) ([]int64, error) {
+	return m.ids, nil
+}
+
+func mutate(t *testing.T, hook ent.Hook, m ent.Mutation, v ent.Value, err error) {
+	t.Helper()
+	_, _ = hook(ent.MutateFunc(func(context.Context, ent.Mutation) (ent.Value, error) {
+		return v, err
+	})).Mutate(context.Background(), m)
+}
+
+func TestSyncHook(t *testing.T) {
+	syncer := &fakeSyncer{}
+	hook := SyncHook[int64](syncer)
+
+	mutate(t, hook, &fakeMutation{op: ent.OpCreate, id: 9007199254740993}, &testEntity{ID: 9007199254740993, Name: "admin"}, nil)
+	mutate(t, hook, &fakeMutation{op: ent.OpDelete, ids: []int64{1, 2}}, 2, nil)
+	mutate(t, hook, &fakeMutation{op: ent.OpUpdate, ids: []int64{3}}, 1, nil) // 未设置 Loader
+	mutate(t, hook, &fakeMutation{op: ent.OpUpdateOne, id: 4}, &testEntity{ID: 4}, errors.New("failed"))
+
+	require.Len(t, syncer.actions, 3)
+	assert.Equal(t, syncAction{
+		Op: "index", Index: "user_groups", ID: "9007199254740993",
+		Doc: map[string]any{"id": json.Number("9007199254740993"), "name": "admin"},
+	}, syncer.actions[0])
+	assert.Equal(t, syncAction{Op: "delete", Index: "user_groups", ID: "1"}, syncer.actions[1])
+	assert.Equal(t, syncAction{Op: "delete", Index: "user_groups", ID: "2"}, syncer.actions[2])
+}
+
+func TestSyncHookLoader(t *testing.T) {
+	syncer := &fakeSyncer{}
+	hook := SyncHook[int64](syncer,
+		WithSyncIndex[int64]("groups_v1"),
+		WithSyncLoader[int64](func(_ context.Context, ids []int64) (map[int64]any, error) {
+			return map[int64]any{3: &testEntity{ID: 3, Name: "ops"}}, nil
+		}),
+	)
+
+	mutate(t, hook, &fakeMutation{op: ent.OpUpdate, ids: []int64{3, 5}}, 2, nil)
+
+	require.Len(t, syncer.actions, 1)
+	assert.Equal(t, "groups_v1", syncer.actions[0].Index)
+	assert.Equal(t, "3", syncer.actions[0].ID)
+	assert.Equal(t, map[string]any{"id": json.Number("3"), "name": "ops"}, syncer.actions[0].Doc)
+}
+
+type fakeDriver struct {
+	dialect.Driver
+}
+
+func (d *fakeDriver) Exec(context.Context, string, any, any) error { return nil }
+
+func (d *fakeDriver) Tx(context.Context) (dialect.Tx, error) {
+	return dialect.NopTx(d), nil
+}
+
+func TestSyncHookTx(t *testing.T) {
+	syncer := &fakeSyncer{}
+	hook := SyncHook[int64](syncer)
+	drv := txhook.NewDriver(&fakeDriver{})
+
+	mutateInTx := func(tx dialect.Tx, id int64) {
+		_, err := hook(ent.MutateFunc(func(ctx context.Context, _ ent.Mutation) (ent.Value, error) {
+			return &testEntity{ID: id}, tx.Exec(ctx, "INSERT INTO user_groups (id) VALUES (?)", []any{id}, nil)
+		})).Mutate(context.Background(), &fakeMutation{op: ent.OpCreate, id: id})
+		require.NoError(t, err)
+	}
+
+	// 回滚时不同步
+	tx, err := drv.Tx(context.Background())
+	require.NoError(t, err)
+	mutateInTx(tx, 1)
+	require.NoError(t, tx.Rollback())
+	assert.Empty(t, syncer.actions)
+
+	// 提交后才同步
+	tx, err = drv.Tx(context.Background())
+	require.NoError(t, err)
+	mutateInTx(tx, 2)
+	assert.Empty(t, syncer.actions)
+	require.NoError(t, tx.Commit())
+	require.Len(t, syncer.actions, 1)
+	assert.Equal(t, "2", syncer.actions[0].ID)
+}
@@ -0,0 +1,275 @@
+package entgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+)
+
+const (
+	DefaultSortOrderField = "sort_order" // 默认的排序字段
+	DefaultSortOrderGap   = 1024         // 默认的排序间隔
+)
+
+var ErrSortItemNotFound = errors.New("sort item not found in scope")
+
+// SortOrderOptions 排序操作的配置
+type SortOrderOptions struct {
+	Table      string         // 表名
+	IDField    string         // 主键字段，默认为 id
+	OrderField string         // 排序字段，默认为 sort_order
+	Scope      map[string]any // 排序范围，例如：{"parent_id": 1}，值为 nil 时使用 IS NULL
+	Gap        int64          // 排序间隔，默认为 1024
+}
+
+func (o SortOrderOptions) withDefaults() SortOrderOptions {
+	if o.IDField == "" {
+		o.IDField = "id"
+	}
+	if o.OrderField == "" {
+		o.OrderField = DefaultSortOrderField
+	}
+	if o.Gap <= 0 {
+		o.Gap = DefaultSortOrderGap
+	}
+	return o
+}
+
+// sortItem 排序范围内的一行
+type sortItem struct {
+	id    any
+	order int64
+}
+
+// MoveBefore 将 id 移动到 targetID 之前，id 与 targetID 相同时不做任何操作
+func MoveBefore[T EntClientInterface](ctx context.Context, entClient *EntClient[T], opts SortOrderOptions, id, targetID any) error {
+	if sameSortID(id, targetID) {
+		return nil
+	}
+	return moveSortItem(ctx, entClient, opts, id, func(items []sortItem) (int, error) {
+		index := findSortItem(items, targetID)
+		if index < 0 {
+			return 0, ErrSortItemNotFound
+		}
+		return index, nil
+	})
+}
+
+// MoveAfter 将 id 移动到 targetID 之后，id 与 targetID 相同时不做任何操作
+func MoveAfter[T EntClientInterface](ctx context.Context, entClient *EntClient[T], opts SortOrderOptions, id, targetID any) error {
+	if sameSortID(id, targetID) {
+		return nil
+	}
+	return moveSortItem(ctx, entClient, opts, id, func(items []sortItem) (int, error) {
+		index := findSortItem(items, targetID)
+		if index < 0 {
+			return 0, ErrSortItemNotFound
+		}
+		return index + 1, nil
+	})
+}
+
+// MoveToPosition 将 id 移动到排序范围内的第 position 位（从 0 开始），超出范围时移动到末尾
+func MoveToPosition[T EntClientInterface](ctx context.Context, entClient *EntClient[T], opts SortOrderOptions, id any, position int) error {
+	return moveSortItem(ctx, entClient, opts, id, func(items []sortItem) (int, error) {
+		if position < 0 {
+			return 0, nil
+		}
+		if position > len(items) {
+			return len(items), nil
+		}
+		return position, nil
+	})
+}
+
+// NormalizeSortOrder 按当前顺序重新编号，消除重复值并恢复间隔
+func NormalizeSortOrder[T EntClientInterface](ctx context.Context, entClient *EntClient[T], opts SortOrderOptions) error {
+	opts = opts.withDefaults()
+	return withSortTx(ctx, entClient, func(tx dialect.Tx) error {
+		items, err := loadSortItems(ctx, tx, entClient.Driver().Dialect(), opts)
+		if err != nil {
+			return err
+		}
+		return updateSortItems(ctx, tx, entClient.Driver().Dialect(), opts, renumberSortItems(items, opts.Gap))
+	})
+}
+
+func moveSortItem[T EntClientInterface](
+	ctx context.Context,
+	entClient *EntClient[T],
+	opts SortOrderOptions,
+	id any,
+	position func(items []sortItem) (int, error),
+) error {
+	opts = opts.withDefaults()
+	return withSortTx(ctx, entClient, func(tx dialect.Tx) error {
+		items, err := loadSortItems(ctx, tx, entClient.Driver().Dialect(), opts)
+		if err != nil {
+			return err
+		}
+
+		from := findSortItem(items, id)
+		if from < 0 {
+			return ErrSortItemNotFound
+		}
+		moved := items[from]
+		rest := append(append([]sortItem{}, items[:from]...), items[from+1:]...)
+
+		to, err := position(rest)
+		if err != nil {
+			return err
+		}
+
+		return updateSortItems(ctx, tx, entClient.Driver().Dialect(), opts, planSortMove(rest, moved, to, opts.Gap))
+	})
+}
+
+// planSortMove 计算将 moved 插入到 items 第 to 位所需的更新。
+// 前后相邻的两行之间有空隙时只更新被移动的行，否则重新编号。
+func planSortMove(items []sortItem, moved sortItem, to int, gap int64) []sortItem {
+	var order int64
+	ok := true
+
+	switch {
+	case len(items) == 0:
+		order = gap
+	case to == 0:
+		order = items[0].order - gap
+		ok = order >= math.MinInt32
+	case to == len(items):
+		order = items[len(items)-1].order + gap
+		ok = order <= math.MaxInt32
+	default:
+		prev, next := items[to-1].order, items[to].order
+		order = prev + (next-prev)/2
+		ok = next-prev > 1
+	}
+
+	if ok {
+		if order == moved.order {
+			return nil
+		}
+		return []sortItem{{id: moved.id, order: order}}
+	}
+
+	all := make([]sortItem, 0, len(items)+1)
+	all = append(all, items[:to]...)
+	all = append(all, moved)
+	all = append(all, items[to:]...)
+
+	return renumberSortItems(all, gap)
+}
+
+// renumberSortItems 按顺序以 gap 为间隔重新编号，只返回值有变化的行
+func renumberSortItems(items []sortItem, gap int64) []sortItem {
+	var updates []sortItem
+	for i, item := range items {
+		order := int64(i+1) * gap
+		if order != item.order {
+			updates = append(updates, sortItem{id: item.id, order: order})
+		}
+	}
+	return updates
+}
+
+func findSortItem(items []sortItem, id any) int {
+	for i, item := range items {
+		if sameSortID(item.id, id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameSortID 按字符串比较ID，数据库返回的ID类型可能与调用方传入的不同
+func sameSortID(a, b any) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func withSortTx[T EntClientInterface](ctx context.Context, entClient *EntClient[T], fn func(tx dialect.Tx) error) error {
+	tx, err := entClient.Driver().Tx(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		return Rollback(tx, err)
+	}
+
+	return tx.Commit()
+}
+
+func sortScopePredicates(scope map[string]any) []*entSql.Predicate {
+	fields := make([]string, 0, len(scope))
+	for f := range scope {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	ps := make([]*entSql.Predicate, 0, len(fields))
+	for _, f := range fields {
+		if scope[f] == nil {
+			ps = append(ps, entSql.IsNull(f))
+		} else {
+			ps = append(ps, entSql.EQ(f, scope[f]))
+		}
+	}
+	return ps
+}
+
+// loadSortItems 按顺序加载排序范围内的行，排序字段为 NULL 时视为 0。
+// 排序时同样使用 COALESCE，避免不同数据库中 NULL 的位置不同导致顺序与计算使用的值不一致。
+func loadSortItems(ctx context.Context, tx dialect.Tx, dialectName string, opts SortOrderOptions) ([]sortItem, error) {
+	s := entSql.Dialect(dialectName).
+		Select(opts.IDField, opts.OrderField).
+		From(entSql.Table(opts.Table))
+	s.OrderExpr(entSql.Expr(fmt.Sprintf("COALESCE(%s, 0)", s.C(opts.OrderField)))).
+		OrderBy(entSql.Asc(s.C(opts.IDField)))
+	if ps := sortScopePredicates(opts.Scope); len(ps) > 0 {
+		s.Where(entSql.And(ps...))
+	}
+	if dialectName != dialect.SQLite {
+		s.ForUpdate()
+	}
+
+	query, args := s.Query()
+	rows := &entSql.Rows{}
+	if err := tx.Query(ctx, query, args, rows); err != nil {
+		return nil, fmt.Errorf("query sort items failed: %w", err)
+	}
+	defer rows.Close()
+
+	var items []sortItem
+	for rows.Next() {
+		var id any
+		var order entSql.NullInt64
+		if err := rows.Scan(&id, &order); err != nil {
+			return nil, fmt.Errorf("scan sort item failed: %w", err)
+		}
+		if b, ok := id.([]byte); ok {
+			id = string(b)
+		}
+		items = append(items, sortItem{id: id, order: order.Int64})
+	}
+
+	return items, rows.Err()
+}
+
+func updateSortItems(ctx context.Context, tx dialect.Tx, dialectName string, opts SortOrderOptions, updates []sortItem) error {
+	for _, item := range updates {
+		query, args := entSql.Dialect(dialectName).
+			Update(opts.Table).
+			Set(opts.OrderField, item.order).
+			Where(entSql.EQ(opts.IDField, item.id)).
+			Query()
+		if err := tx.Exec(ctx, query, args, nil); err != nil {
+			return fmt.Errorf("update sort order failed: %w", err)
+		}
+	}
+	return nil
+}
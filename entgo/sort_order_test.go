@@ -0,0 +1,149 @@
+package entgo
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	entSql "entgo.io/ent/dialect/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanSortMove(t *testing.T) {
+	items := []sortItem{{id: 1, order: 1024}, {id: 2, order: 2048}, {id: 3, order: 3072}}
+
+	t.Run("Empty", func(t *testing.T) {
+		updates := planSortMove(nil, sortItem{id: 9, order: 0}, 0, 1024)
+		require.Equal(t, []sortItem{{id: 9, order: 1024}}, updates)
+	})
+
+	t.Run("First", func(t *testing.T) {
+		updates := planSortMove(items, sortItem{id: 9, order: 5000}, 0, 1024)
+		require.Equal(t, []sortItem{{id: 9, order: 0}}, updates)
+	})
+
+	t.Run("Last", func(t *testing.T) {
+		updates := planSortMove(items, sortItem{id: 9, order: 0}, 3, 1024)
+		require.Equal(t, []sortItem{{id: 9, order: 4096}}, updates)
+	})
+
+	t.Run("Between", func(t *testing.T) {
+		updates := planSortMove(items, sortItem{id: 9, order: 0}, 1, 1024)
+		require.Equal(t, []sortItem{{id: 9, order: 1536}}, updates)
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		updates := planSortMove(items, sortItem{id: 9, order: 1536}, 1, 1024)
+		require.Empty(t, updates)
+	})
+
+	t.Run("NoGap", func(t *testing.T) {
+		crowded := []sortItem{{id: 1, order: 1}, {id: 2, order: 2}, {id: 3, order: 2}}
+		updates := planSortMove(crowded, sortItem{id: 9, order: 0}, 1, 10)
+		require.Equal(t, []sortItem{
+			{id: 1, order: 10},
+			{id: 9, order: 20},
+			{id: 2, order: 30},
+			{id: 3, order: 40},
+		}, updates)
+	})
+}
+
+func TestRenumberSortItems(t *testing.T) {
+	items := []sortItem{{id: 1, order: 10}, {id: 2, order: 10}, {id: 3, order: 30}}
+	require.Equal(t, []sortItem{{id: 2, order: 20}}, renumberSortItems(items, 10))
+}
+
+func TestFindSortItem(t *testing.T) {
+	items := []sortItem{{id: int64(1)}, {id: "2"}}
+	require.Equal(t, 0, findSortItem(items, uint32(1)))
+	require.Equal(t, 1, findSortItem(items, 2))
+	require.Equal(t, -1, findSortItem(items, 3))
+}
+
+type sortTestClient struct{}
+
+func (sortTestClient) Close() error { return nil }
+
+// newSortTestClient 创建内存 SQLite 数据库，parent_id 为 1 的行按 COALESCE(sort_order, 0), id 的顺序为 2、1、4、3
+func newSortTestClient(t *testing.T) *EntClient[sortTestClient] {
+	t.Helper()
+
+	drv, err := entSql.Open(dialect.SQLite, "file:"+t.Name()+"?mode=memory&cache=shared")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = drv.Close() })
+
+	_, err = drv.DB().Exec(`
+CREATE TABLE items (id INTEGER PRIMARY KEY, parent_id INTEGER, sort_order INTEGER NULL);
+INSERT INTO items (id, parent_id, sort_order) VALUES (1, 1, NULL), (2, 1, -1024), (3, 1, 2048), (4, 1, NULL), (5, 2, 0);`)
+	require.NoError(t, err)
+
+	return NewEntClient(sortTestClient{}, drv)
+}
+
+func sortedIDs(t *testing.T, entClient *EntClient[sortTestClient]) []int {
+	t.Helper()
+
+	rows, err := entClient.Driver().DB().Query("SELECT id FROM items WHERE parent_id = 1 ORDER BY COALESCE(sort_order, 0), id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		require.NoError(t, rows.Scan(&id))
+		ids = append(ids, id)
+	}
+	require.NoError(t, rows.Err())
+	return ids
+}
+
+func TestMoveSortItem(t *testing.T) {
+	ctx := context.Background()
+	entClient := newSortTestClient(t)
+	opts := SortOrderOptions{Table: "items", Scope: map[string]any{"parent_id": 1}}
+
+	// NULL 视为 0，移动到末尾时不与第一行重复
+	require.NoError(t, MoveAfter(ctx, entClient, opts, 1, 3))
+	require.Equal(t, []int{2, 4, 3, 1}, sortedIDs(t, entClient))
+
+	require.NoError(t, MoveBefore(ctx, entClient, opts, 3, 4))
+	require.Equal(t, []int{2, 3, 4, 1}, sortedIDs(t, entClient))
+
+	require.NoError(t, MoveToPosition(ctx, entClient, opts, 1, 1))
+	require.Equal(t, []int{2, 1, 3, 4}, sortedIDs(t, entClient))
+
+	// 移动到自身之前或之后不做任何操作
+	require.NoError(t, MoveBefore(ctx, entClient, opts, 2, 2))
+	require.NoError(t, MoveAfter(ctx, entClient, opts, 2, 2))
+	require.Equal(t, []int{2, 1, 3, 4}, sortedIDs(t, entClient))
+
+	// 不在排序范围内
+	require.ErrorIs(t, MoveBefore(ctx, entClient, opts, 9, 2), ErrSortItemNotFound)
+	require.ErrorIs(t, MoveAfter(ctx, entClient, opts, 1, 5), ErrSortItemNotFound)
+
+	require.NoError(t, MoveToPosition(ctx, entClient, opts, 3, 100))
+	require.Equal(t, []int{2, 1, 4, 3}, sortedIDs(t, entClient))
+}
+
+func TestNormalizeSortOrder(t *testing.T) {
+	ctx := context.Background()
+	entClient := newSortTestClient(t)
+
+	require.NoError(t, NormalizeSortOrder(ctx, entClient, SortOrderOptions{Table: "items", Scope: map[string]any{"parent_id": 1}}))
+
+	rows, err := entClient.Driver().DB().Query("SELECT id, sort_order FROM items ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	orders := make(map[int]int64)
+	for rows.Next() {
+		var id int
+		var order int64
+		require.NoError(t, rows.Scan(&id, &order))
+		orders[id] = order
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, map[int]int64{2: 1024, 1: 2048, 4: 3072, 3: 4096, 5: 0}, orders)
+}
@@ -0,0 +1,12 @@
+package entgo
+
+import (
+	"github.com/go-openapi/inflect"
+
+	"github.com/alec404/go-libs/stringcase"
+)
+
+// DefaultTableName 由 ent 的类型名推导默认表名，与 ent 生成代码的规则一致，例如：UserGroup -> user_groups
+func DefaultTableName(typ string) string {
+	return stringcase.ToSnakeCase(inflect.Pluralize(typ))
+}
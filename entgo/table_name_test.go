@@ -0,0 +1,12 @@
+package entgo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTableName(t *testing.T) {
+	require.Equal(t, "users", DefaultTableName("User"))
+	require.Equal(t, "user_groups", DefaultTableName("UserGroup"))
+}
@@ -0,0 +1,166 @@
+// Package txhook 在事务提交之后执行回调，用于缓存失效、搜索同步等必须在数据提交后才能执行的副作用。
+//
+// 驱动需要使用 NewDriver 包装（cache.NewDriver 已包含）：
+//
+//	client := ent.NewClient(ent.Driver(txhook.NewDriver(drv)))
+//
+// ent 的 Hook 中通过 Scope 注册回调，变更在事务中执行时回调在提交成功后执行，回滚时丢弃，不在事务中时立即执行：
+//
+//	ctx, scope := txhook.NewScope(ctx)
+//	v, err := next.Mutate(ctx, m)
+//	if err == nil {
+//		scope.OnCommit(func() { ... })
+//	}
+package txhook
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"entgo.io/ent/dialect"
+)
+
+// 确保 Driver 实现了 dialect.Driver 接口
+var _ dialect.Driver = (*Driver)(nil)
+
+// Driver 包装 dialect.Driver，开启的事务在提交成功后执行注册的回调
+type Driver struct {
+	dialect.Driver
+}
+
+// NewDriver 包装驱动，drv 已经是 *Driver 时直接返回
+func NewDriver(drv dialect.Driver) *Driver {
+	if d, ok := drv.(*Driver); ok {
+		return d
+	}
+	return &Driver{Driver: drv}
+}
+
+// Tx 开启事务
+func (d *Driver) Tx(ctx context.Context) (dialect.Tx, error) {
+	tx, err := d.Driver.Tx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
+
+// BeginTx 以指定的隔离级别开启事务，底层驱动需要支持 BeginTx
+func (d *Driver) BeginTx(ctx context.Context, opts *sql.TxOptions) (dialect.Tx, error) {
+	drv, ok := d.Driver.(interface {
+		BeginTx(context.Context, *sql.TxOptions) (dialect.Tx, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("txhook: driver %T does not support BeginTx", d.Driver)
+	}
+
+	tx, err := drv.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
+
+// Tx 事务，在事务中执行语句时将上下文中的 Scope 关联到该事务
+type Tx struct {
+	dialect.Tx
+
+	mu       sync.Mutex
+	onCommit []func()
+}
+
+// Exec 执行语句
+func (t *Tx) Exec(ctx context.Context, query string, args, v any) error {
+	t.bind(ctx)
+	return t.Tx.Exec(ctx, query, args, v)
+}
+
+// Query 执行查询
+func (t *Tx) Query(ctx context.Context, query string, args, v any) error {
+	t.bind(ctx)
+	return t.Tx.Query(ctx, query, args, v)
+}
+
+// OnCommit 注册提交成功后执行的回调，按注册顺序执行
+func (t *Tx) OnCommit(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onCommit = append(t.onCommit, fn)
+}
+
+// Commit 提交事务，成功后执行注册的回调
+func (t *Tx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	fns := t.onCommit
+	t.onCommit = nil
+	t.mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+	return nil
+}
+
+// Rollback 回滚事务，丢弃注册的回调
+func (t *Tx) Rollback() error {
+	t.mu.Lock()
+	t.onCommit = nil
+	t.mu.Unlock()
+
+	return t.Tx.Rollback()
+}
+
+func (t *Tx) bind(ctx context.Context) {
+	for s, _ := ctx.Value(scopeKey{}).(*Scope); s != nil; s = s.parent {
+		s.mu.Lock()
+		if s.tx == nil {
+			s.tx = t
+		}
+		s.mu.Unlock()
+	}
+}
+
+type scopeKey struct{}
+
+// Scope 记录一次变更是否在事务中执行
+type Scope struct {
+	parent *Scope
+
+	mu sync.Mutex
+	tx *Tx
+}
+
+// NewScope 返回带有 Scope 的上下文，变更需要使用返回的上下文执行；嵌套的 Scope 会关联到同一个事务
+func NewScope(ctx context.Context) (context.Context, *Scope) {
+	parent, _ := ctx.Value(scopeKey{}).(*Scope)
+	s := &Scope{parent: parent}
+	return context.WithValue(ctx, scopeKey{}, s), s
+}
+
+// InTx 变更是否在事务中执行，驱动未使用 NewDriver 包装时总是返回 false
+func (s *Scope) InTx() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tx != nil
+}
+
+// OnCommit 变更在事务中执行时，在事务提交成功后执行 fn，回滚时不执行；否则立即执行
+func (s *Scope) OnCommit(fn func()) {
+	s.mu.Lock()
+	tx := s.tx
+	s.mu.Unlock()
+
+	if tx == nil {
+		fn()
+		return
+	}
+	tx.OnCommit(fn)
+}
@@ -0,0 +1,60 @@
+package txhook
+
+import (
+	"context"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDriver struct {
+	dialect.Driver
+}
+
+func (d *fakeDriver) Exec(context.Context, string, any, any) error { return nil }
+
+func (d *fakeDriver) Tx(context.Context) (dialect.Tx, error) {
+	return dialect.NopTx(d), nil
+}
+
+func TestScope(t *testing.T) {
+	drv := NewDriver(&fakeDriver{})
+	assert.Same(t, drv, NewDriver(drv))
+
+	var calls []string
+
+	// 不在事务中时立即执行
+	ctx, scope := NewScope(context.Background())
+	require.NoError(t, drv.Exec(ctx, "UPDATE users SET name = ?", []any{"tom"}, nil))
+	assert.False(t, scope.InTx())
+	scope.OnCommit(func() { calls = append(calls, "direct") })
+	assert.Equal(t, []string{"direct"}, calls)
+
+	// 嵌套的 Scope 关联到同一个事务，提交后按注册顺序执行
+	tx, err := drv.Tx(context.Background())
+	require.NoError(t, err)
+	ctx, outer := NewScope(context.Background())
+	ctx, inner := NewScope(ctx)
+	require.NoError(t, tx.Exec(ctx, "UPDATE users SET name = ?", []any{"tom"}, nil))
+	assert.True(t, outer.InTx())
+	assert.True(t, inner.InTx())
+	inner.OnCommit(func() { calls = append(calls, "inner") })
+	outer.OnCommit(func() { calls = append(calls, "outer") })
+	assert.Equal(t, []string{"direct"}, calls)
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"direct", "inner", "outer"}, calls)
+
+	// 回滚时丢弃
+	tx, err = drv.Tx(context.Background())
+	require.NoError(t, err)
+	ctx, scope = NewScope(context.Background())
+	require.NoError(t, tx.Exec(ctx, "UPDATE users SET name = ?", []any{"tom"}, nil))
+	scope.OnCommit(func() { calls = append(calls, "rollback") })
+	require.NoError(t, tx.Rollback())
+	assert.Equal(t, []string{"direct", "inner", "outer"}, calls)
+
+	_, err = drv.BeginTx(context.Background(), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package entgo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	entSql "entgo.io/ent/dialect/sql"
+	"github.com/google/uuid"
+
+	"github.com/alec404/go-libs/id"
+)
+
+const DefaultWorkerIDTable = "worker_id_leases" // 默认的工作节点ID租约表
+
+// WorkerIDAllocator 基于数据库表的工作节点ID分配器，实现 id.WorkerIDAllocator。
+// 表结构（MySQL）：
+//
+//	CREATE TABLE worker_id_leases (
+//	    worker_id BIGINT PRIMARY KEY,
+//	    owner     VARCHAR(128) NOT NULL,
+//	    expire_at BIGINT NOT NULL -- 过期时间，毫秒时间戳
+//	);
+//
+// 过期时间使用应用服务器的时钟，各节点间的时钟偏差需要远小于租约有效期。
+type WorkerIDAllocator[T EntClientInterface] struct {
+	entClient *EntClient[T]
+	table     string
+	owner     string
+}
+
+// NewWorkerIDAllocator 创建数据库工作节点ID分配器，tableName 为空时使用 DefaultWorkerIDTable
+func NewWorkerIDAllocator[T EntClientInterface](entClient *EntClient[T], tableName string) *WorkerIDAllocator[T] {
+	if tableName == "" {
+		tableName = DefaultWorkerIDTable
+	}
+
+	hostname, _ := os.Hostname()
+
+	return &WorkerIDAllocator[T]{
+		entClient: entClient,
+		table:     tableName,
+		owner:     fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+	}
+}
+
+// Owner 返回租约持有者标识
+func (a *WorkerIDAllocator[T]) Owner() string {
+	return a.owner
+}
+
+// Acquire 分配一个未被占用或已过期的工作节点ID
+func (a *WorkerIDAllocator[T]) Acquire(ctx context.Context, maxID int64, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	expireAt := now.Add(ttl).UnixMilli()
+
+	leases, err := a.loadLeases(ctx, maxID)
+	if err != nil {
+		return 0, err
+	}
+
+	for workerID := int64(0); workerID <= maxID; workerID++ {
+		lease, ok := leases[workerID]
+
+		var query string
+		var args []any
+		switch {
+		case !ok:
+			query, args = entSql.Dialect(a.entClient.Driver().Dialect()).
+				Insert(a.table).
+				Columns("worker_id", "owner", "expire_at").
+				Values(workerID, a.owner, expireAt).
+				OnConflict(entSql.ConflictColumns("worker_id"), entSql.DoNothing()).
+				Query()
+		case lease.expireAt > now.UnixMilli() && lease.owner != a.owner:
+			continue
+		default:
+			// 以读取到的持有者和过期时间作为条件，避免与其他节点同时抢占
+			query, args = entSql.Dialect(a.entClient.Driver().Dialect()).
+				Update(a.table).
+				Set("owner", a.owner).
+				Set("expire_at", expireAt).
+				Where(entSql.And(
+					entSql.EQ("worker_id", workerID),
+					entSql.EQ("owner", lease.owner),
+					entSql.EQ("expire_at", lease.expireAt),
+				)).
+				Query()
+		}
+
+		affected, err := a.exec(ctx, query, args)
+		if err != nil {
+			return 0, err
+		}
+		if affected == 1 {
+			return workerID, nil
+		}
+	}
+
+	return 0, id.ErrNoWorkerIDAvailable
+}
+
+// Renew 延长租约，租约已被其他节点占用时返回 id.ErrWorkerLeaseLost
+func (a *WorkerIDAllocator[T]) Renew(ctx context.Context, workerID int64, ttl time.Duration) error {
+	query, args := entSql.Dialect(a.entClient.Driver().Dialect()).
+		Update(a.table).
+		Set("expire_at", time.Now().Add(ttl).UnixMilli()).
+		Where(entSql.And(
+			entSql.EQ("worker_id", workerID),
+			entSql.EQ("owner", a.owner),
+		)).
+		Query()
+
+	affected, err := a.exec(ctx, query, args)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return id.ErrWorkerLeaseLost
+	}
+	return nil
+}
+
+// Release 将租约置为过期，供其他节点使用
+func (a *WorkerIDAllocator[T]) Release(ctx context.Context, workerID int64) error {
+	query, args := entSql.Dialect(a.entClient.Driver().Dialect()).
+		Update(a.table).
+		Set("expire_at", 0).
+		Where(entSql.And(
+			entSql.EQ("worker_id", workerID),
+			entSql.EQ("owner", a.owner),
+		)).
+		Query()
+
+	_, err := a.exec(ctx, query, args)
+	return err
+}
+
+type workerIDLease struct {
+	owner    string
+	expireAt int64
+}
+
+func (a *WorkerIDAllocator[T]) loadLeases(ctx context.Context, maxID int64) (map[int64]workerIDLease, error) {
+	query, args := entSql.Dialect(a.entClient.Driver().Dialect()).
+		Select("worker_id", "owner", "expire_at").
+		From(entSql.Table(a.table)).
+		Where(entSql.LTE("worker_id", maxID)).
+		Query()
+
+	rows := &entSql.Rows{}
+	if err := a.entClient.Query(ctx, query, args, rows); err != nil {
+		return nil, fmt.Errorf("query worker id leases failed: %w", err)
+	}
+	defer rows.Close()
+
+	leases := make(map[int64]workerIDLease)
+	for rows.Next() {
+		var workerID int64
+		var lease workerIDLease
+		if err := rows.Scan(&workerID, &lease.owner, &lease.expireAt); err != nil {
+			return nil, fmt.Errorf("scan worker id lease failed: %w", err)
+		}
+		leases[workerID] = lease
+	}
+
+	return leases, rows.Err()
+}
+
+func (a *WorkerIDAllocator[T]) exec(ctx context.Context, query string, args []any) (int64, error) {
+	affected, err := execAffected(ctx, a.entClient.Driver(), query, args)
+	if err != nil {
+		return 0, fmt.Errorf("update worker id lease failed: %w", err)
+	}
+	return affected, nil
+}
+
+// 确保 WorkerIDAllocator 实现了 id.WorkerIDAllocator 接口
+var _ id.WorkerIDAllocator = (*WorkerIDAllocator[EntClientInterface])(nil)
@@ -0,0 +1,181 @@
+package esquery
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/olivere/elastic"
+)
+
+// Agg 命名的聚合，子聚合通过 subAggs 参数组合
+type Agg struct {
+	Name        string
+	Aggregation elastic.Aggregation
+}
+
+// AddAggregation 添加聚合，同名的聚合会被覆盖
+func (q *ESQuery) AddAggregation(aggs ...Agg) {
+	if q.Aggregations == nil {
+		q.Aggregations = make(map[string]elastic.Aggregation, len(aggs))
+	}
+	for _, agg := range aggs {
+		q.Aggregations[agg.Name] = agg.Aggregation
+	}
+}
+
+// TermsAgg 按字段值分组，size 为返回的分组数量，小于 1 时使用 ES 的默认值
+func TermsAgg(name, field string, size int, subAggs ...Agg) Agg {
+	agg := elastic.NewTermsAggregation().Field(field)
+	if size > 0 {
+		agg = agg.Size(size)
+	}
+	for _, sub := range subAggs {
+		agg = agg.SubAggregation(sub.Name, sub.Aggregation)
+	}
+	return Agg{Name: name, Aggregation: agg}
+}
+
+// DateHistogramAgg 按时间间隔分组，interval 例如 "1d"、"month"；
+// timeZone 为空时使用 UTC，没有数据的时间段不返回
+func DateHistogramAgg(name, field, interval, timeZone string, subAggs ...Agg) Agg {
+	agg := elastic.NewDateHistogramAggregation().
+		Field(field).
+		Interval(interval).
+		MinDocCount(1)
+	if timeZone != "" {
+		agg = agg.TimeZone(timeZone)
+	}
+	for _, sub := range subAggs {
+		agg = agg.SubAggregation(sub.Name, sub.Aggregation)
+	}
+	return Agg{Name: name, Aggregation: agg}
+}
+
+// NestedAgg 在 nested 字段 path 下聚合
+func NestedAgg(name, path string, subAggs ...Agg) Agg {
+	agg := elastic.NewNestedAggregation().Path(path)
+	for _, sub := range subAggs {
+		agg = agg.SubAggregation(sub.Name, sub.Aggregation)
+	}
+	return Agg{Name: name, Aggregation: agg}
+}
+
+// SumAgg 求和
+func SumAgg(name, field string) Agg {
+	return Agg{Name: name, Aggregation: elastic.NewSumAggregation().Field(field)}
+}
+
+// AvgAgg 求平均值
+func AvgAgg(name, field string) Agg {
+	return Agg{Name: name, Aggregation: elastic.NewAvgAggregation().Field(field)}
+}
+
+// CardinalityAgg 去重计数（近似值）
+func CardinalityAgg(name, field string) Agg {
+	return Agg{Name: name, Aggregation: elastic.NewCardinalityAggregation().Field(field)}
+}
+
+// TermsBucket 按字段值分组的结果
+type TermsBucket struct {
+	Key          string // 分组的值，数字会转为字符串
+	DocCount     int64
+	Aggregations elastic.Aggregations // 子聚合结果
+}
+
+// DateHistogramBucket 按时间间隔分组的结果
+type DateHistogramBucket struct {
+	Time         time.Time // 时间段的开始时间（UTC）
+	KeyAsString  string
+	DocCount     int64
+	Aggregations elastic.Aggregations // 子聚合结果
+}
+
+// TermsBuckets 解析 terms 聚合结果，聚合不存在时返回 false
+func TermsBuckets(aggs elastic.Aggregations, name string) ([]TermsBucket, bool) {
+	items, ok := aggs.Terms(name)
+	if !ok || items == nil {
+		return nil, false
+	}
+
+	buckets := make([]TermsBucket, 0, len(items.Buckets))
+	for _, item := range items.Buckets {
+		bucket := TermsBucket{DocCount: item.DocCount, Aggregations: item.Aggregations}
+		switch {
+		case item.KeyAsString != nil:
+			bucket.Key = *item.KeyAsString
+		case item.KeyNumber != "":
+			bucket.Key = item.KeyNumber.String()
+		default:
+			bucket.Key = fmt.Sprint(item.Key)
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, true
+}
+
+// TermsCounts 将 terms 聚合结果转为 分组值 -> 文档数
+func TermsCounts(aggs elastic.Aggregations, name string) map[string]int64 {
+	buckets, _ := TermsBuckets(aggs, name)
+
+	counts := make(map[string]int64, len(buckets))
+	for _, bucket := range buckets {
+		counts[bucket.Key] = bucket.DocCount
+	}
+	return counts
+}
+
+// TermsMetrics 将 terms 聚合结果转为 分组值 -> 子聚合 metricName 的值
+func TermsMetrics(aggs elastic.Aggregations, name, metricName string) map[string]float64 {
+	buckets, _ := TermsBuckets(aggs, name)
+
+	metrics := make(map[string]float64, len(buckets))
+	for _, bucket := range buckets {
+		if v, ok := MetricValue(bucket.Aggregations, metricName); ok {
+			metrics[bucket.Key] = v
+		}
+	}
+	return metrics
+}
+
+// DateHistogramBuckets 解析 date_histogram 聚合结果，聚合不存在时返回 false
+func DateHistogramBuckets(aggs elastic.Aggregations, name string) ([]DateHistogramBucket, bool) {
+	items, ok := aggs.DateHistogram(name)
+	if !ok || items == nil {
+		return nil, false
+	}
+
+	buckets := make([]DateHistogramBucket, 0, len(items.Buckets))
+	for _, item := range items.Buckets {
+		bucket := DateHistogramBucket{
+			Time:         time.UnixMilli(int64(item.Key)).UTC(),
+			DocCount:     item.DocCount,
+			Aggregations: item.Aggregations,
+		}
+		if item.KeyAsString != nil {
+			bucket.KeyAsString = *item.KeyAsString
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, true
+}
+
+// MetricValue 读取 sum/avg/cardinality 等单值聚合的结果，聚合不存在或没有值时返回 false
+func MetricValue(aggs elastic.Aggregations, name string) (float64, bool) {
+	// 单值聚合的结果格式相同，都可以按 sum 解析
+	metric, ok := aggs.Sum(name)
+	if !ok || metric == nil || metric.Value == nil {
+		return 0, false
+	}
+	return *metric.Value, true
+}
+
+// NestedAggregations 读取 nested 聚合下的子聚合结果
+func NestedAggregations(aggs elastic.Aggregations, name string) (elastic.Aggregations, int64, bool) {
+	bucket, ok := aggs.Nested(name)
+	if !ok || bucket == nil {
+		return nil, 0, false
+	}
+	return bucket.Aggregations, bucket.DocCount, true
+}
@@ -0,0 +1,75 @@
+package esquery
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddAggregation(t *testing.T) {
+	q := NewESQuery([]string{"orders"})
+	q.AddAggregation(
+		TermsAgg("by_status", "status", 5, SumAgg("amount", "amount")),
+		DateHistogramAgg("per_day", "created_at", "1d", "+08:00", CardinalityAgg("users", "user_id")),
+		NestedAgg("items", "items", AvgAgg("price", "items.price")),
+	)
+
+	src, err := q.BuildSearchSource().Source()
+	require.NoError(t, err)
+	b, err := json.Marshal(src.(map[string]interface{})["aggregations"])
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"by_status": {"terms": {"field": "status", "size": 5}, "aggregations": {"amount": {"sum": {"field": "amount"}}}},
+		"per_day": {"date_histogram": {"field": "created_at", "interval": "1d", "min_doc_count": 1, "time_zone": "+08:00"}, "aggregations": {"users": {"cardinality": {"field": "user_id"}}}},
+		"items": {"nested": {"path": "items"}, "aggregations": {"price": {"avg": {"field": "items.price"}}}}
+	}`, string(b))
+}
+
+func TestAggregationDecoders(t *testing.T) {
+	var aggs elastic.Aggregations
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"by_status": {"buckets": [
+			{"key": "paid", "doc_count": 3, "amount": {"value": 30.5}},
+			{"key": 2, "doc_count": 1, "amount": {"value": null}}
+		]},
+		"per_day": {"buckets": [
+			{"key": 1704067200000, "key_as_string": "2024-01-01", "doc_count": 4, "users": {"value": 2}}
+		]},
+		"items": {"doc_count": 7, "price": {"value": 9.9}}
+	}`), &aggs))
+
+	buckets, ok := TermsBuckets(aggs, "by_status")
+	require.True(t, ok)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "paid", buckets[0].Key)
+	assert.Equal(t, "2", buckets[1].Key)
+
+	assert.Equal(t, map[string]int64{"paid": 3, "2": 1}, TermsCounts(aggs, "by_status"))
+	assert.Equal(t, map[string]float64{"paid": 30.5}, TermsMetrics(aggs, "by_status", "amount"))
+
+	days, ok := DateHistogramBuckets(aggs, "per_day")
+	require.True(t, ok)
+	require.Len(t, days, 1)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), days[0].Time)
+	assert.Equal(t, "2024-01-01", days[0].KeyAsString)
+	users, ok := MetricValue(days[0].Aggregations, "users")
+	require.True(t, ok)
+	assert.Equal(t, float64(2), users)
+
+	nested, docCount, ok := NestedAggregations(aggs, "items")
+	require.True(t, ok)
+	assert.Equal(t, int64(7), docCount)
+	price, ok := MetricValue(nested, "price")
+	require.True(t, ok)
+	assert.Equal(t, 9.9, price)
+
+	_, ok = TermsBuckets(aggs, "missing")
+	assert.False(t, ok)
+	_, ok = MetricValue(aggs, "missing")
+	assert.False(t, ok)
+}
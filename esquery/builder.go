@@ -0,0 +1,85 @@
+package esquery
+
+import (
+	"strings"
+
+	"github.com/olivere/elastic"
+
+	paging "github.com/alec404/go-libs/pagination"
+)
+
+const MaxResultWindow = 10000 // 索引默认的 max_result_window，不分页时最多返回的行数
+
+// BuildESQuery 使用与 entgo/query 的 BuildQuerySelector 相同的过滤、排序、分页、字段参数构建查询，
+// 同一套接口参数可以由数据库或 Elasticsearch 提供服务
+func BuildESQuery(
+	indices []string,
+	andFilterJsonString, orFilterJsonString string,
+	page, pageSize int32, noPaging bool,
+	orderBys []string, defaultOrderField string,
+	selectFields []string,
+	opts ...FilterOption,
+) (*ESQuery, error) {
+	q := NewESQuery(indices)
+
+	if err := q.ApplyFilter(andFilterJsonString, orFilterJsonString, opts...); err != nil {
+		return nil, err
+	}
+
+	q.ApplyOrder(orderBys, defaultOrderField)
+	q.ApplyPagination(page, pageSize, noPaging)
+	q.ApplySelect(selectFields)
+
+	return q, nil
+}
+
+// ApplyOrder 添加排序，"-" 前缀表示降序；orderBys 为空时按 defaultOrderField 降序
+func (q *ESQuery) ApplyOrder(orderBys []string, defaultOrderField string) {
+	if len(orderBys) == 0 {
+		if defaultOrderField != "" {
+			q.AddSort(elastic.NewFieldSort(defaultOrderField).Desc())
+		}
+		return
+	}
+
+	for _, v := range orderBys {
+		if strings.HasPrefix(v, "-") {
+			// 降序
+			if key := v[1:]; len(key) > 0 {
+				q.AddSort(elastic.NewFieldSort(key).Desc())
+			}
+		} else if len(v) > 0 {
+			// 升序
+			q.AddSort(elastic.NewFieldSort(v).Asc())
+		}
+	}
+}
+
+// ApplyPagination 设置分页，noPaging 为 true 时返回前 MaxResultWindow 行
+func (q *ESQuery) ApplyPagination(page, pageSize int32, noPaging bool) {
+	if noPaging {
+		q.From = DefaultFrom
+		q.Size = MaxResultWindow
+		return
+	}
+
+	if page < 1 {
+		page = paging.DefaultPage
+	}
+	if pageSize < 1 {
+		pageSize = paging.DefaultPageSize
+	}
+
+	q.From = int32(paging.GetPageOffset(page, pageSize))
+	q.Size = pageSize
+}
+
+// ApplySelect 设置返回的 _source 字段
+func (q *ESQuery) ApplySelect(fields []string) {
+	for _, field := range fields {
+		if field == "id_" || field == "_id" {
+			field = "id"
+		}
+		q.Include = append(q.Include, normalizeFieldPath(field))
+	}
+}
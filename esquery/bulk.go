@@ -0,0 +1,413 @@
+package esquery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/olivere/elastic"
+)
+
+const (
+	DefaultBulkActions       = 1000                   // 默认每批的最大操作数
+	DefaultBulkSize          = 5 << 20                // 默认每批的最大字节数
+	DefaultBulkFlushInterval = time.Second            // 默认的定时提交间隔
+	DefaultBulkWorkers       = 1                      // 默认的并发提交数
+	DefaultBulkMaxRetries    = 3                      // 默认的最大重试次数
+	DefaultBulkRetryInitial  = 100 * time.Millisecond // 默认的首次重试等待时间
+	DefaultBulkRetryMax      = 10 * time.Second       // 默认的最大重试等待时间
+	DefaultBulkTimeout       = 30 * time.Second       // 默认的单批请求超时时间
+)
+
+var ErrBulkIndexerClosed = errors.New("bulk indexer is closed")
+
+// BulkDeadLetterFunc 重试后仍然失败的操作，item 为 ES 返回的失败结果，整批请求失败时 item 为 nil
+type BulkDeadLetterFunc func(req elastic.BulkableRequest, item *elastic.BulkResponseItem, err error)
+
+// BulkIndexerStats 批量写入的统计
+type BulkIndexerStats struct {
+	Added     int64 // 已添加的操作数
+	Succeeded int64 // 成功的操作数
+	Failed    int64 // 重试后仍失败的操作数
+	Retried   int64 // 重试的操作数
+	Committed int64 // 提交的批次数，重试不重复计数
+}
+
+// BulkIndexerOption BulkIndexer 的配置项
+type BulkIndexerOption func(b *BulkIndexer)
+
+// WithBulkActions 设置每批的最大操作数
+func WithBulkActions(actions int) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.actions = actions
+	}
+}
+
+// WithBulkSize 设置每批的最大字节数
+func WithBulkSize(size int) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.size = size
+	}
+}
+
+// WithBulkFlushInterval 设置定时提交间隔，小于等于 0 时只在达到批量大小或调用 Flush 时提交
+func WithBulkFlushInterval(interval time.Duration) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.flushInterval = interval
+	}
+}
+
+// WithBulkWorkers 设置并发提交数
+func WithBulkWorkers(workers int) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.workers = workers
+	}
+}
+
+// WithBulkRetry 设置失败重试次数和指数退避的初始、最大等待时间
+func WithBulkRetry(maxRetries int, initial, max time.Duration) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.maxRetries = maxRetries
+		b.retryInitial = initial
+		b.retryMax = max
+	}
+}
+
+// WithBulkTimeout 设置单批请求的超时时间
+func WithBulkTimeout(timeout time.Duration) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.timeout = timeout
+	}
+}
+
+// WithBulkDocType 设置文档类型，ES 6 需要设置（例如 "_doc"），ES 7+ 不需要
+func WithBulkDocType(docType string) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.docType = docType
+	}
+}
+
+// WithBulkDeadLetter 设置重试后仍失败的操作的回调
+func WithBulkDeadLetter(fn BulkDeadLetterFunc) BulkIndexerOption {
+	return func(b *BulkIndexer) {
+		b.deadLetter = fn
+	}
+}
+
+// BulkIndexer 批量写入索引，达到批量大小或定时提交。
+// 整批请求失败以及 408/429/5xx 的单条失败会按指数退避重试，其他失败和重试耗尽的操作交给死信回调。
+type BulkIndexer struct {
+	client *elastic.Client
+
+	actions       int
+	size          int
+	flushInterval time.Duration
+	workers       int
+	maxRetries    int
+	retryInitial  time.Duration
+	retryMax      time.Duration
+	timeout       time.Duration
+	docType       string
+	deadLetter    BulkDeadLetterFunc
+
+	mu           sync.Mutex
+	idle         *sync.Cond
+	pending      []elastic.BulkableRequest
+	pendingBytes int
+	inflight     int // 已交给后台但未提交完成的批次数
+	closed       bool
+
+	batches  chan []elastic.BulkableRequest
+	workerWg sync.WaitGroup
+	stopC    chan struct{}
+
+	added     atomic.Int64
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	retried   atomic.Int64
+	committed atomic.Int64
+}
+
+// NewBulkIndexer 创建批量写入器并启动后台提交，使用完后需要调用 Close
+func NewBulkIndexer(client *elastic.Client, opts ...BulkIndexerOption) *BulkIndexer {
+	b := &BulkIndexer{
+		client:        client,
+		actions:       DefaultBulkActions,
+		size:          DefaultBulkSize,
+		flushInterval: DefaultBulkFlushInterval,
+		workers:       DefaultBulkWorkers,
+		maxRetries:    DefaultBulkMaxRetries,
+		retryInitial:  DefaultBulkRetryInitial,
+		retryMax:      DefaultBulkRetryMax,
+		timeout:       DefaultBulkTimeout,
+		stopC:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if b.workers < 1 {
+		b.workers = 1
+	}
+	b.batches = make(chan []elastic.BulkableRequest, b.workers)
+	b.idle = sync.NewCond(&b.mu)
+
+	for i := 0; i < b.workers; i++ {
+		b.workerWg.Add(1)
+		go b.work()
+	}
+
+	if b.flushInterval > 0 {
+		b.workerWg.Add(1)
+		go b.flushPeriodically()
+	}
+
+	return b
+}
+
+// Index 写入整个文档
+func (b *BulkIndexer) Index(index, id string, doc interface{}) error {
+	req := elastic.NewBulkIndexRequest().Index(index).Id(id).Doc(doc)
+	if b.docType != "" {
+		req = req.Type(b.docType)
+	}
+	return b.Add(req)
+}
+
+// Update 部分更新文档，文档不存在时写入
+func (b *BulkIndexer) Update(index, id string, doc interface{}) error {
+	req := elastic.NewBulkUpdateRequest().Index(index).Id(id).Doc(doc).DocAsUpsert(true)
+	if b.docType != "" {
+		req = req.Type(b.docType)
+	}
+	return b.Add(req)
+}
+
+// Delete 删除文档，文档不存在时视为成功
+func (b *BulkIndexer) Delete(index, id string) error {
+	req := elastic.NewBulkDeleteRequest().Index(index).Id(id)
+	if b.docType != "" {
+		req = req.Type(b.docType)
+	}
+	return b.Add(req)
+}
+
+// Add 添加批量操作
+func (b *BulkIndexer) Add(req elastic.BulkableRequest) error {
+	lines, err := req.Source()
+	if err != nil {
+		return err
+	}
+	size := 0
+	for _, line := range lines {
+		size += len(line) + 1
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrBulkIndexerClosed
+	}
+
+	b.pending = append(b.pending, req)
+	b.pendingBytes += size
+	b.added.Add(1)
+
+	var batch []elastic.BulkableRequest
+	if (b.actions > 0 && len(b.pending) >= b.actions) || (b.size > 0 && b.pendingBytes >= b.size) {
+		batch = b.takeLocked()
+	}
+	b.mu.Unlock()
+
+	b.dispatch(batch)
+
+	return nil
+}
+
+// Flush 提交所有待写入的操作并等待完成
+func (b *BulkIndexer) Flush() {
+	b.mu.Lock()
+	batch := b.takeLocked()
+	b.mu.Unlock()
+
+	b.dispatch(batch)
+	b.wait()
+}
+
+// Close 提交剩余的操作并停止后台提交
+func (b *BulkIndexer) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	batch := b.takeLocked()
+	b.mu.Unlock()
+
+	b.dispatch(batch)
+	close(b.stopC)
+	b.wait()
+	close(b.batches)
+	b.workerWg.Wait()
+}
+
+// Stats 返回统计
+func (b *BulkIndexer) Stats() BulkIndexerStats {
+	return BulkIndexerStats{
+		Added:     b.added.Load(),
+		Succeeded: b.succeeded.Load(),
+		Failed:    b.failed.Load(),
+		Retried:   b.retried.Load(),
+		Committed: b.committed.Load(),
+	}
+}
+
+// takeLocked 取出待写入的操作作为一个批次，需要持有锁
+func (b *BulkIndexer) takeLocked() []elastic.BulkableRequest {
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	batch := b.pending
+	b.pending, b.pendingBytes = nil, 0
+	b.inflight++
+
+	return batch
+}
+
+// dispatch 将批次交给后台提交，提交队列满时阻塞，形成背压
+func (b *BulkIndexer) dispatch(batch []elastic.BulkableRequest) {
+	if batch != nil {
+		b.batches <- batch
+	}
+}
+
+// wait 等待所有批次提交完成
+func (b *BulkIndexer) wait() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.inflight > 0 {
+		b.idle.Wait()
+	}
+}
+
+func (b *BulkIndexer) flushPeriodically() {
+	defer b.workerWg.Done()
+
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			var batch []elastic.BulkableRequest
+			b.mu.Lock()
+			if !b.closed {
+				batch = b.takeLocked()
+			}
+			b.mu.Unlock()
+
+			b.dispatch(batch)
+		case <-b.stopC:
+			return
+		}
+	}
+}
+
+func (b *BulkIndexer) work() {
+	defer b.workerWg.Done()
+
+	for batch := range b.batches {
+		b.commit(batch)
+
+		b.mu.Lock()
+		b.inflight--
+		b.idle.Broadcast()
+		b.mu.Unlock()
+	}
+}
+
+// commit 提交一批操作，可重试的失败按指数退避重试
+func (b *BulkIndexer) commit(reqs []elastic.BulkableRequest) {
+	b.committed.Add(1)
+	for attempt := 0; len(reqs) > 0; attempt++ {
+		if attempt > 0 {
+			b.retried.Add(int64(len(reqs)))
+			time.Sleep(b.backoff(attempt))
+		}
+
+		retry, err := b.commitOnce(reqs, attempt >= b.maxRetries)
+		if err != nil {
+			if attempt >= b.maxRetries {
+				b.fail(reqs, nil, err)
+				return
+			}
+			continue
+		}
+		reqs = retry
+	}
+}
+
+// commitOnce 提交一次，返回需要重试的操作；last 为 true 时可重试的失败也交给死信回调
+func (b *BulkIndexer) commitOnce(reqs []elastic.BulkableRequest, last bool) ([]elastic.BulkableRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	res, err := b.client.Bulk().Add(reqs...).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var retry []elastic.BulkableRequest
+	for i, req := range reqs {
+		if i >= len(res.Items) {
+			retry = append(retry, req)
+			continue
+		}
+
+		for _, item := range res.Items[i] {
+			switch {
+			case item.Status < 300, item.Status == http.StatusNotFound && isBulkDelete(req):
+				b.succeeded.Add(1)
+			case isRetryableStatus(item.Status) && !last:
+				retry = append(retry, req)
+			default:
+				b.fail([]elastic.BulkableRequest{req}, item, nil)
+			}
+		}
+	}
+
+	return retry, nil
+}
+
+func (b *BulkIndexer) fail(reqs []elastic.BulkableRequest, item *elastic.BulkResponseItem, err error) {
+	b.failed.Add(int64(len(reqs)))
+	if b.deadLetter == nil {
+		return
+	}
+	for _, req := range reqs {
+		b.deadLetter(req, item, err)
+	}
+}
+
+func (b *BulkIndexer) backoff(attempt int) time.Duration {
+	d := b.retryInitial << (attempt - 1)
+	if d <= 0 || d > b.retryMax {
+		d = b.retryMax
+	}
+	return d
+}
+
+func isBulkDelete(req elastic.BulkableRequest) bool {
+	_, ok := req.(*elastic.BulkDeleteRequest)
+	return ok
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,175 @@
+package esquery_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alec404/go-libs/esquery"
+	"github.com/alec404/go-libs/esquery/estest"
+)
+
+// bulkAction _bulk 请求中的一个操作
+type bulkAction struct {
+	Op    string
+	Index string
+	ID    string
+}
+
+// parseBulk 解析 _bulk 请求体，响应函数中也会调用，不能使用 require，无法解析的行忽略
+func parseBulk(body []byte) []bulkAction {
+	var actions []bulkAction
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var line map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		for op, meta := range line {
+			actions = append(actions, bulkAction{Op: op, Index: meta.Index, ID: meta.ID})
+			if op != "delete" {
+				scanner.Scan() // 文档内容
+			}
+		}
+	}
+	return actions
+}
+
+// newBulkServer 模拟 _bulk 接口，status 返回每个文档ID在第 attempt 次提交时的状态码
+func newBulkServer(t *testing.T, status func(id string, attempt int) int) *estest.Server {
+	t.Helper()
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+
+	srv := estest.NewServer(t)
+	srv.On("POST", "/_bulk", func(r estest.Request) estest.Response {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var items []string
+		for _, a := range parseBulk(r.Body) {
+			attempts[a.ID]++
+			items = append(items, fmt.Sprintf(`{%q:{"_index":%q,"_id":%q,"status":%d}}`, a.Op, a.Index, a.ID, status(a.ID, attempts[a.ID])))
+		}
+		return estest.Response{Body: fmt.Sprintf(`{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))}
+	})
+	return srv
+}
+
+// bulkBatchSizes 返回每次 _bulk 请求的操作数
+func bulkBatchSizes(srv *estest.Server) []int {
+	var sizes []int
+	for _, r := range srv.Requests() {
+		sizes = append(sizes, len(parseBulk(r.Body)))
+	}
+	return sizes
+}
+
+func TestBulkIndexer(t *testing.T) {
+	srv := newBulkServer(t, func(id string, attempt int) int {
+		switch {
+		case id == "retry" && attempt == 1:
+			return http.StatusTooManyRequests
+		case id == "bad":
+			return http.StatusBadRequest
+		case id == "missing":
+			return http.StatusNotFound
+		default:
+			return http.StatusOK
+		}
+	})
+
+	var mu sync.Mutex
+	var dead []*elastic.BulkResponseItem
+	indexer := esquery.NewBulkIndexer(srv.Client(),
+		esquery.WithBulkActions(3),
+		esquery.WithBulkFlushInterval(0),
+		esquery.WithBulkRetry(2, time.Millisecond, 10*time.Millisecond),
+		esquery.WithBulkDeadLetter(func(req elastic.BulkableRequest, item *elastic.BulkResponseItem, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			dead = append(dead, item)
+		}),
+	)
+
+	require.NoError(t, indexer.Index("users", "1", map[string]string{"name": "a"}))
+	require.NoError(t, indexer.Update("users", "retry", map[string]string{"name": "b"}))
+	require.NoError(t, indexer.Index("users", "bad", map[string]string{"name": "c"}))
+	require.NoError(t, indexer.Delete("users", "missing"))
+
+	indexer.Flush()
+
+	mu.Lock()
+	require.Len(t, dead, 1)
+	require.NotNil(t, dead[0])
+	assert.Equal(t, "bad", dead[0].Id)
+	mu.Unlock()
+	assert.Equal(t, []int{3, 1, 1}, bulkBatchSizes(srv)) // 第一批 3 条，重试 1 条，Flush 提交剩余 1 条
+
+	stats := indexer.Stats()
+	assert.Equal(t, int64(4), stats.Added)
+	assert.Equal(t, int64(3), stats.Succeeded)
+	assert.Equal(t, int64(1), stats.Failed)
+	assert.Equal(t, int64(1), stats.Retried)
+	assert.Equal(t, int64(2), stats.Committed) // 重试不计入批次数
+
+	indexer.Close()
+	assert.ErrorIs(t, indexer.Index("users", "2", nil), esquery.ErrBulkIndexerClosed)
+}
+
+func TestBulkIndexerRetryExhausted(t *testing.T) {
+	srv := newBulkServer(t, func(string, int) int {
+		return http.StatusServiceUnavailable
+	})
+
+	var mu sync.Mutex
+	var dead []int
+	indexer := esquery.NewBulkIndexer(srv.Client(),
+		esquery.WithBulkFlushInterval(0),
+		esquery.WithBulkRetry(2, time.Millisecond, time.Millisecond),
+		esquery.WithBulkDeadLetter(func(req elastic.BulkableRequest, item *elastic.BulkResponseItem, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if item != nil {
+				dead = append(dead, item.Status)
+			}
+		}),
+	)
+
+	require.NoError(t, indexer.Index("users", "1", nil))
+	indexer.Close()
+
+	mu.Lock()
+	assert.Equal(t, []int{http.StatusServiceUnavailable}, dead)
+	mu.Unlock()
+	assert.Equal(t, []int{1, 1, 1}, bulkBatchSizes(srv))
+	assert.Equal(t, int64(1), indexer.Stats().Committed)
+}
+
+func TestBulkIndexerFlushInterval(t *testing.T) {
+	srv := newBulkServer(t, func(string, int) int {
+		return http.StatusCreated
+	})
+
+	indexer := esquery.NewBulkIndexer(srv.Client(), esquery.WithBulkFlushInterval(10*time.Millisecond), esquery.WithBulkWorkers(2))
+	defer indexer.Close()
+
+	require.NoError(t, indexer.Index("users", "1", nil))
+	assert.Eventually(t, func() bool {
+		return len(srv.Requests()) == 1
+	}, time.Second, 5*time.Millisecond)
+}
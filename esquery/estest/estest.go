@@ -0,0 +1,214 @@
+// Package estest 提供不依赖 ES 集群的测试工具：记录请求并返回预设响应的模拟服务，以及 golden 文件断言。
+//
+//	srv := estest.NewServer(t)
+//	srv.Respond("POST", "/users/_search", http.StatusOK, `{"hits":{"total":0,"hits":[]}}`)
+//
+//	page, err := esquery.Search[User](ctx, srv.Client(), q)
+//	estest.AssertGoldenJSON(t, "testdata/search.golden.json", srv.LastRequest().Body)
+package estest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/olivere/elastic"
+
+	"github.com/alec404/go-libs/esquery"
+)
+
+// UpdateGoldenEnv 设置该环境变量（例如 ESTEST_UPDATE_GOLDEN=1 go test ./...）时，golden 断言会用实际结果覆盖 golden 文件
+const UpdateGoldenEnv = "ESTEST_UPDATE_GOLDEN"
+
+// Request 记录的请求
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Body   []byte
+}
+
+// JSON 返回规范化的请求体，参见 esquery.CanonicalJSON
+func (r Request) JSON() ([]byte, error) {
+	return esquery.CanonicalJSON(r.Body)
+}
+
+// Response 预设的响应
+type Response struct {
+	Status int // 为 0 时返回 200
+	Body   string
+}
+
+// Responder 根据请求返回响应
+type Responder func(r Request) Response
+
+type route struct {
+	method    string
+	path      string
+	responder Responder
+}
+
+// Server 模拟的 ES 服务，按注册顺序匹配请求，没有匹配的请求返回 404
+type Server struct {
+	srv    *httptest.Server
+	client *elastic.Client
+
+	mu       sync.Mutex
+	routes   []route
+	requests []Request
+}
+
+// NewServer 创建模拟服务，测试结束时自动关闭
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.srv.Close)
+
+	client, err := elastic.NewClient(elastic.SetURL(s.srv.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	if err != nil {
+		t.Fatalf("create elastic client failed: %v", err)
+	}
+	s.client = client
+
+	return s
+}
+
+// Client 返回连接模拟服务的客户端
+func (s *Server) Client() *elastic.Client {
+	return s.client
+}
+
+// URL 返回模拟服务的地址
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// On 注册响应，method 为空时匹配所有方法，pattern 的语法参见 path.Match，例如 "/users_v*/_alias"
+func (s *Server) On(method, pattern string, responder Responder) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.routes = append(s.routes, route{method: method, path: pattern, responder: responder})
+	return s
+}
+
+// Respond 注册固定的响应
+func (s *Server) Respond(method, pattern string, status int, body string) *Server {
+	return s.On(method, pattern, func(Request) Response {
+		return Response{Status: status, Body: body}
+	})
+}
+
+// Requests 返回已记录的请求
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
+
+// LastRequest 返回最后一个请求，没有请求时返回零值
+func (s *Server) LastRequest() Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.requests) == 0 {
+		return Request{}
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// Reset 清空已记录的请求，注册的响应保留
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Body: body}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	responder := s.match(req)
+	s.mu.Unlock()
+
+	res := Response{
+		Status: http.StatusNotFound,
+		Body:   fmt.Sprintf(`{"error":{"type":"estest_no_route","reason":"no response registered for %s %s"},"status":404}`, req.Method, req.Path),
+	}
+	if responder != nil {
+		res = responder(req)
+	}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(res.Status)
+	_, _ = io.WriteString(w, res.Body)
+}
+
+// match 返回第一个匹配的响应，需要持有锁
+func (s *Server) match(req Request) Responder {
+	for _, rt := range s.routes {
+		if rt.method != "" && rt.method != req.Method {
+			continue
+		}
+		if ok, _ := path.Match(rt.path, req.Path); ok {
+			return rt.responder
+		}
+	}
+	return nil
+}
+
+// AssertGoldenJSON 将 actual 规范化后与 golden 文件比较，设置 UpdateGoldenEnv 环境变量时更新 golden 文件
+func AssertGoldenJSON(t testing.TB, golden string, actual []byte) {
+	t.Helper()
+
+	canonical, err := esquery.CanonicalJSON(actual)
+	if err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, actual)
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err = os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			t.Fatalf("create golden dir failed: %v", err)
+		}
+		if err = os.WriteFile(golden, canonical, 0o644); err != nil {
+			t.Fatalf("write golden file failed: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file failed: %v (run with %s=1 to create it)", err, UpdateGoldenEnv)
+	}
+	if !bytes.Equal(expected, canonical) {
+		t.Errorf("%s mismatch (run with %s=1 to update)\nexpected:\n%s\nactual:\n%s", golden, UpdateGoldenEnv, expected, canonical)
+	}
+}
+
+// AssertQueryGolden 将查询的请求体与 golden 文件比较，参见 AssertGoldenJSON
+func AssertQueryGolden(t testing.TB, golden string, q *esquery.ESQuery) {
+	t.Helper()
+
+	b, err := q.RenderJSON()
+	if err != nil {
+		t.Fatalf("render query failed: %v", err)
+	}
+	AssertGoldenJSON(t, golden, b)
+}
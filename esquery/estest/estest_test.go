@@ -0,0 +1,65 @@
+package estest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer(t)
+	srv.Respond(http.MethodGet, "/users_v*/_alias", http.StatusOK, `{"users_v1":{"aliases":{"users":{}}}}`).
+		On("", "/_bulk", func(r Request) Response {
+			return Response{Body: `{"took":1,"errors":false,"items":[]}`}
+		})
+
+	ctx := context.Background()
+	res, err := srv.Client().PerformRequest(ctx, elastic.PerformRequestOptions{Method: "GET", Path: "/users_v*/_alias"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"users_v1":{"aliases":{"users":{}}}}`, string(res.Body))
+
+	_, err = srv.Client().PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "POST",
+		Path:   "/users/_search",
+		Params: map[string][]string{"routing": {"1"}},
+		Body:   map[string]interface{}{"size": 1, "query": map[string]interface{}{"match_all": map[string]interface{}{}}},
+	})
+	assert.True(t, elastic.IsNotFound(err))
+
+	requests := srv.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "/users/_search", srv.LastRequest().Path)
+	assert.Equal(t, "1", srv.LastRequest().Query.Get("routing"))
+
+	b, err := srv.LastRequest().JSON()
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"query\": {\n    \"match_all\": {}\n  },\n  \"size\": 1\n}\n", string(b))
+
+	srv.Reset()
+	assert.Empty(t, srv.Requests())
+	assert.Equal(t, Request{}, srv.LastRequest())
+}
+
+func TestAssertGoldenJSON(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "query.golden.json")
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	AssertGoldenJSON(t, golden, []byte(`{"b":1,"a":{"tag":"<em>"}}`))
+
+	b, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"a\": {\n    \"tag\": \"<em>\"\n  },\n  \"b\": 1\n}\n", string(b))
+
+	t.Setenv(UpdateGoldenEnv, "")
+	AssertGoldenJSON(t, golden, []byte(`{"a":{"tag":"<em>"},"b":1}`))
+
+	mock := &testing.T{}
+	AssertGoldenJSON(mock, golden, []byte(`{"a":{"tag":"<em>"},"b":2}`))
+	assert.True(t, mock.Failed())
+}
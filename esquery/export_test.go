@@ -0,0 +1,4 @@
+package esquery
+
+// MigrateCatchUpSkew 导出给外部测试包使用
+const MigrateCatchUpSkew = migrateCatchUpSkew
@@ -0,0 +1,336 @@
+package esquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/olivere/elastic"
+
+	"github.com/alec404/go-libs/stringcase"
+)
+
+// 与 entgo/query 相同的过滤操作
+const (
+	FilterNot                   = "not"         // 不等于
+	FilterIn                    = "in"          // 检查值是否在列表中
+	FilterNotIn                 = "not_in"      // 不在列表中
+	FilterGTE                   = "gte"         // 大于或等于传递的值
+	FilterGT                    = "gt"          // 大于传递值
+	FilterLTE                   = "lte"         // 小于或等于传递值
+	FilterLT                    = "lt"          // 小于传递值
+	FilterRange                 = "range"       // 是否介于和给定的两个值之间
+	FilterIsNull                = "isnull"      // 是否为空
+	FilterNotIsNull             = "not_isnull"  // 是否不为空
+	FilterContains              = "contains"    // 是否包含指定的子字符串
+	FilterInsensitiveContains   = "icontains"   // 不区分大小写，是否包含指定的子字符串
+	FilterStartsWith            = "startswith"  // 以值开头
+	FilterInsensitiveStartsWith = "istartswith" // 不区分大小写，以值开头
+	FilterEndsWith              = "endswith"    // 以值结尾
+	FilterInsensitiveEndsWith   = "iendswith"   // 不区分大小写，以值结尾
+	FilterExact                 = "exact"       // 精确匹配
+	FilterInsensitiveExact      = "iexact"      // 不区分大小写，精确匹配
+	FilterRegex                 = "regex"       // 正则表达式
+	FilterInsensitiveRegex      = "iregex"      // 不区分大小写，正则表达式
+	FilterSearch                = "search"      // 全文搜索
+)
+
+var filterOps = map[string]bool{
+	FilterNot: true, FilterIn: true, FilterNotIn: true,
+	FilterGTE: true, FilterGT: true, FilterLTE: true, FilterLT: true, FilterRange: true,
+	FilterIsNull: true, FilterNotIsNull: true,
+	FilterContains: true, FilterInsensitiveContains: true,
+	FilterStartsWith: true, FilterInsensitiveStartsWith: true,
+	FilterEndsWith: true, FilterInsensitiveEndsWith: true,
+	FilterExact: true, FilterInsensitiveExact: true,
+	FilterRegex: true, FilterInsensitiveRegex: true,
+	FilterSearch: true,
+}
+
+var dateParts = map[string]bool{
+	"date": true, "year": true, "iso_year": true, "quarter": true, "month": true,
+	"week": true, "week_day": true, "iso_week_day": true, "day": true, "time": true,
+	"hour": true, "minute": true, "second": true, "microsecond": true,
+}
+
+const (
+	QueryDelimiter     = "__" // 分隔符
+	JsonFieldDelimiter = "."  // JSON字段分隔符
+)
+
+// FilterOption 过滤条件转换的配置项
+type FilterOption func(c *filterConfig)
+
+type filterConfig struct {
+	nestedPaths   map[string]bool
+	datePartField func(field, datePart string) string
+}
+
+// WithNestedPaths 设置 nested 类型的字段路径，这些路径下的字段条件会包装为 nested 查询
+func WithNestedPaths(paths ...string) FilterOption {
+	return func(c *filterConfig) {
+		for _, path := range paths {
+			c.nestedPaths[path] = true
+		}
+	}
+}
+
+// WithDatePartField 设置日期部分对应的字段名，默认为 field.datePart（例如 created_at.year），
+// 需要在写入索引时提取对应的日期部分
+func WithDatePartField(fn func(field, datePart string) string) FilterOption {
+	return func(c *filterConfig) {
+		c.datePartField = fn
+	}
+}
+
+func newFilterConfig(opts []FilterOption) *filterConfig {
+	c := &filterConfig{
+		nestedPaths: make(map[string]bool),
+		datePartField: func(field, datePart string) string {
+			return field + JsonFieldDelimiter + datePart
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ApplyFilter 将 entgo/query 格式的过滤条件（field__op）转换为查询条件。
+// and 条件加入 Filters/MustNotQuery，or 条件合并为一个 should 查询加入 Filters。
+func (q *ESQuery) ApplyFilter(andFilterJsonString, orFilterJsonString string, opts ...FilterOption) error {
+	c := newFilterConfig(opts)
+
+	andFilters, err := parseFilterJson(andFilterJsonString, c)
+	if err != nil {
+		return err
+	}
+	for _, f := range andFilters {
+		if f.negate {
+			q.AddMustNotQuery(f.query)
+		} else {
+			q.AddFilters(f.query)
+		}
+	}
+
+	orFilters, err := parseFilterJson(orFilterJsonString, c)
+	if err != nil {
+		return err
+	}
+	if len(orFilters) > 0 {
+		should := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+		for _, f := range orFilters {
+			should.Should(f.boolQuery())
+		}
+		q.AddFilters(should)
+	}
+
+	return nil
+}
+
+// fieldFilter 单个字段的查询条件，negate 为 true 时表示排除匹配 query 的文档
+type fieldFilter struct {
+	query  elastic.Query
+	negate bool
+}
+
+func (f fieldFilter) boolQuery() elastic.Query {
+	if f.negate {
+		return elastic.NewBoolQuery().MustNot(f.query)
+	}
+	return f.query
+}
+
+// parseFilterJson 解析过滤条件，支持对象或对象数组
+func parseFilterJson(strJson string, c *filterConfig) ([]fieldFilter, error) {
+	if len(strJson) == 0 {
+		return nil, nil
+	}
+
+	queryMap := make(map[string]string)
+	var queryMapArray []map[string]string
+	if err1 := json.Unmarshal([]byte(strJson), &queryMap); err1 != nil {
+		if err2 := json.Unmarshal([]byte(strJson), &queryMapArray); err2 != nil {
+			return nil, err2
+		}
+	}
+
+	var filters []fieldFilter
+	for _, m := range append([]map[string]string{queryMap}, queryMapArray...) {
+		fs, err := processQueryMap(m, c)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, fs...)
+	}
+
+	return filters, nil
+}
+
+// processQueryMap 处理查询映射表，按键排序以保证生成的查询稳定
+func processQueryMap(queryMap map[string]string, c *filterConfig) ([]fieldFilter, error) {
+	keys := make([]string, 0, len(queryMap))
+	for k := range queryMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var filters []fieldFilter
+	for _, k := range keys {
+		f, ok, err := makeFieldFilter(strings.Split(k, QueryDelimiter), queryMap[k], c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", k, err)
+		}
+		if ok {
+			filters = append(filters, f)
+		}
+	}
+
+	return filters, nil
+}
+
+// makeFieldFilter 构建一个字段的查询条件，键的格式与 entgo/query 相同：
+//
+//	field / field.json_key
+//	field__op / field__date_part / field__json_key
+//	field__date_part__op / field__json_key__op
+func makeFieldFilter(keys []string, value string, c *filterConfig) (fieldFilter, bool, error) {
+	if len(keys) == 0 || len(keys[0]) == 0 || len(value) == 0 {
+		return fieldFilter{}, false, nil
+	}
+
+	field := normalizeFieldPath(keys[0])
+	op := ""
+
+	switch len(keys) {
+	case 1:
+
+	case 2:
+		switch {
+		case len(keys[1]) == 0:
+			return fieldFilter{}, false, nil
+		case filterOps[strings.ToLower(keys[1])]:
+			op = strings.ToLower(keys[1])
+		case dateParts[strings.ToLower(keys[1])]:
+			field = c.datePartField(field, strings.ToLower(keys[1]))
+		default:
+			field = field + JsonFieldDelimiter + normalizeFieldPath(keys[1])
+		}
+
+	case 3:
+		if len(keys[1]) == 0 || !filterOps[strings.ToLower(keys[2])] {
+			return fieldFilter{}, false, nil
+		}
+		if dateParts[strings.ToLower(keys[1])] {
+			field = c.datePartField(field, strings.ToLower(keys[1]))
+		} else {
+			field = field + JsonFieldDelimiter + normalizeFieldPath(keys[1])
+		}
+		op = strings.ToLower(keys[2])
+
+	default:
+		return fieldFilter{}, false, nil
+	}
+
+	f, err := processOp(op, field, value)
+	if err != nil {
+		return fieldFilter{}, false, err
+	}
+
+	if path := c.nestedPath(field); path != "" {
+		f.query = elastic.NewNestedQuery(path, f.query)
+	}
+
+	return f, true, nil
+}
+
+// nestedPath 返回字段所属的最长 nested 路径
+func (c *filterConfig) nestedPath(field string) string {
+	for i := strings.LastIndex(field, JsonFieldDelimiter); i > 0; i = strings.LastIndex(field[:i], JsonFieldDelimiter) {
+		if c.nestedPaths[field[:i]] {
+			return field[:i]
+		}
+	}
+	return ""
+}
+
+// processOp 将操作转换为 Elasticsearch 查询
+func processOp(op, field, value string) (fieldFilter, error) {
+	switch op {
+	case "", FilterExact:
+		return fieldFilter{query: elastic.NewTermQuery(field, value)}, nil
+	case FilterInsensitiveExact:
+		return fieldFilter{query: elastic.NewTermQuery(field, strings.ToLower(value))}, nil
+	case FilterNot:
+		return fieldFilter{query: elastic.NewTermQuery(field, value), negate: true}, nil
+
+	case FilterIn, FilterNotIn:
+		var values []interface{}
+		if err := json.Unmarshal([]byte(value), &values); err != nil {
+			return fieldFilter{}, err
+		}
+		return fieldFilter{query: elastic.NewTermsQuery(field, values...), negate: op == FilterNotIn}, nil
+
+	case FilterGTE:
+		return fieldFilter{query: elastic.NewRangeQuery(field).Gte(value)}, nil
+	case FilterGT:
+		return fieldFilter{query: elastic.NewRangeQuery(field).Gt(value)}, nil
+	case FilterLTE:
+		return fieldFilter{query: elastic.NewRangeQuery(field).Lte(value)}, nil
+	case FilterLT:
+		return fieldFilter{query: elastic.NewRangeQuery(field).Lt(value)}, nil
+	case FilterRange:
+		var values []interface{}
+		if err := json.Unmarshal([]byte(value), &values); err != nil {
+			return fieldFilter{}, err
+		}
+		if len(values) != 2 {
+			return fieldFilter{}, fmt.Errorf("range requires 2 values, got %d", len(values))
+		}
+		return fieldFilter{query: elastic.NewRangeQuery(field).Gte(values[0]).Lte(values[1])}, nil
+
+	case FilterIsNull:
+		return fieldFilter{query: elastic.NewExistsQuery(field), negate: true}, nil
+	case FilterNotIsNull:
+		return fieldFilter{query: elastic.NewExistsQuery(field)}, nil
+
+	// 不区分大小写的匹配要求字段使用 lowercase normalizer，查询值同样转为小写
+	case FilterContains:
+		return fieldFilter{query: elastic.NewWildcardQuery(field, "*"+escapeWildcard(value)+"*")}, nil
+	case FilterInsensitiveContains:
+		return fieldFilter{query: elastic.NewWildcardQuery(field, "*"+escapeWildcard(strings.ToLower(value))+"*")}, nil
+	case FilterStartsWith:
+		return fieldFilter{query: elastic.NewPrefixQuery(field, value)}, nil
+	case FilterInsensitiveStartsWith:
+		return fieldFilter{query: elastic.NewPrefixQuery(field, strings.ToLower(value))}, nil
+	case FilterEndsWith:
+		return fieldFilter{query: elastic.NewWildcardQuery(field, "*"+escapeWildcard(value))}, nil
+	case FilterInsensitiveEndsWith:
+		return fieldFilter{query: elastic.NewWildcardQuery(field, "*"+escapeWildcard(strings.ToLower(value)))}, nil
+	case FilterRegex:
+		return fieldFilter{query: elastic.NewRegexpQuery(field, value)}, nil
+	case FilterInsensitiveRegex:
+		return fieldFilter{query: elastic.NewRegexpQuery(field, strings.ToLower(value))}, nil
+
+	case FilterSearch:
+		return fieldFilter{query: elastic.NewMatchQuery(field, value)}, nil
+
+	default:
+		return fieldFilter{}, fmt.Errorf("unsupported filter operation %q", op)
+	}
+}
+
+// escapeWildcard 转义通配符查询中的特殊字符
+func escapeWildcard(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`).Replace(value)
+}
+
+// normalizeFieldPath 将字段路径的每一段转换为 snake_case
+func normalizeFieldPath(path string) string {
+	parts := strings.Split(path, JsonFieldDelimiter)
+	for i, part := range parts {
+		parts[i] = stringcase.ToSnakeCase(part)
+	}
+	return strings.Join(parts, JsonFieldDelimiter)
+}
@@ -0,0 +1,193 @@
+package esquery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func querySource(t *testing.T, q elastic.Query) string {
+	t.Helper()
+	src, err := q.Source()
+	require.NoError(t, err)
+	b, err := json.Marshal(src)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestApplyFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		filters []string
+		mustNot []string
+	}{
+		{
+			name:    "equal",
+			filter:  `{"userName":"tom"}`,
+			filters: []string{`{"term":{"user_name":"tom"}}`},
+		},
+		{
+			name:    "not",
+			filter:  `{"status__not":"deleted"}`,
+			mustNot: []string{`{"term":{"status":"deleted"}}`},
+		},
+		{
+			name:    "in",
+			filter:  `{"id__in":"[1,2,3]"}`,
+			filters: []string{`{"terms":{"id":[1,2,3]}}`},
+		},
+		{
+			name:    "not_in",
+			filter:  `{"id__not_in":"[\"a\"]"}`,
+			mustNot: []string{`{"terms":{"id":["a"]}}`},
+		},
+		{
+			name:    "gte",
+			filter:  `{"age__gte":"18"}`,
+			filters: []string{`{"range":{"age":{"from":"18","include_lower":true,"include_upper":true,"to":null}}}`},
+		},
+		{
+			name:    "range",
+			filter:  `{"created_at__range":"[\"2024-01-01\",\"2024-12-31\"]"}`,
+			filters: []string{`{"range":{"created_at":{"from":"2024-01-01","include_lower":true,"include_upper":true,"to":"2024-12-31"}}}`},
+		},
+		{
+			name:    "isnull",
+			filter:  `{"remark__isnull":"true","email__not_isnull":"true"}`,
+			filters: []string{`{"exists":{"field":"email"}}`},
+			mustNot: []string{`{"exists":{"field":"remark"}}`},
+		},
+		{
+			name:    "contains",
+			filter:  `{"name__contains":"a*b","title__icontains":"Go"}`,
+			filters: []string{`{"wildcard":{"name":{"wildcard":"*a\\*b*"}}}`, `{"wildcard":{"title":{"wildcard":"*go*"}}}`},
+		},
+		{
+			name:    "startswith",
+			filter:  `{"name__startswith":"Al"}`,
+			filters: []string{`{"prefix":{"name":"Al"}}`},
+		},
+		{
+			name:    "regex",
+			filter:  `{"code__regex":"A[0-9]+"}`,
+			filters: []string{`{"regexp":{"code":{"value":"A[0-9]+"}}}`},
+		},
+		{
+			name:    "search",
+			filter:  `{"content__search":"hello world"}`,
+			filters: []string{`{"match":{"content":{"query":"hello world"}}}`},
+		},
+		{
+			name:    "date part",
+			filter:  `{"created_at__year__gte":"2024","updated_at__month":"5"}`,
+			filters: []string{`{"range":{"created_at.year":{"from":"2024","include_lower":true,"include_upper":true,"to":null}}}`, `{"term":{"updated_at.month":"5"}}`},
+		},
+		{
+			name:    "json field",
+			filter:  `{"preferences.dailyEmail":"true","preferences__theme__in":"[\"dark\"]"}`,
+			filters: []string{`{"term":{"preferences.daily_email":"true"}}`, `{"terms":{"preferences.theme":["dark"]}}`},
+		},
+		{
+			name:    "array",
+			filter:  `[{"a":"1"},{"b__gt":"2"}]`,
+			filters: []string{`{"term":{"a":"1"}}`, `{"range":{"b":{"from":"2","include_lower":false,"include_upper":true,"to":null}}}`},
+		},
+		{
+			name:   "empty value",
+			filter: `{"a":""}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewESQuery(nil)
+			require.NoError(t, q.ApplyFilter(tt.filter, ""))
+
+			var filters, mustNot []string
+			for _, f := range q.Filters {
+				filters = append(filters, querySource(t, f))
+			}
+			for _, f := range q.MustNotQuery {
+				mustNot = append(mustNot, querySource(t, f))
+			}
+			assert.Equal(t, tt.filters, filters)
+			assert.Equal(t, tt.mustNot, mustNot)
+		})
+	}
+}
+
+func TestApplyFilterOr(t *testing.T) {
+	q := NewESQuery(nil)
+	require.NoError(t, q.ApplyFilter("", `{"name":"a","status__not":"b"}`))
+
+	require.Len(t, q.Filters, 1)
+	assert.Equal(t,
+		`{"bool":{"minimum_should_match":"1","should":[{"term":{"name":"a"}},{"bool":{"must_not":{"term":{"status":"b"}}}}]}}`,
+		querySource(t, q.Filters[0]),
+	)
+}
+
+func TestApplyFilterNested(t *testing.T) {
+	q := NewESQuery(nil)
+	require.NoError(t, q.ApplyFilter(`{"items.sku__in":"[\"x\"]","tags":"a"}`, "", WithNestedPaths("items")))
+
+	require.Len(t, q.Filters, 2)
+	assert.Equal(t, `{"nested":{"path":"items","query":{"terms":{"items.sku":["x"]}}}}`, querySource(t, q.Filters[0]))
+	assert.Equal(t, `{"term":{"tags":"a"}}`, querySource(t, q.Filters[1]))
+}
+
+func TestApplyFilterDatePartField(t *testing.T) {
+	q := NewESQuery(nil)
+	require.NoError(t, q.ApplyFilter(`{"created_at__year":"2024"}`, "", WithDatePartField(func(field, datePart string) string {
+		return field + "_" + datePart
+	})))
+
+	require.Len(t, q.Filters, 1)
+	assert.Equal(t, `{"term":{"created_at_year":"2024"}}`, querySource(t, q.Filters[0]))
+}
+
+func TestApplyFilterError(t *testing.T) {
+	q := NewESQuery(nil)
+	assert.Error(t, q.ApplyFilter(`not json`, ""))
+	assert.Error(t, q.ApplyFilter(`{"a__in":"1"}`, ""))
+	assert.Error(t, q.ApplyFilter(`{"a__range":"[1]"}`, ""))
+}
+
+func TestBuildESQuery(t *testing.T) {
+	q, err := BuildESQuery(
+		[]string{"users"},
+		`{"age__gte":"18"}`, "",
+		3, 20, false,
+		[]string{"-createdAt", "name", "-"}, "id",
+		[]string{"_id", "userName"},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"users"}, q.Indices)
+	assert.Len(t, q.Filters, 1)
+	assert.Equal(t, int32(40), q.From)
+	assert.Equal(t, int32(20), q.Size)
+	assert.Equal(t, []string{"id", "user_name"}, q.Include)
+
+	require.Len(t, q.Sorters, 2)
+	src, _ := q.Sorters[0].Source()
+	assert.Equal(t, map[string]interface{}{"createdAt": map[string]interface{}{"order": "desc"}}, src)
+
+	q, err = BuildESQuery(nil, "", "", 0, 0, true, nil, "id", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), q.From)
+	assert.Equal(t, int32(MaxResultWindow), q.Size)
+	require.Len(t, q.Sorters, 1)
+	src, _ = q.Sorters[0].Source()
+	assert.Equal(t, map[string]interface{}{"id": map[string]interface{}{"order": "desc"}}, src)
+
+	q, err = BuildESQuery(nil, "", "", 0, 0, false, nil, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(0), q.From)
+	assert.Equal(t, int32(10), q.Size)
+	assert.Empty(t, q.Sorters)
+}
@@ -2,12 +2,19 @@ module github.com/alec404/go-libs/esquery
 
 go 1.25.4
 
-require github.com/olivere/elastic v6.2.37+incompatible
+require (
+	github.com/alec404/go-libs v0.0.1
+	github.com/olivere/elastic v6.2.37+incompatible
+	github.com/stretchr/testify v1.11.1
+)
 
 require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fortytw2/leaktest v1.3.0 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.9.1 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
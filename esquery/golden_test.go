@@ -0,0 +1,82 @@
+package esquery_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alec404/go-libs/esquery"
+	"github.com/alec404/go-libs/esquery/estest"
+)
+
+func TestQueryGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(t *testing.T) *esquery.ESQuery
+	}{
+		{
+			name: "filter",
+			build: func(t *testing.T) *esquery.ESQuery {
+				q, err := esquery.BuildESQuery([]string{"users"},
+					`{"status":"active","age__gte":"18","name__icontains":"Tom","deleted_at__isnull":"true"}`,
+					`{"role":"admin","role__in":"[\"owner\"]"}`,
+					2, 20, false, []string{"-created_at", "id"}, "", []string{"id", "name"})
+				require.NoError(t, err)
+				return q
+			},
+		},
+		{
+			name: "scoring",
+			build: func(t *testing.T) *esquery.ESQuery {
+				q := esquery.NewESQuery([]string{"articles"})
+				q.AddMultiMatch("elastic search", esquery.FieldBoost{Field: "title", Boost: 3}, esquery.FieldBoost{Field: "content"})
+				q.AddRecencyDecay("published_at", "30d", "7d", 0.5)
+				q.AddHighlight("title", "content")
+				q.MinimumShouldMatch = "1"
+				return q
+			},
+		},
+		{
+			name: "aggregations",
+			build: func(t *testing.T) *esquery.ESQuery {
+				q := esquery.NewESQuery([]string{"orders"})
+				q.Size = 0
+				q.AddFilters(elastic.NewTermQuery("paid", true))
+				q.AddAggregation(
+					esquery.TermsAgg("by_status", "status", 10, esquery.SumAgg("amount", "amount")),
+					esquery.DateHistogramAgg("by_day", "created_at", "1d", "+08:00", esquery.CardinalityAgg("buyers", "buyer_id")),
+				)
+				return q
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estest.AssertQueryGolden(t, "testdata/"+tt.name+".golden.json", tt.build(t))
+		})
+	}
+}
+
+func TestSearchRequestGolden(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond(http.MethodPost, "/users/_search", http.StatusOK, `{"hits":{"total":1,"hits":[{"_id":"1","_source":{"name":"tom"}}]}}`)
+
+	q, err := esquery.BuildESQuery([]string{"users"},
+		`{"status":"active","age__gte":"18","name__icontains":"Tom","deleted_at__isnull":"true"}`,
+		`{"role":"admin","role__in":"[\"owner\"]"}`,
+		2, 20, false, []string{"-created_at", "id"}, "", []string{"id", "name"})
+	require.NoError(t, err)
+
+	page, err := esquery.Search[map[string]string](context.Background(), srv.Client(), q)
+	require.NoError(t, err)
+	assert.Equal(t, "tom", page.Hits[0].Source["name"])
+
+	// 实际发送的请求体与 RenderJSON 一致
+	require.Len(t, srv.Requests(), 1)
+	estest.AssertGoldenJSON(t, "testdata/filter.golden.json", srv.LastRequest().Body)
+}
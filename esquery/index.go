@@ -0,0 +1,418 @@
+package esquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/olivere/elastic"
+)
+
+var ErrIndexSpecInvalid = errors.New("index spec requires an alias")
+
+// IndexSettings 索引设置
+type IndexSettings struct {
+	NumberOfShards   int                    // 分片数，0 时使用 ES 默认值
+	NumberOfReplicas *int                   // 副本数，nil 时使用 ES 默认值
+	RefreshInterval  string                 // 刷新间隔，例如 "1s"、"-1"
+	MaxResultWindow  int                    // from + size 的上限，0 时使用 ES 默认值
+	Analysis         map[string]interface{} // 自定义分析器
+}
+
+// Map 转换为 settings 请求体
+func (s IndexSettings) Map() map[string]interface{} {
+	index := make(map[string]interface{})
+	if s.NumberOfShards > 0 {
+		index["number_of_shards"] = s.NumberOfShards
+	}
+	if s.NumberOfReplicas != nil {
+		index["number_of_replicas"] = *s.NumberOfReplicas
+	}
+	if s.RefreshInterval != "" {
+		index["refresh_interval"] = s.RefreshInterval
+	}
+	if s.MaxResultWindow > 0 {
+		index["max_result_window"] = s.MaxResultWindow
+	}
+
+	settings := map[string]interface{}{}
+	if len(index) > 0 {
+		settings["index"] = index
+	}
+	if len(s.Analysis) > 0 {
+		settings["analysis"] = s.Analysis
+	}
+	return settings
+}
+
+// IndexSpec 声明的索引。应用通过别名 Alias 读写，实际的索引为带版本号的 alias_v1、alias_v2...，
+// 修改映射后通过 MigrateIndex 创建新版本索引、重建数据并切换别名，读写不中断。
+type IndexSpec struct {
+	Alias    string
+	DocType  string // ES 6 的文档类型，例如 "_doc"，ES 7+ 留空
+	Settings IndexSettings
+	Mappings map[string]interface{} // {"properties": {...}}
+}
+
+// NewIndexSpec 由结构体声明索引，映射参见 MappingFromStruct
+func NewIndexSpec(alias string, doc interface{}, settings IndexSettings) (*IndexSpec, error) {
+	mappings, err := MappingFromStruct(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &IndexSpec{Alias: alias, Settings: settings, Mappings: mappings}, nil
+}
+
+// VersionedIndex 返回指定版本的索引名
+func (s *IndexSpec) VersionedIndex(version int) string {
+	return fmt.Sprintf("%s_v%d", s.Alias, version)
+}
+
+// Body 返回创建索引的请求体
+func (s *IndexSpec) Body() map[string]interface{} {
+	return map[string]interface{}{
+		"settings": s.Settings.Map(),
+		"mappings": s.mappingsBody(),
+	}
+}
+
+func (s *IndexSpec) mappingsBody() map[string]interface{} {
+	if s.DocType != "" {
+		return map[string]interface{}{s.DocType: s.Mappings}
+	}
+	return s.Mappings
+}
+
+func (s *IndexSpec) validate() error {
+	if s == nil || s.Alias == "" {
+		return ErrIndexSpecInvalid
+	}
+	return nil
+}
+
+// PutIndexTemplate 创建或更新索引模板，模板匹配该别名的所有版本索引（alias_v*），
+// 手动或由其他工具创建的版本索引也会使用声明的设置和映射。
+func PutIndexTemplate(ctx context.Context, client *elastic.Client, spec *IndexSpec) error {
+	if err := spec.validate(); err != nil {
+		return err
+	}
+
+	body := spec.Body()
+	body["index_patterns"] = []string{spec.Alias + "_v*"}
+
+	_, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "PUT",
+		Path:   "/_template/" + url.PathEscape(spec.Alias),
+		Body:   body,
+	})
+	return err
+}
+
+// EnsureIndex 别名不存在时创建第一个版本的索引并指向别名，返回别名当前指向的索引。
+// 已存在与别名同名的索引（例如之前直接按名称创建）时，将其数据复制到第一个版本，切换别名时删除该索引。
+func EnsureIndex(ctx context.Context, client *elastic.Client, spec *IndexSpec) (string, error) {
+	if err := spec.validate(); err != nil {
+		return "", err
+	}
+
+	versions, err := indexVersions(ctx, client, spec.Alias)
+	if err != nil {
+		return "", err
+	}
+	if current := versions.current(); current != "" {
+		return current, nil
+	}
+
+	ret, err := migrateIndex(ctx, client, spec, versions, false, &migrateOptions{})
+	if err != nil {
+		return "", err
+	}
+	return ret.NewIndex, nil
+}
+
+const migrateCatchUpSkew = time.Minute // 按更新时间追平时向前多复制的时长，容忍应用与 ES 之间的时钟误差
+
+// MigrateOption MigrateIndex 的配置项
+type MigrateOption func(o *migrateOptions)
+
+type migrateOptions struct {
+	catchUpField string
+}
+
+// WithCatchUpField 设置文档的更新时间字段（date 类型，写入时由应用更新），
+// 追平时复制该字段在迁移开始之后的文档，新增和修改都会同步到新索引
+func WithCatchUpField(field string) MigrateOption {
+	return func(o *migrateOptions) {
+		o.catchUpField = field
+	}
+}
+
+// MigrateResult 索引迁移的结果
+type MigrateResult struct {
+	OldIndices []string // 迁移前别名指向的索引，迁移后不会删除，确认无误后可以调用 DeleteIndices 删除
+	NewIndex   string
+}
+
+// MigrateIndex 按声明创建新版本的索引，reindex 为 true 时从别名当前指向的索引复制数据，
+// 复制完成后再追平一次复制期间的写入，最后原子地将别名切换到新索引。别名不存在时创建第一个版本，
+// 存在与别名同名的索引时总是复制其数据，并在切换别名的同一请求中删除该索引。
+//
+// 追平的范围：
+//   - 设置了 WithCatchUpField 时，复制更新时间在迁移开始之后的文档，包括新增和修改；
+//   - 未设置时只复制新索引中还不存在的文档，复制期间的修改不会同步。
+//
+// 两种方式都不会同步删除，追平之后、切换别名之前的写入也会丢失。
+// 需要严格一致时，调用方必须在迁移期间暂停写入，或者同时写入新旧两个索引。
+func MigrateIndex(ctx context.Context, client *elastic.Client, spec *IndexSpec, reindex bool, opts ...MigrateOption) (*MigrateResult, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	o := &migrateOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	versions, err := indexVersions(ctx, client, spec.Alias)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrateIndex(ctx, client, spec, versions, reindex, o)
+}
+
+func migrateIndex(ctx context.Context, client *elastic.Client, spec *IndexSpec, versions *aliasVersions, reindex bool, o *migrateOptions) (*MigrateResult, error) {
+	ret := &MigrateResult{
+		OldIndices: versions.aliased,
+		NewIndex:   spec.VersionedIndex(versions.latest + 1),
+	}
+
+	_, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "PUT",
+		Path:   "/" + url.PathEscape(ret.NewIndex),
+		Body:   spec.Body(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create index %s failed: %w", ret.NewIndex, err)
+	}
+
+	var source []string
+	if reindex {
+		source = append(source, ret.OldIndices...)
+	}
+	if versions.concrete != "" {
+		source = append(source, versions.concrete)
+	}
+
+	if len(source) > 0 {
+		startedAt := time.Now()
+		if err = reindexInto(ctx, client, source, ret.NewIndex, nil, ""); err != nil {
+			return nil, fmt.Errorf("reindex into %s failed: %w", ret.NewIndex, err)
+		}
+
+		// 追平复制期间写入旧索引的文档
+		if o.catchUpField != "" {
+			query := map[string]interface{}{
+				"range": map[string]interface{}{
+					o.catchUpField: map[string]interface{}{
+						"gte":    startedAt.Add(-migrateCatchUpSkew).UnixMilli(),
+						"format": "epoch_millis",
+					},
+				},
+			}
+			err = reindexInto(ctx, client, source, ret.NewIndex, query, "")
+		} else {
+			err = reindexInto(ctx, client, source, ret.NewIndex, nil, "create")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("catch up %s failed: %w", ret.NewIndex, err)
+		}
+	}
+
+	var actions []interface{}
+	for _, index := range ret.OldIndices {
+		actions = append(actions, map[string]interface{}{"remove": map[string]interface{}{"index": index, "alias": spec.Alias}})
+	}
+	// 同名的索引必须在添加别名的同一请求中删除，否则别名与索引重名
+	if versions.concrete != "" {
+		actions = append(actions, map[string]interface{}{"remove_index": map[string]interface{}{"index": versions.concrete}})
+	}
+	actions = append(actions, map[string]interface{}{"add": map[string]interface{}{"index": ret.NewIndex, "alias": spec.Alias}})
+
+	_, err = client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "POST",
+		Path:   "/_aliases",
+		Body:   map[string]interface{}{"actions": actions},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("switch alias %s to %s failed: %w", spec.Alias, ret.NewIndex, err)
+	}
+
+	return ret, nil
+}
+
+// reindexInto 将 source 中匹配 query 的文档复制到 dest，opType 为 create 时跳过 dest 中已存在的文档
+func reindexInto(ctx context.Context, client *elastic.Client, source []string, dest string, query map[string]interface{}, opType string) error {
+	src := map[string]interface{}{"index": source}
+	if query != nil {
+		src["query"] = query
+	}
+	dst := map[string]interface{}{"index": dest}
+	body := map[string]interface{}{"source": src, "dest": dst}
+	if opType != "" {
+		dst["op_type"] = opType
+		body["conflicts"] = "proceed"
+	}
+
+	_, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "POST",
+		Path:   "/_reindex",
+		Params: url.Values{"wait_for_completion": []string{"true"}, "refresh": []string{"true"}},
+		Body:   body,
+	})
+	return err
+}
+
+// DeleteIndices 删除索引，不存在的索引忽略
+func DeleteIndices(ctx context.Context, client *elastic.Client, indices ...string) error {
+	for _, index := range indices {
+		_, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+			Method:       "DELETE",
+			Path:         "/" + url.PathEscape(index),
+			IgnoreErrors: []int{http.StatusNotFound},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateMapping 将声明的映射更新到别名指向的索引，只能新增字段，修改已有字段的类型需要 MigrateIndex
+func UpdateMapping(ctx context.Context, client *elastic.Client, spec *IndexSpec) error {
+	if err := spec.validate(); err != nil {
+		return err
+	}
+
+	path := "/" + url.PathEscape(spec.Alias) + "/_mapping"
+	if spec.DocType != "" {
+		path += "/" + url.PathEscape(spec.DocType)
+	}
+
+	_, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "PUT",
+		Path:   path,
+		Body:   spec.Mappings,
+	})
+	return err
+}
+
+// DetectMappingDrift 比较别名指向的索引的线上映射与声明的映射，参见 CompareMappings
+func DetectMappingDrift(ctx context.Context, client *elastic.Client, spec *IndexSpec) ([]MappingDrift, error) {
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+
+	res, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "GET",
+		Path:   "/" + url.PathEscape(spec.Alias) + "/_mapping",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ret map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err = json.Unmarshal(res.Body, &ret); err != nil {
+		return nil, err
+	}
+
+	indices := make([]string, 0, len(ret))
+	for index := range ret {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	var drifts []MappingDrift
+	for _, index := range indices {
+		live := ret[index].Mappings
+		// ES 6 的映射按文档类型分组：{"_doc": {"properties": ...}}
+		if typed, ok := live[spec.DocType].(map[string]interface{}); ok && spec.DocType != "" {
+			live = typed
+		}
+		drifts = append(drifts, CompareMappings(spec.Mappings, live)...)
+	}
+
+	return drifts, nil
+}
+
+var versionedIndexRe = regexp.MustCompile(`_v(\d+)$`)
+
+// aliasVersions 别名的版本索引
+type aliasVersions struct {
+	latest   int      // 已存在的最大版本号
+	aliased  []string // 别名当前指向的索引
+	concrete string   // 与别名同名的索引，不存在时为空
+}
+
+func (v *aliasVersions) current() string {
+	if len(v.aliased) == 0 {
+		return ""
+	}
+	return v.aliased[len(v.aliased)-1]
+}
+
+// indexVersions 查询别名的所有版本索引、别名当前指向的索引以及与别名同名的索引
+func indexVersions(ctx context.Context, client *elastic.Client, alias string) (*aliasVersions, error) {
+	res, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "GET",
+		Path:   "/" + url.PathEscape(alias) + "_v*," + url.PathEscape(alias) + "/_alias",
+		Params: url.Values{"ignore_unavailable": []string{"true"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ret map[string]struct {
+		Aliases map[string]interface{} `json:"aliases"`
+	}
+	if err = json.Unmarshal(res.Body, &ret); err != nil {
+		return nil, err
+	}
+
+	versions := &aliasVersions{}
+	aliased := make(map[string]int)
+	for index, v := range ret {
+		// 别名存在时返回的是它指向的索引，只有同名的索引才会以别名本身为键
+		if index == alias {
+			versions.concrete = index
+			continue
+		}
+		m := versionedIndexRe.FindStringSubmatch(index)
+		if m == nil || index != alias+m[0] {
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		if n > versions.latest {
+			versions.latest = n
+		}
+		if _, ok := v.Aliases[alias]; ok {
+			aliased[index] = n
+			versions.aliased = append(versions.aliased, index)
+		}
+	}
+	// 按版本号排序，最后一个为最新版本
+	sort.Slice(versions.aliased, func(i, j int) bool {
+		return aliased[versions.aliased[i]] < aliased[versions.aliased[j]]
+	})
+
+	return versions, nil
+}
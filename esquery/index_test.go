@@ -0,0 +1,149 @@
+package esquery_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alec404/go-libs/esquery"
+	"github.com/alec404/go-libs/esquery/estest"
+)
+
+func TestMigrateIndex(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("GET", "/users_v*/_alias", http.StatusOK, `{"users_v1":{"aliases":{}},"users_v2":{"aliases":{"users":{}}},"users_v10":{"aliases":{}},"users_vx":{"aliases":{}}}`)
+	srv.Respond("", "/*", http.StatusOK, `{"acknowledged":true}`)
+
+	replicas := 0
+	spec, err := esquery.NewIndexSpec("users", &testUser{}, esquery.IndexSettings{NumberOfShards: 1, NumberOfReplicas: &replicas})
+	require.NoError(t, err)
+
+	ret, err := esquery.MigrateIndex(context.Background(), srv.Client(), spec, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users_v2"}, ret.OldIndices)
+	assert.Equal(t, "users_v11", ret.NewIndex)
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 5)
+	assert.Equal(t, "/users_v*,users/_alias", reqs[0].Path)
+	assert.Equal(t, "true", reqs[0].Query.Get("ignore_unavailable"))
+
+	assert.Equal(t, "PUT", reqs[1].Method)
+	assert.Equal(t, "/users_v11", reqs[1].Path)
+	assert.JSONEq(t, `{"settings":{"index":{"number_of_shards":1,"number_of_replicas":0}},"mappings":{"properties":{"name":{"type":"keyword"},"age":{"type":"long"}}}}`, string(reqs[1].Body))
+
+	assert.Equal(t, "/_reindex", reqs[2].Path)
+	assert.Equal(t, "true", reqs[2].Query.Get("wait_for_completion"))
+	assert.JSONEq(t, `{"source":{"index":["users_v2"]},"dest":{"index":"users_v11"}}`, string(reqs[2].Body))
+
+	// 追平复制期间新增的文档
+	assert.Equal(t, "/_reindex", reqs[3].Path)
+	assert.JSONEq(t, `{"source":{"index":["users_v2"]},"dest":{"index":"users_v11","op_type":"create"},"conflicts":"proceed"}`, string(reqs[3].Body))
+
+	assert.Equal(t, "/_aliases", reqs[4].Path)
+	assert.JSONEq(t, `{"actions":[{"remove":{"index":"users_v2","alias":"users"}},{"add":{"index":"users_v11","alias":"users"}}]}`, string(reqs[4].Body))
+}
+
+func TestMigrateIndexCatchUpField(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("GET", "/users_v*/_alias", http.StatusOK, `{"users_v1":{"aliases":{"users":{}}}}`)
+	srv.Respond("", "/*", http.StatusOK, `{"acknowledged":true}`)
+
+	startedAt := time.Now()
+	spec := &esquery.IndexSpec{Alias: "users", Mappings: map[string]interface{}{"properties": map[string]interface{}{}}}
+	ret, err := esquery.MigrateIndex(context.Background(), srv.Client(), spec, true, esquery.WithCatchUpField("updated_at"))
+	require.NoError(t, err)
+	assert.Equal(t, "users_v2", ret.NewIndex)
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 5)
+
+	catchUp := reqs[3]
+	assert.Equal(t, "/_reindex", catchUp.Path)
+	body := decodeBody(t, catchUp)
+	assert.Equal(t, map[string]interface{}{"index": "users_v2"}, body["dest"])
+	assert.NotContains(t, body, "conflicts")
+
+	rng := body["source"].(map[string]interface{})["query"].(map[string]interface{})["range"].(map[string]interface{})["updated_at"].(map[string]interface{})
+	assert.Equal(t, "epoch_millis", rng["format"])
+	gte := int64(rng["gte"].(float64))
+	assert.LessOrEqual(t, gte, startedAt.Add(-esquery.MigrateCatchUpSkew+time.Second).UnixMilli())
+	assert.GreaterOrEqual(t, gte, startedAt.Add(-esquery.MigrateCatchUpSkew-time.Second).UnixMilli())
+}
+
+func TestEnsureIndex(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("GET", "/users_v*/_alias", http.StatusOK, `{}`)
+	srv.Respond("", "/*", http.StatusOK, `{"acknowledged":true}`)
+
+	spec := &esquery.IndexSpec{Alias: "users", DocType: "_doc", Mappings: map[string]interface{}{"properties": map[string]interface{}{}}}
+	index, err := esquery.EnsureIndex(context.Background(), srv.Client(), spec)
+	require.NoError(t, err)
+	assert.Equal(t, "users_v1", index)
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 3) // 查询、创建、添加别名，没有数据需要复制
+	assert.JSONEq(t, `{"settings":{},"mappings":{"_doc":{"properties":{}}}}`, string(reqs[1].Body))
+	assert.JSONEq(t, `{"actions":[{"add":{"index":"users_v1","alias":"users"}}]}`, string(reqs[2].Body))
+
+	_, err = esquery.EnsureIndex(context.Background(), srv.Client(), &esquery.IndexSpec{})
+	assert.ErrorIs(t, err, esquery.ErrIndexSpecInvalid)
+}
+
+func TestEnsureIndexConcreteIndex(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("GET", "/users_v*/_alias", http.StatusOK, `{"users":{"aliases":{}}}`)
+	srv.Respond("", "/*", http.StatusOK, `{"acknowledged":true}`)
+
+	spec := &esquery.IndexSpec{Alias: "users", Mappings: map[string]interface{}{"properties": map[string]interface{}{}}}
+	index, err := esquery.EnsureIndex(context.Background(), srv.Client(), spec)
+	require.NoError(t, err)
+	assert.Equal(t, "users_v1", index)
+
+	reqs := srv.Requests()
+	require.Len(t, reqs, 5) // 查询、创建、复制、追平、切换别名
+
+	assert.Equal(t, "PUT", reqs[1].Method)
+	assert.Equal(t, "/users_v1", reqs[1].Path)
+
+	// 同名索引中的数据复制到第一个版本
+	assert.Equal(t, "/_reindex", reqs[2].Path)
+	assert.JSONEq(t, `{"source":{"index":["users"]},"dest":{"index":"users_v1"}}`, string(reqs[2].Body))
+	assert.Equal(t, "/_reindex", reqs[3].Path)
+	assert.JSONEq(t, `{"source":{"index":["users"]},"dest":{"index":"users_v1","op_type":"create"},"conflicts":"proceed"}`, string(reqs[3].Body))
+
+	// 在同一请求中删除同名索引并添加别名
+	assert.Equal(t, "/_aliases", reqs[4].Path)
+	assert.JSONEq(t, `{"actions":[{"remove_index":{"index":"users"}},{"add":{"index":"users_v1","alias":"users"}}]}`, string(reqs[4].Body))
+}
+
+func TestPutIndexTemplate(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("PUT", "/_template/users", http.StatusOK, `{"acknowledged":true}`)
+
+	spec := &esquery.IndexSpec{Alias: "users", Settings: esquery.IndexSettings{RefreshInterval: "5s"}, Mappings: map[string]interface{}{"properties": map[string]interface{}{}}}
+	require.NoError(t, esquery.PutIndexTemplate(context.Background(), srv.Client(), spec))
+
+	r := srv.LastRequest()
+	assert.Equal(t, "PUT", r.Method)
+	assert.Equal(t, "/_template/users", r.Path)
+	assert.JSONEq(t, `{"index_patterns":["users_v*"],"settings":{"index":{"refresh_interval":"5s"}},"mappings":{"properties":{}}}`, string(r.Body))
+}
+
+func TestDetectMappingDrift(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("GET", "/users/_mapping", http.StatusOK, `{"users_v1":{"mappings":{"_doc":{"properties":{"name":{"type":"text"},"age":{"type":"long"}}}}}}`)
+
+	spec, err := esquery.NewIndexSpec("users", testUser{}, esquery.IndexSettings{})
+	require.NoError(t, err)
+	spec.DocType = "_doc"
+
+	drifts, err := esquery.DetectMappingDrift(context.Background(), srv.Client(), spec)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, esquery.MappingDrift{Path: "name", Kind: esquery.DriftChanged, Attribute: "type", Declared: "keyword", Live: "text"}, drifts[0])
+}
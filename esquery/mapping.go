@@ -0,0 +1,339 @@
+package esquery
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const MappingTagName = "es" // 映射使用的结构体标签
+
+// MappingFromStruct 由结构体生成索引映射 {"properties": {...}}，字段名与 JSON 编码一致（使用 json 标签）。
+// 字段类型默认按 Go 类型推导，可以通过 es 标签设置：
+//
+//	type Article struct {
+//		ID      int64     `json:"id"`
+//		Title   string    `json:"title" es:"type=text,analyzer=ik_max_word,search_analyzer=ik_smart,keyword"`
+//		Tags    []string  `json:"tags"`
+//		Items   []Item    `json:"items" es:"type=nested"`
+//		Secret  string    `json:"secret" es:"-"`
+//		Created time.Time `json:"created" es:"format=epoch_millis"`
+//	}
+//
+// 标签选项：type、analyzer、search_analyzer、format、ignore_above、copy_to、index=false，
+// keyword 为 text 字段添加 keyword 子字段，"-" 表示不生成映射。
+func MappingFromStruct(doc interface{}) (map[string]interface{}, error) {
+	t := reflect.TypeOf(doc)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("mapping requires a struct, got %T", doc)
+	}
+
+	properties, err := structProperties(t, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{"properties": properties}, nil
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func structProperties(t reflect.Type, visiting []reflect.Type) (map[string]interface{}, error) {
+	for _, v := range visiting {
+		if v == t {
+			return nil, fmt.Errorf("recursive type %s", t)
+		}
+	}
+	visiting = append(visiting, t)
+
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get(MappingTagName)
+		if tag == "-" {
+			continue
+		}
+
+		name, skip := jsonFieldName(f)
+		if skip {
+			continue
+		}
+
+		// 匿名结构体字段的属性合并到外层，与 JSON 编码一致
+		if f.Anonymous && name == "" && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded, err := structProperties(ft, visiting)
+				if err != nil {
+					return nil, err
+				}
+				for k, v := range embedded {
+					properties[k] = v
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		property, err := fieldProperty(f.Type, tag, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("field %s.%s: %w", t.Name(), f.Name, err)
+		}
+		properties[name] = property
+	}
+
+	return properties, nil
+}
+
+// jsonFieldName 返回 json 标签中的字段名，标签为 "-" 时跳过
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+func fieldProperty(t reflect.Type, tag string, visiting []reflect.Type) (map[string]interface{}, error) {
+	property := make(map[string]interface{})
+	keyword := false
+
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(opt, "=")
+		switch {
+		case key == "keyword" && !hasValue:
+			keyword = true
+		case key == "type", key == "analyzer", key == "search_analyzer", key == "format", key == "copy_to":
+			property[key] = value
+		case key == "index":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index option %q", value)
+			}
+			property[key] = b
+		case key == "ignore_above":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ignore_above option %q", value)
+			}
+			property[key] = n
+		default:
+			return nil, fmt.Errorf("unknown mapping option %q", opt)
+		}
+	}
+
+	for t.Kind() == reflect.Pointer || ((t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8) {
+		t = t.Elem()
+	}
+
+	typ, _ := property["type"].(string)
+	switch {
+	case typ != "" && typ != "object" && typ != "nested":
+		// 显式指定的类型
+
+	case t == timeType:
+		property["type"] = "date"
+
+	case t.Kind() == reflect.Struct && !t.Implements(textMarshalerType) && !reflect.PointerTo(t).Implements(textMarshalerType):
+		properties, err := structProperties(t, visiting)
+		if err != nil {
+			return nil, err
+		}
+		property["properties"] = properties
+
+	case typ != "":
+
+	default:
+		inferred, err := inferType(t)
+		if err != nil {
+			return nil, err
+		}
+		property["type"] = inferred
+	}
+
+	if keyword {
+		property["fields"] = map[string]interface{}{
+			"keyword": map[string]interface{}{"type": "keyword", "ignore_above": 256},
+		}
+	}
+
+	return property, nil
+}
+
+// inferType 由 Go 类型推导字段类型
+func inferType(t reflect.Type) (string, error) {
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		return "keyword", nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "keyword", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int8, reflect.Uint8:
+		return "short", nil
+	case reflect.Int16, reflect.Uint16:
+		return "integer", nil
+	case reflect.Int32:
+		return "integer", nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return "long", nil
+	case reflect.Float32:
+		return "float", nil
+	case reflect.Float64:
+		return "double", nil
+	case reflect.Slice, reflect.Array:
+		// []byte
+		return "binary", nil
+	case reflect.Map:
+		return "object", nil
+	default:
+		return "", fmt.Errorf("cannot infer mapping type for %s, set it with the es tag", t)
+	}
+}
+
+// 映射差异的类型
+const (
+	DriftMissing    = "missing"    // 声明的字段在线上映射中不存在
+	DriftUnexpected = "unexpected" // 线上映射中存在未声明的字段（通常来自动态映射）
+	DriftChanged    = "changed"    // 字段属性不一致
+)
+
+// MappingDrift 声明的映射与线上映射的差异
+type MappingDrift struct {
+	Path      string      // 字段路径，例如 items.price
+	Kind      string      // missing、unexpected 或 changed
+	Attribute string      // 不一致的属性，例如 type、analyzer
+	Declared  interface{} // 声明的值
+	Live      interface{} // 线上的值
+}
+
+func (d MappingDrift) String() string {
+	if d.Kind == DriftChanged {
+		return fmt.Sprintf("%s %s: %s declared %v, live %v", d.Kind, d.Path, d.Attribute, d.Declared, d.Live)
+	}
+	return fmt.Sprintf("%s %s", d.Kind, d.Path)
+}
+
+// CompareMappings 比较声明的映射与线上映射（均为 {"properties": {...}} 格式），按字段路径排序返回差异。
+// 只比较声明中设置的属性，线上映射的其他属性不视为差异。
+func CompareMappings(declared, live map[string]interface{}) []MappingDrift {
+	declaredFields := flattenMapping(declared, "")
+	liveFields := flattenMapping(live, "")
+
+	var drifts []MappingDrift
+	for path, d := range declaredFields {
+		l, ok := liveFields[path]
+		if !ok {
+			drifts = append(drifts, MappingDrift{Path: path, Kind: DriftMissing})
+			continue
+		}
+
+		for attr, dv := range d {
+			if lv := l[attr]; fmt.Sprint(dv) != fmt.Sprint(lv) {
+				drifts = append(drifts, MappingDrift{Path: path, Kind: DriftChanged, Attribute: attr, Declared: dv, Live: lv})
+			}
+		}
+	}
+	for path := range liveFields {
+		if _, ok := declaredFields[path]; !ok {
+			drifts = append(drifts, MappingDrift{Path: path, Kind: DriftUnexpected})
+		}
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Path != drifts[j].Path {
+			return drifts[i].Path < drifts[j].Path
+		}
+		return drifts[i].Attribute < drifts[j].Attribute
+	})
+
+	return drifts
+}
+
+// flattenMapping 将映射展开为 字段路径 -> 属性，子字段（fields）的路径为 field.sub
+func flattenMapping(mapping map[string]interface{}, prefix string) map[string]map[string]interface{} {
+	fields := make(map[string]map[string]interface{})
+
+	properties, _ := mapping["properties"].(map[string]interface{})
+	for name, v := range properties {
+		property, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := prefix + name
+
+		attrs := make(map[string]interface{})
+		for attr, value := range property {
+			if attr == "properties" || attr == "fields" {
+				continue
+			}
+			attrs[attr] = normalizeAttribute(attr, value)
+		}
+		if _, ok := attrs["type"]; !ok {
+			attrs["type"] = "object"
+		}
+		fields[path] = attrs
+
+		for subPath, subAttrs := range flattenMapping(property, path+".") {
+			fields[subPath] = subAttrs
+		}
+
+		if subFields, ok := property["fields"].(map[string]interface{}); ok {
+			for subPath, subAttrs := range flattenMapping(map[string]interface{}{"properties": subFields}, path+".") {
+				fields[subPath] = subAttrs
+			}
+		}
+	}
+
+	return fields
+}
+
+// listAttributes 值为字段列表的属性，声明时可以是单个字符串，ES 返回的总是数组
+var listAttributes = map[string]bool{"copy_to": true}
+
+// normalizeAttribute 将列表属性统一为排序后的 []string，单个字符串与只有一个元素的数组视为相同
+func normalizeAttribute(attr string, value interface{}) interface{} {
+	var list []string
+	switch v := value.(type) {
+	case string:
+		if !listAttributes[attr] {
+			return value
+		}
+		list = []string{v}
+	case []string:
+		list = append(list, v...)
+	case []interface{}:
+		for _, item := range v {
+			list = append(list, fmt.Sprint(item))
+		}
+	default:
+		return value
+	}
+	sort.Strings(list)
+	return list
+}
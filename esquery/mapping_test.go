@@ -0,0 +1,129 @@
+package esquery
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at" es:"format=strict_date_optional_time||epoch_millis"`
+}
+
+type testItem struct {
+	SKU   string  `json:"sku"`
+	Price float64 `json:"price"`
+}
+
+type testArticle struct {
+	testBase
+	Title   string            `json:"title" es:"type=text,analyzer=ik_max_word,search_analyzer=ik_smart,keyword"`
+	Tags    []string          `json:"tags"`
+	Views   int32             `json:"views"`
+	Public  *bool             `json:"public,omitempty"`
+	Items   []testItem        `json:"items" es:"type=nested"`
+	Author  testItem          `json:"author"`
+	Extra   map[string]string `json:"extra" es:"index=false"`
+	Secret  string            `json:"secret" es:"-"`
+	Ignored string            `json:"-"`
+	Number  json.Number       `json:"number" es:"type=scaled_float"`
+}
+
+func TestMappingFromStruct(t *testing.T) {
+	mapping, err := MappingFromStruct(&testArticle{})
+	require.NoError(t, err)
+
+	expected := `{"properties":{
+		"id":{"type":"long"},
+		"created_at":{"type":"date","format":"strict_date_optional_time||epoch_millis"},
+		"title":{"type":"text","analyzer":"ik_max_word","search_analyzer":"ik_smart","fields":{"keyword":{"type":"keyword","ignore_above":256}}},
+		"tags":{"type":"keyword"},
+		"views":{"type":"integer"},
+		"public":{"type":"boolean"},
+		"items":{"type":"nested","properties":{"sku":{"type":"keyword"},"price":{"type":"double"}}},
+		"author":{"properties":{"sku":{"type":"keyword"},"price":{"type":"double"}}},
+		"extra":{"type":"object","index":false},
+		"number":{"type":"scaled_float"}
+	}}`
+	b, err := json.Marshal(mapping)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(b))
+}
+
+func TestMappingFromStructError(t *testing.T) {
+	_, err := MappingFromStruct("x")
+	assert.Error(t, err)
+
+	_, err = MappingFromStruct(struct {
+		Name string `es:"analyser=x"`
+	}{})
+	assert.Error(t, err)
+
+	_, err = MappingFromStruct(struct {
+		Any interface{}
+	}{})
+	assert.Error(t, err)
+
+	type node struct {
+		Children []node
+	}
+	_, err = MappingFromStruct(node{})
+	assert.Error(t, err)
+}
+
+func TestCompareMappings(t *testing.T) {
+	declared, err := MappingFromStruct(&testArticle{})
+	require.NoError(t, err)
+
+	var live map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"properties":{
+		"id":{"type":"long"},
+		"created_at":{"type":"date","format":"strict_date_optional_time||epoch_millis"},
+		"title":{"type":"text","analyzer":"standard","search_analyzer":"ik_smart","fields":{"keyword":{"type":"keyword","ignore_above":256}}},
+		"tags":{"type":"keyword"},
+		"views":{"type":"long"},
+		"public":{"type":"boolean"},
+		"items":{"type":"nested","properties":{"sku":{"type":"keyword"}}},
+		"author":{"properties":{"sku":{"type":"keyword"},"price":{"type":"double"}}},
+		"extra":{"type":"object","index":"false"},
+		"number":{"type":"scaled_float","scaling_factor":100},
+		"dynamic":{"type":"text"}
+	}}`), &live))
+
+	var drifts []string
+	for _, d := range CompareMappings(declared, live) {
+		drifts = append(drifts, d.String())
+	}
+	assert.Equal(t, []string{
+		"unexpected dynamic",
+		"missing items.price",
+		"changed title: analyzer declared ik_max_word, live standard",
+		"changed views: type declared integer, live long",
+	}, drifts)
+
+	assert.Empty(t, CompareMappings(declared, declared))
+}
+
+func TestCompareMappingsListAttributes(t *testing.T) {
+	declared := map[string]interface{}{"properties": map[string]interface{}{
+		"title": map[string]interface{}{"type": "text", "copy_to": "all"},
+		"body":  map[string]interface{}{"type": "text", "copy_to": []string{"all", "content"}},
+		"tags":  map[string]interface{}{"type": "keyword", "copy_to": "all"},
+	}}
+
+	var live map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"properties":{
+		"title":{"type":"text","copy_to":["all"]},
+		"body":{"type":"text","copy_to":["content","all"]},
+		"tags":{"type":"keyword","copy_to":["all","content"]}
+	}}`), &live))
+
+	// 单个字符串与只有一个元素的数组相同，顺序不同也相同
+	drifts := CompareMappings(declared, live)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, MappingDrift{Path: "tags", Kind: DriftChanged, Attribute: "copy_to", Declared: []string{"all"}, Live: []string{"all", "content"}}, drifts[0])
+}
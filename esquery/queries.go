@@ -1,8 +1,9 @@
 package esquery
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"reflect"
 
 	"github.com/olivere/elastic"
 )
@@ -42,57 +43,115 @@ func (q *ESQuery) GetFirstSorterField() (string, error) {
 		return "", fmt.Errorf("sorters is empty")
 	}
 
-	// 获取第一个 Sorter
-	firstSorter := q.Sorters[0]
+	return sorterField(q.Sorters[0])
+}
 
-	// 类型断言：检查 Sorter 是否为 FieldSort 类型
-	fieldSort, ok := firstSorter.(*elastic.FieldSort)
-	if !ok {
-		return "", fmt.Errorf("first sorter is not a FieldSort type")
+// SortFields 返回所有排序字段的名称，无法识别的排序（例如脚本排序）会被跳过
+func (q *ESQuery) SortFields() []string {
+	var fields []string
+	for _, sorter := range q.Sorters {
+		if field, err := sorterField(sorter); err == nil {
+			fields = append(fields, field)
+		}
 	}
-	// 使用反射获取 fieldName 的值
-	val := reflect.ValueOf(fieldSort).Elem()
-	fieldNameField := val.FieldByName("fieldName")
+	return fields
+}
 
-	if !fieldNameField.IsValid() {
-		return "", fmt.Errorf("fieldName field not found")
+// sorterField 从排序的请求体中读取字段名，格式为 {"field": {...}} 或 "field"
+func sorterField(sorter elastic.Sorter) (string, error) {
+	src, err := sorter.Source()
+	if err != nil {
+		return "", err
 	}
 
-	if fieldNameField.Kind() != reflect.String {
-		return "", fmt.Errorf("fieldName is not of type string")
+	switch v := src.(type) {
+	case string:
+		return v, nil
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for field := range v {
+				return field, nil
+			}
+		}
 	}
 
-	return fieldNameField.String(), nil
+	return "", fmt.Errorf("unsupported sorter type %T", sorter)
 }
 
-func (q *ESQuery) BuildSearchService(client *elastic.Client) *elastic.SearchService {
-	searchService := client.Search().
-		Index(q.Indices...).
+// BuildSearchSource 构建查询请求体
+func (q *ESQuery) BuildSearchSource() *elastic.SearchSource {
+	searchSource := elastic.NewSearchSource().
 		From(int(q.From)).
 		Size(int(q.Size)).
-		Query(
-			elastic.NewConstantScoreQuery(
-				elastic.NewBoolQuery().
-					Must(q.MustQuery...).
-					MustNot(q.MustNotQuery...).
-					Should(q.ShouldQuery...).
-					Filter(q.Filters...),
-			),
-		).
+		Query(q.buildQuery()).
 		SortBy(q.Sorters...).
-		TrackTotalHits(true)
-
-	if q.NoTotal {
-		searchService = searchService.TrackTotalHits(false)
-	}
+		TrackTotalHits(!q.NoTotal)
 
 	if len(q.SearchAfter) > 0 {
-		searchService = searchService.SearchAfter(q.SearchAfter...)
+		searchSource = searchSource.SearchAfter(q.SearchAfter...)
 	}
 
 	if len(q.Include) > 0 {
-		searchService = searchService.FetchSourceContext(elastic.NewFetchSourceContext(true).Include(q.Include...))
+		searchSource = searchSource.FetchSourceContext(elastic.NewFetchSourceContext(true).Include(q.Include...))
+	}
+
+	if q.Highlight != nil {
+		searchSource = searchSource.Highlight(q.Highlight)
+	}
+
+	for name, agg := range q.Aggregations {
+		searchSource = searchSource.Aggregation(name, agg)
+	}
+
+	return searchSource
+}
+
+func (q *ESQuery) BuildSearchService(client *elastic.Client) *elastic.SearchService {
+	return client.Search().
+		Index(q.Indices...).
+		SearchSource(q.BuildSearchSource())
+}
+
+// Source 返回查询的请求体，与 BuildSearchService 发送的内容一致，不需要 ES 客户端
+func (q *ESQuery) Source() (interface{}, error) {
+	return q.BuildSearchSource().Source()
+}
+
+// RenderJSON 将查询的请求体渲染为规范化的 JSON，可用于 golden 文件测试，参见 CanonicalJSON
+func (q *ESQuery) RenderJSON() ([]byte, error) {
+	src, err := q.Source()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return CanonicalJSON(b)
+}
+
+// CanonicalJSON 规范化 JSON：对象的字段按字母排序，两个空格缩进，数字保持原样，不转义 HTML 字符。
+// 内容相同的 JSON 总是得到相同的输出。
+func CanonicalJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
 	}
 
-	return searchService
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,98 @@
+package esquery
+
+import "github.com/olivere/elastic"
+
+const (
+	DefaultHighlightPreTag  = "<em>"  // 默认的高亮开始标签
+	DefaultHighlightPostTag = "</em>" // 默认的高亮结束标签
+)
+
+// FieldBoost 搜索字段及其权重
+type FieldBoost struct {
+	Field string
+	Boost float64 // 权重，小于等于 0 时使用默认权重 1
+}
+
+// EnableScoring 保留相关性评分，不再使用 constant_score 包装查询
+func (q *ESQuery) EnableScoring() {
+	q.Scoring = true
+}
+
+// AddMultiMatch 在多个字段中搜索关键词并按字段权重计算评分，加入 MustQuery，同时开启评分
+func (q *ESQuery) AddMultiMatch(text string, fields ...FieldBoost) {
+	query := elastic.NewMultiMatchQuery(text)
+	for _, f := range fields {
+		if f.Boost > 0 {
+			query = query.FieldWithBoost(f.Field, f.Boost)
+		} else {
+			query = query.Field(f.Field)
+		}
+	}
+
+	q.AddMust(query)
+	q.EnableScoring()
+}
+
+// AddScoreFunction 添加 function_score 的评分函数，仅在开启评分时生效
+func (q *ESQuery) AddScoreFunction(fn elastic.ScoreFunction) {
+	q.ScoreFunctions = append(q.ScoreFunctions, fn)
+}
+
+// AddRecencyDecay 按日期字段的新旧程度衰减评分（高斯衰减），同时开启评分。
+// 距今 offset 以内不衰减，距今 offset+scale 时评分乘以 decay，例如 ("created_at", "7d", "1d", 0.5)。
+func (q *ESQuery) AddRecencyDecay(field, scale, offset string, decay float64) {
+	fn := elastic.NewGaussDecayFunction().
+		FieldName(field).
+		Origin("now").
+		Scale(scale)
+	if offset != "" {
+		fn = fn.Offset(offset)
+	}
+	if decay > 0 && decay < 1 {
+		fn = fn.Decay(decay)
+	}
+
+	q.AddScoreFunction(fn)
+	q.EnableScoring()
+}
+
+// AddHighlight 高亮匹配的字段，使用 DefaultHighlightPreTag/DefaultHighlightPostTag 包裹关键词
+func (q *ESQuery) AddHighlight(fields ...string) {
+	if q.Highlight == nil {
+		q.Highlight = elastic.NewHighlight().
+			PreTags(DefaultHighlightPreTag).
+			PostTags(DefaultHighlightPostTag)
+	}
+	for _, field := range fields {
+		q.Highlight = q.Highlight.Field(field)
+	}
+}
+
+// buildQuery 组合查询条件：未开启评分时使用 constant_score，
+// 开启评分且有评分函数时使用 function_score（评分相乘）
+func (q *ESQuery) buildQuery() elastic.Query {
+	boolQuery := elastic.NewBoolQuery().
+		Must(q.MustQuery...).
+		MustNot(q.MustNotQuery...).
+		Should(q.ShouldQuery...).
+		Filter(q.Filters...)
+	if q.MinimumShouldMatch != "" {
+		boolQuery = boolQuery.MinimumShouldMatch(q.MinimumShouldMatch)
+	}
+
+	if !q.Scoring {
+		return elastic.NewConstantScoreQuery(boolQuery)
+	}
+
+	if len(q.ScoreFunctions) == 0 {
+		return boolQuery
+	}
+
+	functionScore := elastic.NewFunctionScoreQuery().
+		Query(boolQuery).
+		BoostMode("multiply")
+	for _, fn := range q.ScoreFunctions {
+		functionScore = functionScore.AddScoreFunc(fn)
+	}
+	return functionScore
+}
@@ -0,0 +1,75 @@
+package esquery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func searchSourceField(t *testing.T, q *ESQuery, key string) string {
+	t.Helper()
+	src, err := q.BuildSearchSource().Source()
+	require.NoError(t, err)
+	b, err := json.Marshal(src.(map[string]interface{})[key])
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestConstantScoreByDefault(t *testing.T) {
+	q := NewESQuery(nil)
+	q.AddFilters(elastic.NewTermQuery("status", 1))
+
+	assert.JSONEq(t,
+		`{"constant_score":{"filter":{"bool":{"filter":{"term":{"status":1}}}}}}`,
+		searchSourceField(t, q, "query"),
+	)
+}
+
+func TestScoringQuery(t *testing.T) {
+	q := NewESQuery(nil)
+	q.AddMultiMatch("golang", FieldBoost{Field: "title", Boost: 3}, FieldBoost{Field: "content"})
+	q.AddShouldQuery(elastic.NewTermQuery("tags", "go"))
+	q.AddShouldQuery(elastic.NewTermQuery("tags", "es"))
+	q.MinimumShouldMatch = "1"
+
+	assert.True(t, q.Scoring)
+	assert.JSONEq(t, `{"bool":{
+		"minimum_should_match":"1",
+		"must":{"multi_match":{"fields":["title^3.000000","content"],"query":"golang"}},
+		"should":[{"term":{"tags":"go"}},{"term":{"tags":"es"}}]
+	}}`, searchSourceField(t, q, "query"))
+}
+
+func TestRecencyDecay(t *testing.T) {
+	q := NewESQuery(nil)
+	q.AddMust(elastic.NewMatchQuery("title", "go"))
+	q.AddRecencyDecay("created_at", "7d", "1d", 0.5)
+
+	assert.JSONEq(t, `{"function_score":{
+		"boost_mode":"multiply",
+		"functions":[{"gauss":{"created_at":{"decay":0.5,"offset":"1d","origin":"now","scale":"7d"}}}],
+		"query":{"bool":{"must":{"match":{"title":{"query":"go"}}}}}
+	}}`, searchSourceField(t, q, "query"))
+}
+
+func TestScoreFunctionsIgnoredWithoutScoring(t *testing.T) {
+	q := NewESQuery(nil)
+	q.AddScoreFunction(elastic.NewWeightFactorFunction(2))
+
+	assert.JSONEq(t, `{"constant_score":{"filter":{"bool":{}}}}`, searchSourceField(t, q, "query"))
+}
+
+func TestAddHighlight(t *testing.T) {
+	q := NewESQuery(nil)
+	q.AddHighlight("title")
+	q.AddHighlight("content")
+
+	assert.JSONEq(t, `{
+		"fields":{"content":{},"title":{}},
+		"post_tags":["</em>"],
+		"pre_tags":["<em>"]
+	}`, searchSourceField(t, q, "highlight"))
+}
@@ -0,0 +1,222 @@
+package esquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+
+	"github.com/olivere/elastic"
+)
+
+const (
+	TotalRelationEqual        = "eq"  // 总数为精确值
+	TotalRelationGreaterEqual = "gte" // 总数为下限
+)
+
+// Hit 解码后的命中文档
+type Hit[T any] struct {
+	ID        string
+	Index     string
+	Score     *float64
+	Sort      []interface{}
+	Highlight map[string][]string
+	Source    T
+}
+
+// Page 解码后的一页查询结果
+type Page[T any] struct {
+	Hits          []Hit[T]
+	Total         int64  // 总数，TotalRelation 为 gte 时是下限
+	TotalRelation string // eq 或 gte
+	TookInMillis  int64
+	NextCursor    string // 游标分页时下一页的游标，没有更多数据时为空
+	Aggregations  elastic.Aggregations
+}
+
+// Search 执行查询并将 _source 解码为 T。
+// 设置 NoTotal 或总数超过统计上限时，Total 为已知的下限（From + 当前页数量），TotalRelation 为 gte。
+func Search[T any](ctx context.Context, client *elastic.Client, q *ESQuery) (*Page[T], error) {
+	src, err := q.BuildSearchSource().Source()
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := doSearch(ctx, client, searchPath(q.Indices), src)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage[T](ret, int64(q.From))
+}
+
+// SearchAfter 游标分页查询并将 _source 解码为 T，cursor 为空时查询第一页，参见 ESQuery.SearchAfterPage
+func SearchAfter[T any](ctx context.Context, client *elastic.Client, q *ESQuery, cursor string, opts ...SearchAfterOption) (*Page[T], error) {
+	ret, err := q.searchAfter(ctx, client, cursor, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := newPage[T](ret.response, 0)
+	if err != nil {
+		return nil, err
+	}
+	page.NextCursor = ret.nextCursor
+
+	return page, nil
+}
+
+// SearchAll 以游标分页遍历所有匹配的文档，用于导出；q.Size 为每批的数量。
+// 遍历中途退出时会关闭 PIT 会话。
+//
+//	for hit, err := range esquery.SearchAll[User](ctx, client, q, esquery.WithPointInTime("1m")) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func SearchAll[T any](ctx context.Context, client *elastic.Client, q *ESQuery, opts ...SearchAfterOption) iter.Seq2[Hit[T], error] {
+	return func(yield func(Hit[T], error) bool) {
+		cursor := ""
+		for {
+			page, err := SearchAfter[T](ctx, client, q, cursor, opts...)
+			if err != nil {
+				yield(Hit[T]{}, err)
+				return
+			}
+
+			for _, hit := range page.Hits {
+				if !yield(hit, nil) {
+					closeCursor(ctx, client, page.NextCursor)
+					return
+				}
+			}
+
+			if page.NextCursor == "" {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
+
+// closeCursor 关闭游标中的 PIT 会话，PIT 过期后也会自动释放，因此忽略错误
+func closeCursor(ctx context.Context, client *elastic.Client, cursor string) {
+	if cursor == "" {
+		return
+	}
+	if c, err := DecodeCursor(cursor); err == nil && c.PitID != "" {
+		_ = ClosePointInTime(ctx, client, c.PitID)
+	}
+}
+
+// DecodeHit 将命中文档的 _source 解码为 T
+func DecodeHit[T any](hit *elastic.SearchHit) (Hit[T], error) {
+	h := Hit[T]{
+		ID:        hit.Id,
+		Index:     hit.Index,
+		Score:     hit.Score,
+		Sort:      hit.Sort,
+		Highlight: hit.Highlight,
+	}
+
+	if hit.Source != nil && len(*hit.Source) > 0 {
+		if err := json.Unmarshal(*hit.Source, &h.Source); err != nil {
+			return Hit[T]{}, fmt.Errorf("decode hit %q failed: %w", hit.Id, err)
+		}
+	}
+
+	return h, nil
+}
+
+func newPage[T any](ret *searchResponse, from int64) (*Page[T], error) {
+	page := &Page[T]{
+		Total:         ret.Hits.Total.Value,
+		TotalRelation: ret.Hits.Total.Relation,
+		TookInMillis:  ret.TookInMillis,
+		Aggregations:  ret.Aggregations,
+	}
+
+	for _, hit := range ret.Hits.Hits {
+		h, err := DecodeHit[T](hit)
+		if err != nil {
+			return nil, err
+		}
+		page.Hits = append(page.Hits, h)
+	}
+
+	// 未统计总数时 ES 6 返回 -1，ES 7+ 不返回
+	if page.TotalRelation == "" {
+		page.TotalRelation = TotalRelationGreaterEqual
+	}
+	if lowerBound := from + int64(len(page.Hits)); page.Total < lowerBound {
+		page.Total = lowerBound
+		page.TotalRelation = TotalRelationGreaterEqual
+	}
+
+	return page, nil
+}
+
+// totalHits 命中总数，兼容 ES 6 的数字格式和 ES 7+ 的 {"value": 10, "relation": "eq"} 格式
+type totalHits struct {
+	Value    int64
+	Relation string
+}
+
+func (t *totalHits) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		var v struct {
+			Value    int64  `json:"value"`
+			Relation string `json:"relation"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		t.Value, t.Relation = v.Value, v.Relation
+		return nil
+	}
+
+	if err := json.Unmarshal(data, &t.Value); err != nil {
+		return err
+	}
+	t.Relation = TotalRelationEqual
+	return nil
+}
+
+type searchResponse struct {
+	TookInMillis int64  `json:"took"`
+	TimedOut     bool   `json:"timed_out"`
+	PitID        string `json:"pit_id,omitempty"`
+	Hits         struct {
+		Total    totalHits            `json:"total"`
+		MaxScore *float64             `json:"max_score,omitempty"`
+		Hits     []*elastic.SearchHit `json:"hits"`
+	} `json:"hits"`
+	Aggregations elastic.Aggregations `json:"aggregations,omitempty"`
+}
+
+// doSearch 执行查询请求，数字排序值保留为 json.Number，避免 int64 精度丢失
+func doSearch(ctx context.Context, client *elastic.Client, path string, body interface{}) (*searchResponse, error) {
+	res, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "POST",
+		Path:   path,
+		Body:   body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &searchResponse{}
+	decoder := json.NewDecoder(bytes.NewReader(res.Body))
+	decoder.UseNumber()
+	if err = decoder.Decode(ret); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,258 @@
+package esquery
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/olivere/elastic"
+)
+
+const (
+	PointInTimeTieBreakerField  = "_shard_doc" // 使用 PIT 时默认的排序兜底字段（ES 7.12+）
+	DefaultPointInTimeKeepAlive = "1m"         // 默认的 PIT 保持时长
+)
+
+var (
+	ErrInvalidCursor      = errors.New("invalid search after cursor")
+	ErrTieBreakerRequired = errors.New("search after requires a unique tie breaker field, use WithTieBreaker or WithPointInTime")
+)
+
+// Cursor 游标分页的位置，编码后作为不透明字符串返回给调用方
+type Cursor struct {
+	SortValues []interface{} `json:"s"`           // 上一页最后一条数据的排序值
+	PitID      string        `json:"p,omitempty"` // PIT 会话ID
+}
+
+// EncodeCursor 将游标编码为 URL 安全的字符串
+func EncodeCursor(c Cursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor 解码游标，数字排序值保留为 json.Number，避免 int64 精度丢失
+func DecodeCursor(cursor string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err = decoder.Decode(&c); err != nil || len(c.SortValues) == 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// SearchAfterOption 游标分页的配置项
+type SearchAfterOption func(o *searchAfterOptions)
+
+type searchAfterOptions struct {
+	tieBreaker    string
+	hasTieBreaker bool
+	pit           bool
+	keepAlive     string
+}
+
+// WithTieBreaker 设置排序兜底字段，需要是值唯一的 keyword 或数值字段（例如业务主键，不要使用 ES 8 禁止排序的 _id）；
+// 为空时不添加，排序字段本身已经唯一时使用
+func WithTieBreaker(field string) SearchAfterOption {
+	return func(o *searchAfterOptions) {
+		o.tieBreaker = field
+		o.hasTieBreaker = true
+	}
+}
+
+// WithPointInTime 使用 PIT 会话分页，翻页期间看到一致的数据快照（ES 7.10+）。
+// 首页时自动打开 PIT，最后一页时自动关闭（关闭失败时忽略）；中途放弃翻页时 PIT 在 keepAlive 后过期。
+func WithPointInTime(keepAlive string) SearchAfterOption {
+	return func(o *searchAfterOptions) {
+		o.pit = true
+		o.keepAlive = keepAlive
+	}
+}
+
+// SearchAfterResult 游标分页的结果
+type SearchAfterResult struct {
+	Hits         []*elastic.SearchHit
+	TotalHits    int64
+	NextCursor   string               // 下一页的游标，没有更多数据时为空
+	Aggregations elastic.Aggregations // 聚合结果
+}
+
+// SearchAfterPage 查询 cursor 之后的一页数据，cursor 为空时查询第一页。
+// 在排序末尾添加兜底字段并忽略 From。兜底字段通过 WithTieBreaker 指定，使用 PIT 时默认为 _shard_doc，
+// 两者都没有时返回 ErrTieBreakerRequired。
+func (q *ESQuery) SearchAfterPage(ctx context.Context, client *elastic.Client, cursor string, opts ...SearchAfterOption) (*SearchAfterResult, error) {
+	ret, err := q.searchAfter(ctx, client, cursor, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchAfterResult{
+		Hits:         ret.response.Hits.Hits,
+		TotalHits:    ret.response.Hits.Total.Value,
+		NextCursor:   ret.nextCursor,
+		Aggregations: ret.response.Aggregations,
+	}, nil
+}
+
+type searchAfterResponse struct {
+	response   *searchResponse
+	nextCursor string
+}
+
+func (q *ESQuery) searchAfter(ctx context.Context, client *elastic.Client, cursor string, opts ...SearchAfterOption) (_ *searchAfterResponse, err error) {
+	o := &searchAfterOptions{keepAlive: DefaultPointInTimeKeepAlive}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.hasTieBreaker {
+		if !o.pit {
+			return nil, ErrTieBreakerRequired
+		}
+		o.tieBreaker = PointInTimeTieBreakerField
+	}
+
+	pq := *q
+	pq.From = DefaultFrom
+	pq.SearchAfter = nil
+	pq.Sorters = append([]elastic.Sorter(nil), q.Sorters...)
+	if o.tieBreaker != "" && !containsString(pq.SortFields(), o.tieBreaker) {
+		pq.Sorters = append(pq.Sorters, elastic.NewFieldSort(o.tieBreaker).Asc())
+	}
+
+	var pitID string
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		pq.SearchAfter = c.SortValues
+		pitID = c.PitID
+	}
+
+	if o.pit && pitID == "" {
+		if pitID, err = OpenPointInTime(ctx, client, q.Indices, o.keepAlive); err != nil {
+			return nil, err
+		}
+
+		// 本次打开的 PIT 在出错时关闭，调用方拿不到游标也就无法再使用它
+		defer func() {
+			if err != nil {
+				if closeErr := ClosePointInTime(context.WithoutCancel(ctx), client, pitID); closeErr != nil {
+					err = errors.Join(err, closeErr)
+				}
+			}
+		}()
+	}
+
+	src, err := pq.BuildSearchSource().Source()
+	if err != nil {
+		return nil, err
+	}
+
+	body := src.(map[string]interface{})
+	path := searchPath(q.Indices)
+	if o.pit {
+		// 使用 PIT 时不能在路径中指定索引
+		body["pit"] = map[string]interface{}{"id": pitID, "keep_alive": o.keepAlive}
+		path = "/_search"
+	}
+
+	ret, err := doSearch(ctx, client, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if ret.PitID != "" {
+		pitID = ret.PitID
+	}
+
+	result := &searchAfterResponse{response: ret}
+
+	hits := ret.Hits.Hits
+	if len(hits) == 0 || len(hits) < int(pq.Size) {
+		// 最后一页，关闭 PIT 只是清理，失败时 PIT 在 keepAlive 后过期，不丢弃已查询到的结果
+		if o.pit && pitID != "" {
+			_ = ClosePointInTime(ctx, client, pitID)
+		}
+		return result, nil
+	}
+
+	last := hits[len(hits)-1]
+	if result.nextCursor, err = EncodeCursor(Cursor{SortValues: last.Sort, PitID: pitID}); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// OpenPointInTime 打开 PIT 会话，返回 PIT ID
+func OpenPointInTime(ctx context.Context, client *elastic.Client, indices []string, keepAlive string) (string, error) {
+	if keepAlive == "" {
+		keepAlive = DefaultPointInTimeKeepAlive
+	}
+
+	res, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "POST",
+		Path:   strings.TrimSuffix(searchPath(indices), "/_search") + "/_pit",
+		Params: url.Values{"keep_alive": []string{keepAlive}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var ret struct {
+		ID string `json:"id"`
+	}
+	if err = json.Unmarshal(res.Body, &ret); err != nil {
+		return "", err
+	}
+	if ret.ID == "" {
+		return "", errors.New("open point in time returned empty id")
+	}
+
+	return ret.ID, nil
+}
+
+// ClosePointInTime 关闭 PIT 会话
+func ClosePointInTime(ctx context.Context, client *elastic.Client, pitID string) error {
+	_, err := client.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method:       "DELETE",
+		Path:         "/_pit",
+		Body:         map[string]interface{}{"id": pitID},
+		IgnoreErrors: []int{404},
+	})
+	return err
+}
+
+func searchPath(indices []string) string {
+	if len(indices) == 0 {
+		return "/_search"
+	}
+
+	escaped := make([]string, len(indices))
+	for i, index := range indices {
+		escaped[i] = url.PathEscape(index)
+	}
+	return "/" + strings.Join(escaped, ",") + "/_search"
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
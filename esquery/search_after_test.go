@@ -0,0 +1,219 @@
+package esquery_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/olivere/elastic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alec404/go-libs/esquery"
+	"github.com/alec404/go-libs/esquery/estest"
+)
+
+// requestBody 解析请求体，响应函数在服务端的 goroutine 中执行，不能使用 require，解析失败时返回 nil
+func requestBody(r estest.Request) map[string]interface{} {
+	var body map[string]interface{}
+	_ = json.Unmarshal(r.Body, &body)
+	return body
+}
+
+// decodeBody 在测试的 goroutine 中解析记录的请求体
+func decodeBody(t *testing.T, r estest.Request) map[string]interface{} {
+	t.Helper()
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(r.Body, &body))
+	return body
+}
+
+func TestCursor(t *testing.T) {
+	cursor, err := esquery.EncodeCursor(esquery.Cursor{SortValues: []interface{}{json.Number("1234567890123456789"), "abc"}, PitID: "pit"})
+	require.NoError(t, err)
+
+	c, err := esquery.DecodeCursor(cursor)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{json.Number("1234567890123456789"), "abc"}, c.SortValues)
+	assert.Equal(t, "pit", c.PitID)
+
+	_, err = esquery.DecodeCursor("!!")
+	assert.ErrorIs(t, err, esquery.ErrInvalidCursor)
+	_, err = esquery.DecodeCursor("e30") // {}
+	assert.ErrorIs(t, err, esquery.ErrInvalidCursor)
+}
+
+func TestSortFields(t *testing.T) {
+	q := esquery.NewESQuery(nil)
+	_, err := q.GetFirstSorterField()
+	assert.Error(t, err)
+
+	q.AddSort(elastic.NewFieldSort("created_at").Desc())
+	q.Sorters = append(q.Sorters, elastic.NewScoreSort())
+
+	field, err := q.GetFirstSorterField()
+	require.NoError(t, err)
+	assert.Equal(t, "created_at", field)
+	assert.Equal(t, []string{"created_at", "_score"}, q.SortFields())
+}
+
+func TestSearchAfterPage(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.On("POST", "/orders/_search", func(r estest.Request) estest.Response {
+		if _, ok := requestBody(r)["search_after"]; ok {
+			return estest.Response{Body: `{"hits":{"total":3,"hits":[{"_id":"3","sort":[1700000000003,"3"]}]}}`}
+		}
+		return estest.Response{Body: `{"hits":{"total":3,"hits":[{"_id":"1","sort":[1700000000001,"1"]},{"_id":"2","sort":[9007199254740993,"2"]}]}}`}
+	})
+
+	q := esquery.NewESQuery([]string{"orders"})
+	q.Size = 2
+	q.From = 20
+	q.AddSort(elastic.NewFieldSort("created_at").Desc())
+
+	_, err := q.SearchAfterPage(context.Background(), srv.Client(), "")
+	assert.ErrorIs(t, err, esquery.ErrTieBreakerRequired)
+
+	page, err := q.SearchAfterPage(context.Background(), srv.Client(), "", esquery.WithTieBreaker("order_no"))
+	require.NoError(t, err)
+	assert.Len(t, page.Hits, 2)
+	assert.Equal(t, int64(3), page.TotalHits)
+	require.NotEmpty(t, page.NextCursor)
+
+	c, err := esquery.DecodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{json.Number("9007199254740993"), "2"}, c.SortValues)
+
+	page, err = q.SearchAfterPage(context.Background(), srv.Client(), page.NextCursor, esquery.WithTieBreaker("order_no"))
+	require.NoError(t, err)
+	assert.Len(t, page.Hits, 1)
+	assert.Empty(t, page.NextCursor)
+
+	requests := srv.Requests()
+	require.Len(t, requests, 2)
+	first := decodeBody(t, requests[0])
+	assert.Equal(t, float64(0), first["from"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"created_at": map[string]interface{}{"order": "desc"}},
+		map[string]interface{}{"order_no": map[string]interface{}{"order": "asc"}},
+	}, first["sort"])
+	assert.Contains(t, string(requests[1].Body), `"search_after":[9007199254740993,"2"]`)
+
+	// 原查询不被修改
+	assert.Len(t, q.Sorters, 1)
+	assert.Equal(t, int32(20), q.From)
+}
+
+// newPointInTimeServer 模拟 PIT 的打开、查询和关闭，第一页返回一条数据，之后没有数据
+func newPointInTimeServer(t *testing.T, closeStatus int) *estest.Server {
+	t.Helper()
+
+	srv := estest.NewServer(t)
+	srv.Respond("POST", "/orders/_pit", http.StatusOK, `{"id":"pit-1"}`)
+	srv.Respond("DELETE", "/_pit", closeStatus, `{"succeeded":true}`)
+	srv.On("POST", "/_search", func(r estest.Request) estest.Response {
+		if _, ok := requestBody(r)["search_after"]; ok {
+			return estest.Response{Body: `{"pit_id":"pit-2","hits":{"total":2,"hits":[]}}`}
+		}
+		return estest.Response{Body: `{"pit_id":"pit-2","hits":{"total":2,"hits":[{"_id":"1","sort":[1,0]}]}}`}
+	})
+	return srv
+}
+
+func TestSearchAfterPagePointInTime(t *testing.T) {
+	srv := newPointInTimeServer(t, http.StatusOK)
+
+	q := esquery.NewESQuery([]string{"orders"})
+	q.Size = 1
+
+	page, err := q.SearchAfterPage(context.Background(), srv.Client(), "", esquery.WithPointInTime("2m"))
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = q.SearchAfterPage(context.Background(), srv.Client(), page.NextCursor, esquery.WithPointInTime("2m"))
+	require.NoError(t, err)
+	assert.Empty(t, page.NextCursor)
+
+	requests := srv.Requests()
+	require.Len(t, requests, 4)
+
+	open := requests[0]
+	assert.Equal(t, "POST", open.Method)
+	assert.Equal(t, "2m", open.Query.Get("keep_alive"))
+
+	search := decodeBody(t, requests[1])
+	assert.Equal(t, "/_search", requests[1].Path)
+	assert.Equal(t, map[string]interface{}{"id": "pit-1", "keep_alive": "2m"}, search["pit"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"_shard_doc": map[string]interface{}{"order": "asc"}},
+	}, search["sort"])
+
+	assert.Equal(t, map[string]interface{}{"id": "pit-2", "keep_alive": "2m"}, decodeBody(t, requests[2])["pit"])
+
+	closing := requests[3]
+	assert.Equal(t, "DELETE", closing.Method)
+	assert.Equal(t, "/_pit", closing.Path)
+	assert.Equal(t, map[string]interface{}{"id": "pit-2"}, decodeBody(t, closing))
+}
+
+func TestSearchAfterPagePointInTimeCloseFailed(t *testing.T) {
+	srv := newPointInTimeServer(t, http.StatusInternalServerError)
+
+	q := esquery.NewESQuery([]string{"orders"})
+	q.Size = 1
+
+	cursor, err := esquery.EncodeCursor(esquery.Cursor{SortValues: []interface{}{1, 0}, PitID: "pit-1"})
+	require.NoError(t, err)
+
+	// 关闭 PIT 失败时仍返回最后一页
+	page, err := q.SearchAfterPage(context.Background(), srv.Client(), cursor, esquery.WithPointInTime("2m"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.TotalHits)
+	assert.Empty(t, page.NextCursor)
+
+	requests := srv.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "DELETE", requests[1].Method)
+}
+
+func TestSearchAfterPagePointInTimeClosedOnError(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("POST", "/orders/_pit", http.StatusOK, `{"id":"pit-1"}`)
+	srv.Respond("DELETE", "/_pit", http.StatusOK, `{"succeeded":true}`)
+	srv.Respond("POST", "/_search", http.StatusOK, `not json`)
+
+	q := esquery.NewESQuery([]string{"orders"})
+	q.Size = 1
+
+	_, err := q.SearchAfterPage(context.Background(), srv.Client(), "", esquery.WithPointInTime("2m"))
+	require.Error(t, err)
+
+	requests := srv.Requests()
+	require.Len(t, requests, 3)
+	closing := requests[2]
+	assert.Equal(t, "DELETE", closing.Method)
+	assert.Equal(t, map[string]interface{}{"id": "pit-1"}, decodeBody(t, closing))
+
+	// 游标中的 PIT 由调用方重试，出错时不关闭
+	srv.Reset()
+	cursor, err := esquery.EncodeCursor(esquery.Cursor{SortValues: []interface{}{1}, PitID: "pit-1"})
+	require.NoError(t, err)
+	_, err = q.SearchAfterPage(context.Background(), srv.Client(), cursor, esquery.WithPointInTime("2m"))
+	require.Error(t, err)
+	assert.Len(t, srv.Requests(), 1)
+}
+
+func TestSearchAfterPageAggregations(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("POST", "/orders/_search", http.StatusOK, `{"hits":{"total":0,"hits":[]},"aggregations":{"by_status":{"buckets":[{"key":"paid","doc_count":1}]}}}`)
+
+	q := esquery.NewESQuery([]string{"orders"})
+	q.AddAggregation(esquery.TermsAgg("by_status", "status", 0))
+
+	page, err := q.SearchAfterPage(context.Background(), srv.Client(), "", esquery.WithTieBreaker("id"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"paid": 1}, esquery.TermsCounts(page.Aggregations, "by_status"))
+	assert.Contains(t, decodeBody(t, srv.LastRequest()), "aggregations")
+}
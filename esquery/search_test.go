@@ -0,0 +1,143 @@
+package esquery_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/alec404/go-libs/esquery"
+	"github.com/alec404/go-libs/esquery/estest"
+)
+
+type testUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestSearch(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("POST", "/users/_search", http.StatusOK, `{"took":7,"hits":{"total":{"value":25,"relation":"eq"},"hits":[
+		{"_index":"users","_id":"1","_score":1.5,"sort":[12345678901234567],"_source":{"name":"tom","age":18},"highlight":{"name":["<em>tom</em>"]}},
+		{"_index":"users","_id":"2","_score":null,"_source":{"name":"amy","age":20}}
+	]}}`)
+
+	q := esquery.NewESQuery([]string{"users"})
+	q.AddHighlight("name")
+
+	page, err := esquery.Search[testUser](context.Background(), srv.Client(), q)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(25), page.Total)
+	assert.Equal(t, esquery.TotalRelationEqual, page.TotalRelation)
+	assert.Equal(t, int64(7), page.TookInMillis)
+	require.Len(t, page.Hits, 2)
+
+	hit := page.Hits[0]
+	assert.Equal(t, "1", hit.ID)
+	assert.Equal(t, "users", hit.Index)
+	require.NotNil(t, hit.Score)
+	assert.Equal(t, 1.5, *hit.Score)
+	assert.Equal(t, []interface{}{json.Number("12345678901234567")}, hit.Sort)
+	assert.Equal(t, map[string][]string{"name": {"<em>tom</em>"}}, hit.Highlight)
+	assert.Equal(t, testUser{Name: "tom", Age: 18}, hit.Source)
+	assert.Nil(t, page.Hits[1].Score)
+
+	assert.Equal(t, "/users/_search", srv.LastRequest().Path)
+}
+
+func TestSearchTotal(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    string
+		from     int32
+		expected int64
+		relation string
+	}{
+		{name: "es6", total: `"total":30,`, expected: 30, relation: esquery.TotalRelationEqual},
+		{name: "es6 no total", total: `"total":-1,`, from: 10, expected: 12, relation: esquery.TotalRelationGreaterEqual},
+		{name: "es7 lower bound", total: `"total":{"value":10000,"relation":"gte"},`, expected: 10000, relation: esquery.TotalRelationGreaterEqual},
+		{name: "es7 no total", total: ``, from: 20, expected: 22, relation: esquery.TotalRelationGreaterEqual},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := estest.NewServer(t)
+			srv.Respond("POST", "/_search", http.StatusOK, `{"hits":{`+tt.total+`"hits":[{"_id":"1","_source":{}},{"_id":"2","_source":{}}]}}`)
+
+			q := esquery.NewESQuery(nil)
+			q.From = tt.from
+			q.NoTotal = true
+
+			page, err := esquery.Search[testUser](context.Background(), srv.Client(), q)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, page.Total)
+			assert.Equal(t, tt.relation, page.TotalRelation)
+		})
+	}
+}
+
+func TestSearchDecodeError(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("POST", "/_search", http.StatusOK, `{"hits":{"total":1,"hits":[{"_id":"1","_source":{"age":"x"}}]}}`)
+
+	_, err := esquery.Search[testUser](context.Background(), srv.Client(), esquery.NewESQuery(nil))
+	assert.Error(t, err)
+}
+
+func TestSearchAll(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.On("POST", "/users/_search", func(r estest.Request) estest.Response {
+		after := 0
+		if sa, ok := requestBody(r)["search_after"].([]interface{}); ok && len(sa) > 0 {
+			v, _ := sa[0].(float64)
+			after = int(v)
+		}
+
+		var hits []string
+		for i := after + 1; i <= min(after+2, 5); i++ {
+			hits = append(hits, fmt.Sprintf(`{"_id":"%d","sort":[%d],"_source":{"age":%d}}`, i, i, i))
+		}
+		return estest.Response{Body: `{"hits":{"total":5,"hits":[` + strings.Join(hits, ",") + `]}}`}
+	})
+
+	q := esquery.NewESQuery([]string{"users"})
+	q.Size = 2
+
+	var ages []int
+	for hit, err := range esquery.SearchAll[testUser](context.Background(), srv.Client(), q, esquery.WithTieBreaker("")) {
+		require.NoError(t, err)
+		ages = append(ages, hit.Source.Age)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, ages)
+	assert.Len(t, srv.Requests(), 3)
+
+	// 中途退出
+	ages = nil
+	for hit := range esquery.SearchAll[testUser](context.Background(), srv.Client(), q, esquery.WithTieBreaker("")) {
+		ages = append(ages, hit.Source.Age)
+		if len(ages) == 3 {
+			break
+		}
+	}
+	assert.Equal(t, []int{1, 2, 3}, ages)
+}
+
+func TestSearchAllError(t *testing.T) {
+	srv := estest.NewServer(t)
+	srv.Respond("POST", "/_search", http.StatusOK, `not json`)
+
+	var errs []error
+	for _, err := range esquery.SearchAll[testUser](context.Background(), srv.Client(), esquery.NewESQuery(nil), esquery.WithTieBreaker("id")) {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, 1)
+	assert.Error(t, errs[0])
+	assert.False(t, errors.Is(errs[0], esquery.ErrInvalidCursor))
+}
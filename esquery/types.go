@@ -13,7 +13,13 @@ type ESQuery struct {
 	Indices      []string
 	SearchAfter  []interface{}
 	Include      []string
-	NoTotal      bool // 不需要总数
+	NoTotal      bool                           // 不需要总数
+	Aggregations map[string]elastic.Aggregation // 聚合，按名称索引
+
+	Scoring            bool                    // 保留相关性评分，默认使用 constant_score 不计算评分
+	MinimumShouldMatch string                  // ShouldQuery 至少匹配的数量，例如 "1"、"75%"
+	ScoreFunctions     []elastic.ScoreFunction // function_score 的评分函数，仅在开启评分时生效
+	Highlight          *elastic.Highlight      // 高亮
 }
 
 const (
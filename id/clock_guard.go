@@ -0,0 +1,110 @@
+package id
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultMaxClockRollbackWait = 10 * time.Millisecond // 默认的最大时钟回拨等待时间
+
+var ErrClockRollback = errors.New("clock moved backwards")
+
+// ClockRollbackError 时钟回拨超过最大等待时间
+type ClockRollbackError struct {
+	Rollback time.Duration // 回拨的时长
+	MaxWait  time.Duration // 最大等待时间
+}
+
+func (e *ClockRollbackError) Error() string {
+	return fmt.Sprintf("clock moved backwards by %s, exceeds max wait %s", e.Rollback, e.MaxWait)
+}
+
+func (e *ClockRollbackError) Unwrap() error {
+	return ErrClockRollback
+}
+
+// ClockGuardStats 时钟回拨统计
+type ClockGuardStats struct {
+	Waits     uint64 // 等待时钟追上的次数
+	Exhausted uint64 // 回拨超过最大等待时间而拒绝生成的次数
+}
+
+// ClockGuard 时钟回拨保护：记录最近一次生成ID时的墙上时钟，
+// 时钟回拨不超过 maxWait 时等待时钟追上，超过时返回 ClockRollbackError。
+// 用于直接使用墙上时钟生成ID的生成器（例如 OrderNoBuilder），每个生成器使用各自的 ClockGuard；
+// 雪花ID（单调时钟）和索尼雪花ID（经过时间单调递增）在进程内不受时钟回拨影响，不需要使用。
+type ClockGuard struct {
+	mu      sync.Mutex
+	last    time.Time
+	maxWait time.Duration
+	now     func() time.Time
+	sleep   func(time.Duration)
+
+	waits     atomic.Uint64
+	exhausted atomic.Uint64
+}
+
+// NewClockGuard 创建时钟回拨保护，maxWait 小于 0 时使用 DefaultMaxClockRollbackWait，等于 0 时不等待
+func NewClockGuard(maxWait time.Duration) *ClockGuard {
+	if maxWait < 0 {
+		maxWait = DefaultMaxClockRollbackWait
+	}
+	return &ClockGuard{
+		maxWait: maxWait,
+		now:     time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+// Check 在生成ID前调用，时钟正常时返回 nil。等待在锁外进行，不阻塞其他调用方
+func (g *ClockGuard) Check() error {
+	_, err := g.Now()
+	return err
+}
+
+// Now 返回当前的墙上时钟，不早于上一次返回的时间；时钟回拨的处理与 Check 相同
+func (g *ClockGuard) Now() (time.Time, error) {
+	now, rollback, err := g.advance()
+	if err != nil || rollback == 0 {
+		return now, err
+	}
+
+	g.waits.Add(1)
+	g.sleep(rollback)
+
+	if now, rollback, err = g.advance(); err == nil && rollback > 0 {
+		g.exhausted.Add(1)
+		return time.Time{}, &ClockRollbackError{Rollback: rollback, MaxWait: g.maxWait}
+	}
+	return now, err
+}
+
+// advance 时钟未回拨时记录并返回当前时间，回拨不超过 maxWait 时返回回拨的时长
+func (g *ClockGuard) advance() (time.Time, time.Duration, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// 去掉单调时钟读数，只比较墙上时钟
+	now := g.now().Round(0)
+	rollback := g.last.Sub(now)
+	if rollback <= 0 {
+		g.last = now
+		return now, 0, nil
+	}
+	if rollback > g.maxWait {
+		g.exhausted.Add(1)
+		return time.Time{}, 0, &ClockRollbackError{Rollback: rollback, MaxWait: g.maxWait}
+	}
+	return time.Time{}, rollback, nil
+}
+
+// Stats 返回时钟回拨统计
+func (g *ClockGuard) Stats() ClockGuardStats {
+	return ClockGuardStats{
+		Waits:     g.waits.Load(),
+		Exhausted: g.exhausted.Load(),
+	}
+}
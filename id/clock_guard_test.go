@@ -0,0 +1,79 @@
+package id
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockGuard(t *testing.T) {
+	base := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC)
+	now := base
+
+	g := NewClockGuard(10 * time.Millisecond)
+	g.now = func() time.Time { return now }
+	g.sleep = func(d time.Duration) { now = now.Add(d) }
+
+	assert.NoError(t, g.Check())
+
+	// 小幅回拨，等待时钟追上
+	now = base.Add(-5 * time.Millisecond)
+	assert.NoError(t, g.Check())
+	assert.Equal(t, base, now)
+	assert.Equal(t, ClockGuardStats{Waits: 1}, g.Stats())
+
+	// 大幅回拨，直接报错
+	now = base.Add(-time.Second)
+	err := g.Check()
+	assert.ErrorIs(t, err, ErrClockRollback)
+	var rollbackErr *ClockRollbackError
+	assert.ErrorAs(t, err, &rollbackErr)
+	assert.Equal(t, time.Second, rollbackErr.Rollback)
+	assert.Equal(t, ClockGuardStats{Waits: 1, Exhausted: 1}, g.Stats())
+
+	// 时钟恢复后继续生成
+	now = base.Add(time.Millisecond)
+	assert.NoError(t, g.Check())
+
+	// Now 返回检查通过的时间
+	now = base.Add(2 * time.Millisecond)
+	tm, err := g.Now()
+	assert.NoError(t, err)
+	assert.Equal(t, now, tm)
+}
+
+func TestNewSnowflakeIDNotZero(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		id, err := NewSnowflakeID(1)
+		assert.NoError(t, err)
+		assert.NotZero(t, id)
+	}
+
+	_, err := NewSnowflakeID(MaxSnowflakeWorkerID + 1)
+	assert.Error(t, err)
+}
+
+func TestGenerateSnowflakeID(t *testing.T) {
+	assert.NotZero(t, GenerateSnowflakeID(1))
+	assert.Zero(t, GenerateSnowflakeID(MaxSnowflakeWorkerID+1))
+}
+
+func TestClockGuardSleepUnlocked(t *testing.T) {
+	base := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC)
+	now := base
+
+	g := NewClockGuard(10 * time.Millisecond)
+	g.now = func() time.Time { return now }
+	g.sleep = func(d time.Duration) {
+		// 等待期间不持有锁
+		assert.True(t, g.mu.TryLock())
+		g.mu.Unlock()
+		now = now.Add(d)
+	}
+
+	assert.NoError(t, g.Check())
+	now = base.Add(-5 * time.Millisecond)
+	assert.NoError(t, g.Check())
+	assert.Equal(t, ClockGuardStats{Waits: 1}, g.Stats())
+}
@@ -0,0 +1,200 @@
+package id
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	DefaultIDAlphabet   = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789" // 默认的编码字母表
+	minIDAlphabetLength = 16
+)
+
+var ErrInvalidEncodedID = errors.New("invalid encoded id")
+
+// IDEncoder 将 uint64 ID 可逆地编码为短字符串（类似 Hashids），用于在 URL 中隐藏自增ID和业务量。
+// 字母表按盐值打乱，不同实体使用不同的盐值时，相同的ID编码结果也不同。
+// 编码只用于混淆，不能代替权限校验。
+type IDEncoder struct {
+	alphabet  []byte
+	separator byte
+	salt      []byte
+	minLength int
+}
+
+// IDEncoderOption IDEncoder 的配置项
+type IDEncoderOption func(e *IDEncoder)
+
+// WithIDAlphabet 设置字母表，至少 16 个不重复的 ASCII 字符
+func WithIDAlphabet(alphabet string) IDEncoderOption {
+	return func(e *IDEncoder) {
+		e.alphabet = []byte(alphabet)
+	}
+}
+
+// WithIDMinLength 设置编码结果的最小长度
+func WithIDMinLength(minLength int) IDEncoderOption {
+	return func(e *IDEncoder) {
+		e.minLength = minLength
+	}
+}
+
+// NewIDEncoder 创建ID编码器
+func NewIDEncoder(salt string, opts ...IDEncoderOption) (*IDEncoder, error) {
+	e := &IDEncoder{
+		alphabet: []byte(DefaultIDAlphabet),
+		salt:     []byte(salt),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if len(e.alphabet) < minIDAlphabetLength {
+		return nil, fmt.Errorf("id alphabet must contain at least %d characters", minIDAlphabetLength)
+	}
+	seen := make(map[byte]bool, len(e.alphabet))
+	for _, c := range e.alphabet {
+		if c >= 0x80 || c <= ' ' {
+			return nil, fmt.Errorf("id alphabet contains invalid character %q", c)
+		}
+		if seen[c] {
+			return nil, fmt.Errorf("id alphabet contains duplicate character %q", c)
+		}
+		seen[c] = true
+	}
+	if e.minLength < 0 {
+		e.minLength = 0
+	}
+
+	e.init()
+
+	return e, nil
+}
+
+func (e *IDEncoder) init() {
+	alphabet := append([]byte(nil), e.alphabet...)
+	consistentShuffle(alphabet, e.salt)
+
+	// 第一个字符作为分隔符，之后为补齐长度的填充字符
+	e.separator = alphabet[0]
+	e.alphabet = alphabet[1:]
+}
+
+// ForEntity 返回指定实体使用的编码器，盐值为 salt + ":" + entity
+func (e *IDEncoder) ForEntity(entity string) *IDEncoder {
+	alphabet := make([]byte, 0, len(e.alphabet)+1)
+	alphabet = append(alphabet, e.separator)
+	alphabet = append(alphabet, e.alphabet...)
+
+	derived := &IDEncoder{
+		alphabet:  alphabet,
+		salt:      append(append(append([]byte(nil), e.salt...), ':'), entity...),
+		minLength: e.minLength,
+	}
+	derived.init()
+
+	return derived
+}
+
+// Encode 编码ID
+func (e *IDEncoder) Encode(id uint64) string {
+	size := uint64(len(e.alphabet))
+
+	// 彩票字符决定本次使用的字母表顺序，使相邻ID的编码看起来无关
+	lottery := e.alphabet[id%size]
+	alphabet := e.shuffledAlphabet(lottery)
+
+	var digits []byte
+	for n := id; ; n /= size {
+		digits = append(digits, alphabet[n%size])
+		if n < size {
+			break
+		}
+	}
+
+	out := make([]byte, 0, max(e.minLength, len(digits)+1))
+	out = append(out, lottery)
+	for i := len(digits) - 1; i >= 0; i-- {
+		out = append(out, digits[i])
+	}
+
+	if len(out) < e.minLength {
+		filler := append([]byte(nil), e.alphabet...)
+		consistentShuffle(filler, out)
+
+		out = append(out, e.separator)
+		for i := 0; len(out) < e.minLength; i++ {
+			out = append(out, filler[i%len(filler)])
+		}
+	}
+
+	return string(out)
+}
+
+// Decode 解码ID，非本编码器生成的字符串返回 ErrInvalidEncodedID
+func (e *IDEncoder) Decode(s string) (uint64, error) {
+	body := s
+	for i := 0; i < len(s); i++ {
+		if s[i] == e.separator {
+			body = s[:i]
+			break
+		}
+	}
+	if len(body) < 2 {
+		return 0, ErrInvalidEncodedID
+	}
+
+	alphabet := e.shuffledAlphabet(body[0])
+	index := make(map[byte]uint64, len(alphabet))
+	for i, c := range alphabet {
+		index[c] = uint64(i)
+	}
+
+	size := uint64(len(alphabet))
+	var id uint64
+	for i := 1; i < len(body); i++ {
+		d, ok := index[body[i]]
+		if !ok {
+			return 0, ErrInvalidEncodedID
+		}
+		if id > (math.MaxUint64-d)/size {
+			return 0, ErrInvalidEncodedID
+		}
+		id = id*size + d
+	}
+
+	// 重新编码校验，拒绝彩票字符、前导字符或填充被篡改的字符串
+	if e.Encode(id) != s {
+		return 0, ErrInvalidEncodedID
+	}
+
+	return id, nil
+}
+
+func (e *IDEncoder) shuffledAlphabet(lottery byte) []byte {
+	alphabet := append([]byte(nil), e.alphabet...)
+
+	buffer := make([]byte, 0, 1+len(e.salt)+len(alphabet))
+	buffer = append(buffer, lottery)
+	buffer = append(buffer, e.salt...)
+	buffer = append(buffer, alphabet...)
+
+	consistentShuffle(alphabet, buffer[:len(alphabet)])
+	return alphabet
+}
+
+// consistentShuffle 按盐值确定性地打乱字母表（Hashids 的洗牌算法）
+func consistentShuffle(alphabet, salt []byte) {
+	if len(salt) == 0 {
+		return
+	}
+
+	for i, v, p := len(alphabet)-1, 0, 0; i > 0; i-- {
+		v %= len(salt)
+		p += int(salt[v])
+		j := (int(salt[v]) + v + p) % i
+		alphabet[i], alphabet[j] = alphabet[j], alphabet[i]
+		v++
+	}
+}
@@ -0,0 +1,56 @@
+package id
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIDEncoder(t *testing.T) {
+	e, err := NewIDEncoder("my-salt", WithIDMinLength(8))
+	assert.NoError(t, err)
+
+	seen := make(map[string]bool)
+	for _, id := range []uint64{0, 1, 2, 61, 62, 1000, 123456789, math.MaxUint32, math.MaxUint64} {
+		s := e.Encode(id)
+		assert.GreaterOrEqual(t, len(s), 8)
+		assert.False(t, seen[s])
+		seen[s] = true
+
+		decoded, err := e.Decode(s)
+		assert.NoError(t, err)
+		assert.Equal(t, id, decoded)
+	}
+
+	// 相邻ID的编码不相似
+	assert.NotEqual(t, e.Encode(1)[:2], e.Encode(2)[:2])
+
+	// 不同实体的编码不同，且不能互相解码
+	user, order := e.ForEntity("user"), e.ForEntity("order")
+	assert.NotEqual(t, user.Encode(1000), order.Encode(1000))
+	_, err = order.Decode(user.Encode(1000))
+	assert.ErrorIs(t, err, ErrInvalidEncodedID)
+
+	// 篡改后的字符串
+	s := e.Encode(1000)
+	_, err = e.Decode(s[:len(s)-1] + "!")
+	assert.ErrorIs(t, err, ErrInvalidEncodedID)
+	_, err = e.Decode("")
+	assert.ErrorIs(t, err, ErrInvalidEncodedID)
+}
+
+func TestIDEncoderAlphabet(t *testing.T) {
+	e, err := NewIDEncoder("", WithIDAlphabet("0123456789abcdef"))
+	assert.NoError(t, err)
+	s := e.Encode(42)
+	assert.Regexp(t, `^[0-9a-f]+$`, s)
+	decoded, err := e.Decode(s)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), decoded)
+
+	_, err = NewIDEncoder("", WithIDAlphabet("abc"))
+	assert.Error(t, err)
+	_, err = NewIDEncoder("", WithIDAlphabet("aabcdefghijklmnopq"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package id
+
+import (
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/sony/sonyflake"
+)
+
+// IDLayout 雪花类ID的位布局，用于解析ID以及按时间构造ID范围
+type IDLayout struct {
+	Epoch        time.Time     // 起始时间
+	TimeUnit     time.Duration // 时间戳单位
+	TimeBits     uint          // 时间戳位数
+	WorkerBits   uint          // 工作节点/机器ID位数
+	SequenceBits uint          // 序列号位数
+
+	// WorkerLow 工作节点ID是否位于最低位：
+	// Snowflake 为 时间戳|工作节点ID|序列号，Sonyflake 为 时间戳|序列号|机器ID
+	WorkerLow bool
+}
+
+// IDParts 解析后的ID
+type IDParts struct {
+	Time     time.Time // 生成时间，精度为 TimeUnit
+	WorkerID int64     // 工作节点/机器ID
+	Sequence int64     // 序列号
+}
+
+var (
+	// SnowflakeLayout Snowflake 的位布局：41 位毫秒时间戳 + 10 位工作节点ID + 12 位序列号
+	SnowflakeLayout = IDLayout{
+		Epoch:        time.UnixMilli(snowflake.Epoch).UTC(),
+		TimeUnit:     time.Millisecond,
+		TimeBits:     41,
+		WorkerBits:   10,
+		SequenceBits: 12,
+	}
+
+	// SonyflakeLayout Sonyflake 的位布局：39 位 10 毫秒时间戳 + 8 位序列号 + 16 位机器ID
+	SonyflakeLayout = IDLayout{
+		Epoch:        time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC),
+		TimeUnit:     10 * time.Millisecond,
+		TimeBits:     sonyflake.BitLenTime,
+		WorkerBits:   sonyflake.BitLenMachineID,
+		SequenceBits: sonyflake.BitLenSequence,
+		WorkerLow:    true,
+	}
+)
+
+// WithEpoch 返回使用自定义起始时间的布局
+func (l IDLayout) WithEpoch(epoch time.Time) IDLayout {
+	l.Epoch = epoch
+	return l
+}
+
+func (l IDLayout) workerShift() uint {
+	if l.WorkerLow {
+		return 0
+	}
+	return l.SequenceBits
+}
+
+func (l IDLayout) sequenceShift() uint {
+	if l.WorkerLow {
+		return l.WorkerBits
+	}
+	return 0
+}
+
+func (l IDLayout) timeShift() uint {
+	return l.WorkerBits + l.SequenceBits
+}
+
+// Decode 解析ID
+func (l IDLayout) Decode(id uint64) IDParts {
+	ticks := id >> l.timeShift()
+	return IDParts{
+		Time:     l.Epoch.Add(time.Duration(ticks) * l.TimeUnit),
+		WorkerID: int64(id >> l.workerShift() & (1<<l.WorkerBits - 1)),
+		Sequence: int64(id >> l.sequenceShift() & (1<<l.SequenceBits - 1)),
+	}
+}
+
+// Compose 按布局构造ID，超出位数的部分被截断
+func (l IDLayout) Compose(t time.Time, workerID, sequence int64) uint64 {
+	return l.ticks(t)<<l.timeShift() |
+		uint64(workerID)&(1<<l.WorkerBits-1)<<l.workerShift() |
+		uint64(sequence)&(1<<l.SequenceBits-1)<<l.sequenceShift()
+}
+
+// ticks 返回 t 对应的时间戳，早于起始时间时为 0，超出位数时为最大值
+func (l IDLayout) ticks(t time.Time) uint64 {
+	if t.Before(l.Epoch) {
+		return 0
+	}
+	ticks := uint64(t.Sub(l.Epoch) / l.TimeUnit)
+	if maxTicks := uint64(1)<<l.TimeBits - 1; ticks > maxTicks {
+		return maxTicks
+	}
+	return ticks
+}
+
+// MinID 返回 t 所在时间单位内可能生成的最小ID
+func (l IDLayout) MinID(t time.Time) uint64 {
+	return l.ticks(t) << l.timeShift()
+}
+
+// MaxID 返回 t 所在时间单位内可能生成的最大ID
+func (l IDLayout) MaxID(t time.Time) uint64 {
+	return l.MinID(t) | (1<<l.timeShift() - 1)
+}
+
+// Range 返回 [from, to) 时间范围对应的ID范围 [min, max)，可以代替 created_at 的范围查询：
+//
+//	min, max := id.SnowflakeLayout.Range(from, to)
+//	WHERE id >= min AND id < max
+func (l IDLayout) Range(from, to time.Time) (uint64, uint64) {
+	return l.MinID(from), l.MinID(to)
+}
+
+// DecodeSnowflakeID 解析雪花ID
+func DecodeSnowflakeID(id int64) IDParts {
+	return SnowflakeLayout.Decode(uint64(id))
+}
+
+// DecodeSonyflakeID 解析索尼雪花ID
+func DecodeSonyflakeID(id uint64) IDParts {
+	return SonyflakeLayout.Decode(id)
+}
+
+// SnowflakeIDRange 返回 [from, to) 时间范围对应的雪花ID范围 [min, max)
+func SnowflakeIDRange(from, to time.Time) (int64, int64) {
+	minID, maxID := SnowflakeLayout.Range(from, to)
+	return int64(minID), int64(maxID)
+}
+
+// SonyflakeIDRange 返回 [from, to) 时间范围对应的索尼雪花ID范围 [min, max)
+func SonyflakeIDRange(from, to time.Time) (uint64, uint64) {
+	return SonyflakeLayout.Range(from, to)
+}
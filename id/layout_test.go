@@ -0,0 +1,60 @@
+package id
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/snowflake"
+	"github.com/sony/sonyflake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSnowflakeID(t *testing.T) {
+	node, err := NewSnowflakeNode(5)
+	assert.NoError(t, err)
+
+	before := time.Now().Truncate(time.Millisecond)
+	id := node.Generate()
+	after := time.Now()
+
+	parts := DecodeSnowflakeID(id)
+	assert.Equal(t, int64(5), parts.WorkerID)
+	assert.Equal(t, snowflake.ParseInt64(id).Step(), parts.Sequence)
+	assert.False(t, parts.Time.Before(before))
+	assert.False(t, parts.Time.After(after))
+	assert.Equal(t, snowflake.ParseInt64(id).Time(), parts.Time.UnixMilli())
+}
+
+func TestDecodeSonyflakeID(t *testing.T) {
+	tm := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC)
+
+	sf, err := sonyflake.New(sonyflake.Settings{
+		MachineID: func() (uint16, error) { return 300, nil },
+	})
+	assert.NoError(t, err)
+	id, err := sonyflake.Compose(sf, tm, 7, 300)
+	assert.NoError(t, err)
+
+	assert.Equal(t, id, SonyflakeLayout.Compose(tm, 300, 7))
+	assert.Equal(t, IDParts{Time: tm, WorkerID: 300, Sequence: 7}, DecodeSonyflakeID(id))
+}
+
+func TestIDLayoutRange(t *testing.T) {
+	tm := time.Date(2025, 6, 4, 12, 0, 0, 0, time.UTC)
+
+	id := int64(SnowflakeLayout.Compose(tm, 1023, 4095))
+	assert.Equal(t, uint64(id), SnowflakeLayout.MaxID(tm))
+	assert.Equal(t, SnowflakeLayout.Compose(tm, 0, 0), SnowflakeLayout.MinID(tm))
+
+	minID, maxID := SnowflakeIDRange(tm, tm.Add(time.Millisecond))
+	assert.GreaterOrEqual(t, id, minID)
+	assert.Less(t, id, maxID)
+
+	minID, _ = SnowflakeIDRange(tm.Add(time.Millisecond), tm.Add(time.Second))
+	assert.Less(t, id, minID)
+
+	// 自定义起始时间
+	layout := SnowflakeLayout.WithEpoch(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, tm, layout.Decode(layout.Compose(tm, 1, 2)).Time)
+	assert.Equal(t, uint64(0), layout.MinID(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
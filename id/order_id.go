@@ -12,11 +12,9 @@ import (
 
 type idCounter uint32
 
+// Increase 返回 0-9999 之间循环递增的序号
 func (c *idCounter) Increase() uint32 {
-	cur := *c
-	atomic.AddUint32((*uint32)(c), 1)
-	atomic.CompareAndSwapUint32((*uint32)(c), 1000, 0)
-	return uint32(cur)
+	return (atomic.AddUint32((*uint32)(c), 1) - 1) % 10000
 }
 
 var orderIdIndex idCounter
@@ -33,7 +31,7 @@ func GenerateOrderIdWithRandom(prefix string, tm *time.Time) string {
 
 	randNum := rand.Intn(10000) // 生成0-9999之间的随机数
 
-	return fmt.Sprintf("%s%s%d", prefix, timestamp, randNum)
+	return fmt.Sprintf("%s%s%04d", prefix, timestamp, randNum)
 }
 
 // GenerateOrderIdWithIncreaseIndex 生成20位订单号，前缀+时间+自增长索引
@@ -46,39 +44,57 @@ func GenerateOrderIdWithIncreaseIndex(prefix string, tm *time.Time) string {
 
 	index := orderIdIndex.Increase()
 
-	return fmt.Sprintf("%s%s%d", prefix, timestamp, index)
+	return fmt.Sprintf("%s%s%04d", prefix, timestamp, index)
 }
 
-// GenerateOrderIdWithTenantId 带商户ID的订单ID生成器：202506041234567890123
+// GenerateOrderIdWithTenantId 带商户ID的订单ID生成器：202506041234567890123。
+// 需要跨节点唯一、校验位或解析时使用 OrderNoBuilder。
 func GenerateOrderIdWithTenantId(tenantID string) string {
-	// 时间戳（14位） + 商户ID（固定 5 位） + 随机数（4位）
+	// 时间戳（14位） + 商户ID（至少 5 位） + 自增序号（4位）
 
 	// 时间戳部分（精确到毫秒）
 	now := time.Now()
 	timestamp := now.Format("20060102150405")
 
-	// 商户ID部分（截取或补零到5位）
+	// 商户ID部分（不足5位时补零，超过5位时保留完整的商户ID，避免不同商户的订单号冲突）
 	tenantPart := tenantID
-	if len(tenantPart) > 5 {
-		tenantPart = tenantPart[:5]
-	} else {
+	if len(tenantPart) < 5 {
 		tenantPart = fmt.Sprintf("%-5s", tenantPart)
 		tenantPart = strings.ReplaceAll(tenantPart, " ", "0")
 	}
 
-	// 随机数部分（4位）
-	n := rand.Int31n(10000)
-	randomPart := fmt.Sprintf("%04d", n)
+	// 序号部分（4位），同一进程内每秒最多 10000 个不重复
+	indexPart := fmt.Sprintf("%04d", orderIdIndex.Increase())
 
-	return timestamp + tenantPart + randomPart
+	return timestamp + tenantPart + indexPart
 }
 
+// GenerateOrderIdWithPrefixSonyflake 生成前缀 + 索尼雪花ID的订单号，失败时返回空字符串，需要处理错误时使用 NewOrderIdWithPrefixSonyflake
 func GenerateOrderIdWithPrefixSonyflake(prefix string) string {
-	id, _ := NewSonyflakeID()
-	return fmt.Sprintf("%s%d", prefix, id)
+	orderID, _ := NewOrderIdWithPrefixSonyflake(prefix)
+	return orderID
 }
 
+// NewOrderIdWithPrefixSonyflake 生成前缀 + 索尼雪花ID的订单号
+func NewOrderIdWithPrefixSonyflake(prefix string) (string, error) {
+	id, err := NewSonyflakeID()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d", prefix, id), nil
+}
+
+// GenerateOrderIdWithPrefixSnowflake 生成前缀 + 雪花ID的订单号，失败时返回空字符串，需要处理错误时使用 NewOrderIdWithPrefixSnowflake
 func GenerateOrderIdWithPrefixSnowflake(workerId int64, prefix string) string {
-	id, _ := NewSnowflakeID(workerId)
-	return fmt.Sprintf("%s%d", prefix, id)
+	orderID, _ := NewOrderIdWithPrefixSnowflake(workerId, prefix)
+	return orderID
+}
+
+// NewOrderIdWithPrefixSnowflake 生成前缀 + 雪花ID的订单号
+func NewOrderIdWithPrefixSnowflake(workerId int64, prefix string) (string, error) {
+	id, err := NewSnowflakeID(workerId)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d", prefix, id), nil
 }
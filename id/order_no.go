@@ -0,0 +1,409 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultOrderNoDateLayout    = "20060102150405" // 默认的订单号日期格式，精确到秒
+	DefaultOrderNoSequenceWidth = 6                // 默认的序号位数
+)
+
+var (
+	ErrInvalidOrderNo          = errors.New("invalid order no")
+	ErrOrderSequenceExhausted  = errors.New("order sequence exhausted")
+	ErrOrderNoTenantIDTooLong  = errors.New("tenant id exceeds tenant width")
+	ErrOrderNoCheckDigitFailed = errors.New("order no check digit mismatch")
+)
+
+// CheckDigit 订单号校验位算法
+type CheckDigit int
+
+const (
+	CheckDigitNone  CheckDigit = iota // 无校验位
+	CheckDigitLuhn                    // Luhn 算法，1 位
+	CheckDigitMod97                   // ISO 7064 MOD 97-10，2 位
+)
+
+func (c CheckDigit) width() int {
+	switch c {
+	case CheckDigitLuhn:
+		return 1
+	case CheckDigitMod97:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// OrderNoTemplate 订单号模板：前缀 + 日期 + 商户段 + 定长序号 + 校验位
+type OrderNoTemplate struct {
+	Prefix        string         // 前缀，例如 PAY
+	DateLayout    string         // 日期格式，必须是定长的数字格式，默认为 20060102150405
+	Location      *time.Location // 时区，默认为 time.Local
+	TenantWidth   int            // 商户段宽度，为 0 时不包含商户段；商户ID不足时左侧补零，超长时返回错误
+	SequenceWidth int            // 序号位数，默认为 6
+	CheckDigit    CheckDigit     // 校验位算法
+}
+
+// OrderNo 解析后的订单号
+type OrderNo struct {
+	Prefix   string    // 前缀
+	Time     time.Time // 日期段对应的时间
+	TenantID string    // 商户段，包含补齐的零
+	Sequence int64     // 序号
+}
+
+// OrderSequence 订单号序号生成器。
+// key 为订单号中序号之前的部分（前缀 + 日期 + 商户段），同一 key 下返回从 1 开始递增的序号，
+// ttl 为 key 的有效期，过期后可以清理。
+type OrderSequence interface {
+	Next(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// OrderNoBuilder 按模板生成、解析和校验订单号
+type OrderNoBuilder struct {
+	tmpl      OrderNoTemplate
+	seq       OrderSequence
+	dateWidth int
+	window    time.Duration
+	maxSeq    int64
+	clock     *ClockGuard
+}
+
+// NewOrderNoBuilder 创建订单号生成器，seq 为空时使用进程内的计数器，多节点部署时需要使用 Redis 或数据库序号保证唯一
+func NewOrderNoBuilder(tmpl OrderNoTemplate, seq OrderSequence) (*OrderNoBuilder, error) {
+	if tmpl.DateLayout == "" {
+		tmpl.DateLayout = DefaultOrderNoDateLayout
+	}
+	if tmpl.Location == nil {
+		tmpl.Location = time.Local
+	}
+	if tmpl.SequenceWidth <= 0 {
+		tmpl.SequenceWidth = DefaultOrderNoSequenceWidth
+	}
+	if tmpl.SequenceWidth > 18 {
+		return nil, fmt.Errorf("sequence width %d exceeds 18", tmpl.SequenceWidth)
+	}
+	if tmpl.TenantWidth < 0 {
+		return nil, fmt.Errorf("invalid tenant width %d", tmpl.TenantWidth)
+	}
+	if !isAlphanumeric(tmpl.Prefix) {
+		return nil, fmt.Errorf("order no prefix %q must be alphanumeric", tmpl.Prefix)
+	}
+
+	// 日期格式必须是定长的数字，才能按位置解析
+	sample1 := time.Date(2001, 1, 1, 1, 1, 1, 0, time.UTC).Format(tmpl.DateLayout)
+	sample2 := time.Date(2099, 12, 31, 23, 59, 59, 0, time.UTC).Format(tmpl.DateLayout)
+	if len(sample1) != len(sample2) || !isDigits(sample1) || !isDigits(sample2) {
+		return nil, fmt.Errorf("date layout %q must be fixed-width digits", tmpl.DateLayout)
+	}
+
+	if seq == nil {
+		seq = NewMemoryOrderSequence()
+	}
+
+	maxSeq := int64(1)
+	for i := 0; i < tmpl.SequenceWidth; i++ {
+		maxSeq *= 10
+	}
+
+	return &OrderNoBuilder{
+		tmpl:      tmpl,
+		seq:       seq,
+		dateWidth: len(sample1),
+		window:    dateLayoutWindow(tmpl.DateLayout),
+		maxSeq:    maxSeq - 1,
+		clock:     NewClockGuard(DefaultMaxClockRollbackWait),
+	}, nil
+}
+
+// Template 返回订单号模板
+func (b *OrderNoBuilder) Template() OrderNoTemplate {
+	return b.tmpl
+}
+
+// ClockStats 返回时钟回拨统计
+func (b *OrderNoBuilder) ClockStats() ClockGuardStats {
+	return b.clock.Stats()
+}
+
+// Next 生成订单号，同一日期段内序号超过位数时返回 ErrOrderSequenceExhausted。
+// 时钟回拨不超过 DefaultMaxClockRollbackWait 时等待时钟追上，超过时返回 ErrClockRollback，
+// 避免回到已过期的日期段重新从 1 开始计数而生成重复的订单号。
+func (b *OrderNoBuilder) Next(ctx context.Context, tenantID string) (string, error) {
+	tenant, err := b.tenantSegment(tenantID)
+	if err != nil {
+		return "", err
+	}
+
+	now, err := b.clock.Now()
+	if err != nil {
+		return "", err
+	}
+	key := b.tmpl.Prefix + now.In(b.tmpl.Location).Format(b.tmpl.DateLayout) + tenant
+
+	seq, err := b.seq.Next(ctx, key, b.window*2)
+	if err != nil {
+		return "", err
+	}
+	if seq <= 0 || seq > b.maxSeq {
+		return "", fmt.Errorf("%w: %s reached %d", ErrOrderSequenceExhausted, key, seq)
+	}
+
+	body := key + fmt.Sprintf("%0*d", b.tmpl.SequenceWidth, seq)
+
+	check, err := computeCheckDigit(b.tmpl.CheckDigit, body)
+	if err != nil {
+		return "", err
+	}
+
+	return body + check, nil
+}
+
+// Parse 按模板解析订单号，并校验格式和校验位
+func (b *OrderNoBuilder) Parse(orderNo string) (*OrderNo, error) {
+	width := len(b.tmpl.Prefix) + b.dateWidth + b.tmpl.TenantWidth + b.tmpl.SequenceWidth + b.tmpl.CheckDigit.width()
+	if len(orderNo) != width {
+		return nil, fmt.Errorf("%w: length %d, expect %d", ErrInvalidOrderNo, len(orderNo), width)
+	}
+	if !strings.HasPrefix(orderNo, b.tmpl.Prefix) {
+		return nil, fmt.Errorf("%w: prefix mismatch", ErrInvalidOrderNo)
+	}
+
+	bodyLen := width - b.tmpl.CheckDigit.width()
+	body := orderNo[:bodyLen]
+
+	check, err := computeCheckDigit(b.tmpl.CheckDigit, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOrderNo, err)
+	}
+	if check != orderNo[bodyLen:] {
+		return nil, ErrOrderNoCheckDigitFailed
+	}
+
+	pos := len(b.tmpl.Prefix)
+	datePart := body[pos : pos+b.dateWidth]
+	pos += b.dateWidth
+	tenantPart := body[pos : pos+b.tmpl.TenantWidth]
+	pos += b.tmpl.TenantWidth
+	seqPart := body[pos:]
+
+	tm, err := time.ParseInLocation(b.tmpl.DateLayout, datePart, b.tmpl.Location)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOrderNo, err)
+	}
+	if !isAlphanumeric(tenantPart) {
+		return nil, fmt.Errorf("%w: invalid tenant segment", ErrInvalidOrderNo)
+	}
+	if !isDigits(seqPart) {
+		return nil, fmt.Errorf("%w: invalid sequence segment", ErrInvalidOrderNo)
+	}
+	seq, err := strconv.ParseInt(seqPart, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOrderNo, err)
+	}
+
+	return &OrderNo{
+		Prefix:   b.tmpl.Prefix,
+		Time:     tm,
+		TenantID: tenantPart,
+		Sequence: seq,
+	}, nil
+}
+
+// Validate 校验订单号是否符合模板
+func (b *OrderNoBuilder) Validate(orderNo string) error {
+	_, err := b.Parse(orderNo)
+	return err
+}
+
+func (b *OrderNoBuilder) tenantSegment(tenantID string) (string, error) {
+	if b.tmpl.TenantWidth == 0 {
+		return "", nil
+	}
+	if !isAlphanumeric(tenantID) {
+		return "", fmt.Errorf("tenant id %q must be alphanumeric", tenantID)
+	}
+	if len(tenantID) > b.tmpl.TenantWidth {
+		return "", fmt.Errorf("%w: %q exceeds %d", ErrOrderNoTenantIDTooLong, tenantID, b.tmpl.TenantWidth)
+	}
+	return strings.Repeat("0", b.tmpl.TenantWidth-len(tenantID)) + tenantID, nil
+}
+
+// dateLayoutWindow 返回日期格式的最小时间单位，同一单位内的订单号共用一个序号
+func dateLayoutWindow(layout string) time.Duration {
+	switch {
+	case strings.Contains(layout, "05"):
+		return time.Second
+	case strings.Contains(layout, "04"):
+		return time.Minute
+	case strings.Contains(layout, "15"):
+		return time.Hour
+	case strings.Contains(layout, "02"):
+		return 24 * time.Hour
+	default:
+		return 31 * 24 * time.Hour
+	}
+}
+
+// computeCheckDigit 计算校验位，字母按 A=10 ... Z=35 转换为数字（与 IBAN 相同）
+func computeCheckDigit(algorithm CheckDigit, body string) (string, error) {
+	if algorithm == CheckDigitNone {
+		return "", nil
+	}
+
+	digits, err := checkDigitNumber(body)
+	if err != nil {
+		return "", err
+	}
+
+	switch algorithm {
+	case CheckDigitLuhn:
+		return strconv.Itoa(LuhnCheckDigit(digits)), nil
+	case CheckDigitMod97:
+		return fmt.Sprintf("%02d", Mod97CheckDigits(digits)), nil
+	default:
+		return "", fmt.Errorf("unknown check digit algorithm %d", algorithm)
+	}
+}
+
+func checkDigitNumber(s string) (string, error) {
+	var sb strings.Builder
+	for _, c := range strings.ToUpper(s) {
+		switch {
+		case c >= '0' && c <= '9':
+			sb.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			sb.WriteString(strconv.Itoa(int(c-'A') + 10))
+		default:
+			return "", fmt.Errorf("invalid character %q", c)
+		}
+	}
+	return sb.String(), nil
+}
+
+// LuhnCheckDigit 计算数字串的 Luhn 校验位
+func LuhnCheckDigit(digits string) int {
+	sum := 0
+	double := true
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
+
+// Mod97CheckDigits 计算数字串的 ISO 7064 MOD 97-10 校验位（两位）
+func Mod97CheckDigits(digits string) int {
+	n, _ := new(big.Int).SetString(digits+"00", 10)
+	if n == nil {
+		return 0
+	}
+	return 98 - int(new(big.Int).Mod(n, big.NewInt(97)).Int64())
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// MemoryOrderSequence 进程内的订单号序号，只能保证单个进程内唯一
+type MemoryOrderSequence struct {
+	mu       sync.Mutex
+	counters map[string]*memoryOrderCounter
+}
+
+type memoryOrderCounter struct {
+	value    int64
+	expireAt time.Time
+}
+
+// NewMemoryOrderSequence 创建进程内的订单号序号
+func NewMemoryOrderSequence() *MemoryOrderSequence {
+	return &MemoryOrderSequence{counters: make(map[string]*memoryOrderCounter)}
+}
+
+// Next 返回 key 的下一个序号
+func (s *MemoryOrderSequence) Next(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	c, ok := s.counters[key]
+	if !ok {
+		// 新的时间窗口开始时清理过期的计数器
+		for k, v := range s.counters {
+			if now.After(v.expireAt) {
+				delete(s.counters, k)
+			}
+		}
+		c = &memoryOrderCounter{expireAt: now.Add(ttl)}
+		s.counters[key] = c
+	}
+
+	c.value++
+	return c.value, nil
+}
+
+// RedisIncrClient 订单号序号使用的 Redis 命令，可以用 go-redis 等客户端适配
+type RedisIncrClient interface {
+	Incr(ctx context.Context, key string) (int64, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// RedisOrderSequence 基于 Redis INCR 的订单号序号，多节点共享
+type RedisOrderSequence struct {
+	client    RedisIncrClient
+	keyPrefix string
+}
+
+// NewRedisOrderSequence 创建 Redis 订单号序号，keyPrefix 为空时使用 orderseq:
+func NewRedisOrderSequence(client RedisIncrClient, keyPrefix string) *RedisOrderSequence {
+	if keyPrefix == "" {
+		keyPrefix = "orderseq:"
+	}
+	return &RedisOrderSequence{client: client, keyPrefix: keyPrefix}
+}
+
+// Next 返回 key 的下一个序号，首次使用 key 时设置过期时间
+func (s *RedisOrderSequence) Next(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	value, err := s.client.Incr(ctx, s.keyPrefix+key)
+	if err != nil {
+		return 0, err
+	}
+	if value == 1 {
+		if err = s.client.Expire(ctx, s.keyPrefix+key, ttl); err != nil {
+			return 0, err
+		}
+	}
+	return value, nil
+}
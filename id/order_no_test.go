@@ -0,0 +1,156 @@
+package id
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderNoBuilder(t *testing.T) {
+	ctx := context.Background()
+	tm := time.Date(2025, 6, 4, 12, 34, 56, 0, time.UTC)
+
+	b, err := NewOrderNoBuilder(OrderNoTemplate{
+		Prefix:        "PAY",
+		Location:      time.UTC,
+		TenantWidth:   6,
+		SequenceWidth: 4,
+		CheckDigit:    CheckDigitLuhn,
+	}, nil)
+	assert.NoError(t, err)
+	b.clock.now = func() time.Time { return tm }
+
+	orderNo, err := b.Next(ctx, "M9876")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^PAY202506041234560M98760001\d$`, orderNo)
+
+	parsed, err := b.Parse(orderNo)
+	assert.NoError(t, err)
+	assert.Equal(t, &OrderNo{Prefix: "PAY", Time: tm, TenantID: "0M9876", Sequence: 1}, parsed)
+
+	// 校验位错误
+	wrong := orderNo[:len(orderNo)-1] + string('0'+(orderNo[len(orderNo)-1]-'0'+1)%10)
+	assert.ErrorIs(t, b.Validate(wrong), ErrOrderNoCheckDigitFailed)
+	assert.ErrorIs(t, b.Validate(orderNo[1:]), ErrInvalidOrderNo)
+
+	// 商户ID不截断
+	_, err = b.Next(ctx, "M987654")
+	assert.ErrorIs(t, err, ErrOrderNoTenantIDTooLong)
+
+	// 不同商户的序号独立
+	orderNo, err = b.Next(ctx, "M1")
+	assert.NoError(t, err)
+	parsed, err = b.Parse(orderNo)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), parsed.Sequence)
+}
+
+func TestOrderNoBuilderSequence(t *testing.T) {
+	ctx := context.Background()
+
+	b, err := NewOrderNoBuilder(OrderNoTemplate{SequenceWidth: 3, CheckDigit: CheckDigitMod97}, nil)
+	assert.NoError(t, err)
+	b.clock.now = func() time.Time { return time.Date(2025, 6, 4, 12, 34, 56, 0, time.Local) }
+
+	var wg sync.WaitGroup
+	var ids sync.Map
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 99; j++ {
+				orderNo, err := b.Next(ctx, "")
+				assert.NoError(t, err)
+				assert.Len(t, orderNo, 14+3+2)
+				assert.NoError(t, b.Validate(orderNo))
+				ids.Store(orderNo, true)
+			}
+		}()
+	}
+	wg.Wait()
+
+	count := 0
+	ids.Range(func(k, v any) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 990, count)
+
+	// 同一秒内超过 999 个
+	for i := 0; i < 9; i++ {
+		_, err = b.Next(ctx, "")
+		assert.NoError(t, err)
+	}
+	_, err = b.Next(ctx, "")
+	assert.ErrorIs(t, err, ErrOrderSequenceExhausted)
+}
+
+func TestOrderNoBuilderClockRollback(t *testing.T) {
+	ctx := context.Background()
+	tm := time.Date(2025, 6, 4, 12, 34, 56, 0, time.UTC)
+
+	b, err := NewOrderNoBuilder(OrderNoTemplate{Location: time.UTC}, nil)
+	assert.NoError(t, err)
+	b.clock.now = func() time.Time { return tm }
+	b.clock.sleep = func(d time.Duration) { tm = tm.Add(d) }
+
+	orderNo, err := b.Next(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "20250604123456000001", orderNo)
+
+	// 小幅回拨时等待时钟追上，继续使用同一个序号
+	tm = tm.Add(-5 * time.Millisecond)
+	orderNo, err = b.Next(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "20250604123456000002", orderNo)
+
+	// 回拨超过两个日期段时拒绝生成，不会回到已过期的序号
+	tm = tm.Add(-3 * time.Second)
+	_, err = b.Next(ctx, "")
+	assert.ErrorIs(t, err, ErrClockRollback)
+	assert.Equal(t, ClockGuardStats{Waits: 1, Exhausted: 1}, b.ClockStats())
+}
+
+func TestCheckDigits(t *testing.T) {
+	assert.Equal(t, 3, LuhnCheckDigit("7992739871"))
+	// IBAN 示例：GB82 WEST 1234 5698 7654 32
+	digits, err := checkDigitNumber("WEST12345698765432GB")
+	assert.NoError(t, err)
+	assert.Equal(t, 82, Mod97CheckDigits(digits))
+
+	_, err = NewOrderNoBuilder(OrderNoTemplate{DateLayout: "Jan 2006"}, nil)
+	assert.Error(t, err)
+}
+
+type fakeRedisIncr struct {
+	values map[string]int64
+	ttls   map[string]time.Duration
+}
+
+func (r *fakeRedisIncr) Incr(_ context.Context, key string) (int64, error) {
+	r.values[key]++
+	return r.values[key], nil
+}
+
+func (r *fakeRedisIncr) Expire(_ context.Context, key string, ttl time.Duration) error {
+	r.ttls[key] = ttl
+	return nil
+}
+
+func TestRedisOrderSequence(t *testing.T) {
+	client := &fakeRedisIncr{values: map[string]int64{}, ttls: map[string]time.Duration{}}
+
+	b, err := NewOrderNoBuilder(OrderNoTemplate{Prefix: "PT"}, NewRedisOrderSequence(client, ""))
+	assert.NoError(t, err)
+
+	orderNo, err := b.Next(context.Background(), "")
+	assert.NoError(t, err)
+
+	parsed, err := b.Parse(orderNo)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), parsed.Sequence)
+	assert.Equal(t, 2*time.Second, client.ttls["orderseq:"+orderNo[:16]])
+}
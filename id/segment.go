@@ -0,0 +1,262 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultSegmentStep     int64 = 1000             // 默认的初始号段大小
+	DefaultSegmentMinStep  int64 = 1000             // 默认的最小号段大小
+	DefaultSegmentMaxStep  int64 = 1000000          // 默认的最大号段大小
+	DefaultSegmentDuration       = 15 * time.Minute // 默认的号段期望消耗时长
+	DefaultSegmentPrefetch       = 0.1              // 当前号段剩余不足 10% 时预取下一个号段
+)
+
+var ErrInvalidSegment = errors.New("invalid id segment")
+
+// Segment 号段，可分配的ID范围为 [Start, End)
+type Segment struct {
+	Start int64
+	End   int64
+}
+
+// Size 号段大小
+func (s Segment) Size() int64 {
+	return s.End - s.Start
+}
+
+// SegmentStore 号段存储，Reserve 为业务标识 bizTag 预留 step 个连续的ID，多个节点之间不能重叠
+type SegmentStore interface {
+	Reserve(ctx context.Context, bizTag string, step int64) (Segment, error)
+}
+
+// SegmentAllocator 号段模式的ID分配器（类似美团 Leaf-segment）。
+// 从存储中批量预留号段，在内存中分配ID；当前号段消耗到一定比例时在后台预取下一个号段（双缓冲），
+// 并根据号段的消耗速度调整号段大小：消耗时长小于 duration 时翻倍，超过 2*duration 时减半。
+type SegmentAllocator struct {
+	store  SegmentStore
+	bizTag string
+
+	step     int64
+	minStep  int64
+	maxStep  int64
+	duration time.Duration
+	prefetch float64
+	timeout  time.Duration
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	current     Segment
+	cursor      int64
+	next        *Segment
+	loading     bool
+	loadErr     error
+	lastReserve time.Time
+}
+
+// SegmentOption SegmentAllocator 的配置项
+type SegmentOption func(a *SegmentAllocator)
+
+// WithSegmentStep 设置初始号段大小
+func WithSegmentStep(step int64) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.step = step
+	}
+}
+
+// WithSegmentStepRange 设置号段大小的调整范围
+func WithSegmentStepRange(minStep, maxStep int64) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.minStep = minStep
+		a.maxStep = maxStep
+	}
+}
+
+// WithSegmentDuration 设置号段的期望消耗时长
+func WithSegmentDuration(duration time.Duration) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.duration = duration
+	}
+}
+
+// WithSegmentPrefetch 设置预取比例，当前号段剩余不足该比例时预取下一个号段
+func WithSegmentPrefetch(ratio float64) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.prefetch = ratio
+	}
+}
+
+// WithSegmentTimeout 设置后台预取号段的超时时间
+func WithSegmentTimeout(timeout time.Duration) SegmentOption {
+	return func(a *SegmentAllocator) {
+		a.timeout = timeout
+	}
+}
+
+// NewSegmentAllocator 创建号段ID分配器
+func NewSegmentAllocator(store SegmentStore, bizTag string, opts ...SegmentOption) *SegmentAllocator {
+	a := &SegmentAllocator{
+		store:    store,
+		bizTag:   bizTag,
+		step:     DefaultSegmentStep,
+		minStep:  DefaultSegmentMinStep,
+		maxStep:  DefaultSegmentMaxStep,
+		duration: DefaultSegmentDuration,
+		prefetch: DefaultSegmentPrefetch,
+		timeout:  5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.minStep <= 0 {
+		a.minStep = 1
+	}
+	if a.maxStep < a.minStep {
+		a.maxStep = a.minStep
+	}
+	a.step = min(max(a.step, a.minStep), a.maxStep)
+
+	a.cond = sync.NewCond(&a.mu)
+
+	return a
+}
+
+// BizTag 返回业务标识
+func (a *SegmentAllocator) BizTag() string {
+	return a.bizTag
+}
+
+// Step 返回当前的号段大小
+func (a *SegmentAllocator) Step() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.step
+}
+
+// NextID 分配一个ID
+func (a *SegmentAllocator) NextID(ctx context.Context) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.cursor >= a.current.End {
+		switch {
+		case a.next != nil:
+			a.current, a.next = *a.next, nil
+			a.cursor = a.current.Start
+
+		case a.loading:
+			// 后台正在预取，等待完成
+			a.cond.Wait()
+
+		default:
+			// 首次分配或后台预取失败，同步加载
+			a.loading = true
+			a.mu.Unlock()
+			segment, err := a.reserve(ctx)
+			a.mu.Lock()
+			a.loaded(segment, err)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	id := a.cursor
+	a.cursor++
+
+	if a.next == nil && !a.loading && a.loadErr == nil && a.shouldPrefetch() {
+		a.loading = true
+		go a.prefetchNext()
+	}
+
+	return id, nil
+}
+
+func (a *SegmentAllocator) shouldPrefetch() bool {
+	remaining := a.current.End - a.cursor
+	return float64(remaining) < float64(a.current.Size())*a.prefetch
+}
+
+func (a *SegmentAllocator) prefetchNext() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+
+	segment, err := a.reserve(ctx)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.loaded(segment, err)
+}
+
+// loaded 记录号段加载结果，需要持有锁
+func (a *SegmentAllocator) loaded(segment Segment, err error) {
+	a.loading = false
+	a.loadErr = err
+	if err == nil {
+		if a.cursor >= a.current.End {
+			a.current, a.cursor = segment, segment.Start
+		} else {
+			a.next = &segment
+		}
+	}
+	a.cond.Broadcast()
+}
+
+// reserve 从存储中预留号段，并根据距上次预留的时长调整下一次的号段大小
+func (a *SegmentAllocator) reserve(ctx context.Context) (Segment, error) {
+	a.mu.Lock()
+	step := a.step
+	a.mu.Unlock()
+
+	segment, err := a.store.Reserve(ctx, a.bizTag, step)
+	if err != nil {
+		return Segment{}, err
+	}
+	if segment.Size() <= 0 {
+		return Segment{}, fmt.Errorf("%w: [%d, %d)", ErrInvalidSegment, segment.Start, segment.End)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if !a.lastReserve.IsZero() {
+		elapsed := now.Sub(a.lastReserve)
+		switch {
+		case elapsed < a.duration:
+			a.step = min(a.step*2, a.maxStep)
+		case elapsed > 2*a.duration:
+			a.step = max(a.step/2, a.minStep)
+		}
+	}
+	a.lastReserve = now
+
+	return segment, nil
+}
+
+// MemorySegmentStore 进程内的号段存储，用于测试或单机部署
+type MemorySegmentStore struct {
+	mu     sync.Mutex
+	maxIDs map[string]int64
+}
+
+// NewMemorySegmentStore 创建进程内的号段存储
+func NewMemorySegmentStore() *MemorySegmentStore {
+	return &MemorySegmentStore{maxIDs: make(map[string]int64)}
+}
+
+// Reserve 预留号段，ID从 1 开始
+func (s *MemorySegmentStore) Reserve(_ context.Context, bizTag string, step int64) (Segment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := s.maxIDs[bizTag] + 1
+	s.maxIDs[bizTag] += step
+
+	return Segment{Start: start, End: s.maxIDs[bizTag] + 1}, nil
+}
@@ -0,0 +1,91 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type countingSegmentStore struct {
+	*MemorySegmentStore
+	reserves atomic.Int32
+	fail     atomic.Bool
+}
+
+func (s *countingSegmentStore) Reserve(ctx context.Context, bizTag string, step int64) (Segment, error) {
+	s.reserves.Add(1)
+	if s.fail.Load() {
+		return Segment{}, errors.New("db unavailable")
+	}
+	return s.MemorySegmentStore.Reserve(ctx, bizTag, step)
+}
+
+func TestSegmentAllocator(t *testing.T) {
+	ctx := context.Background()
+	store := &countingSegmentStore{MemorySegmentStore: NewMemorySegmentStore()}
+	a := NewSegmentAllocator(store, "order", WithSegmentStep(10), WithSegmentStepRange(10, 80))
+
+	var wg sync.WaitGroup
+	var ids sync.Map
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id, err := a.NextID(ctx)
+				assert.NoError(t, err)
+				_, loaded := ids.LoadOrStore(id, true)
+				assert.False(t, loaded, "重复的ID: %d", id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 所有ID在 [1, 1000] 之间
+	for id := int64(1); id <= 1000; id++ {
+		_, ok := ids.Load(id)
+		if !ok {
+			t.Fatalf("缺少ID: %d", id)
+		}
+	}
+
+	// 消耗很快，号段大小增长到上限
+	assert.Equal(t, int64(80), a.Step())
+	assert.Less(t, store.reserves.Load(), int32(100))
+}
+
+func TestSegmentAllocatorStepShrink(t *testing.T) {
+	a := NewSegmentAllocator(NewMemorySegmentStore(), "order",
+		WithSegmentStep(100), WithSegmentStepRange(10, 1000), WithSegmentDuration(time.Millisecond))
+
+	_, err := a.NextID(context.Background())
+	assert.NoError(t, err)
+
+	a.mu.Lock()
+	a.lastReserve = time.Now().Add(-time.Second)
+	a.mu.Unlock()
+
+	_, err = a.reserve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), a.Step())
+}
+
+func TestSegmentAllocatorError(t *testing.T) {
+	ctx := context.Background()
+	store := &countingSegmentStore{MemorySegmentStore: NewMemorySegmentStore()}
+	store.fail.Store(true)
+	a := NewSegmentAllocator(store, "order", WithSegmentStep(10), WithSegmentStepRange(10, 10))
+
+	_, err := a.NextID(ctx)
+	assert.Error(t, err)
+
+	store.fail.Store(false)
+	id, err := a.NextID(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+}
@@ -30,6 +30,14 @@ func (sfNode *SnowflakeNode) Generate() int64 {
 	return sfNode.node.Generate().Int64()
 }
 
+// NextID 生成ID，不会返回 0。时间戳基于单调时钟，进程内的时钟回拨不会产生重复ID
+func (sfNode *SnowflakeNode) NextID() (int64, error) {
+	if sfNode.node == nil {
+		return 0, errors.New("snowflake node is nil")
+	}
+	return sfNode.Generate(), nil
+}
+
 func (sfNode *SnowflakeNode) GenerateString() string {
 	sfNode.Lock()
 	defer sfNode.Unlock()
@@ -57,10 +65,41 @@ func NewSnowflakeID(workerId int64) (int64, error) {
 		return 0, errors.New("snowflake node is nil")
 	}
 
-	return node.Generate(), nil
+	return node.NextID()
 }
 
+// GenerateSnowflakeID 生成雪花ID，失败时返回 0
+//
+// Deprecated: 使用 NewSnowflakeID，需要处理 workerId 超出范围等错误
 func GenerateSnowflakeID(workerId int64) int64 {
 	id, _ := NewSnowflakeID(workerId)
 	return id
 }
+
+// LeasedSnowflakeNode 使用租约分配的工作节点ID生成雪花ID，租约丢失后停止生成
+type LeasedSnowflakeNode struct {
+	lease *WorkerLease
+	node  *SnowflakeNode
+}
+
+// NewLeasedSnowflakeNode 使用租约创建雪花节点，租约需通过 AcquireWorkerLease(ctx, allocator, MaxSnowflakeWorkerID, ttl) 获取
+func NewLeasedSnowflakeNode(lease *WorkerLease) (*LeasedSnowflakeNode, error) {
+	node, err := NewSnowflakeNode(lease.WorkerID())
+	if err != nil {
+		return nil, err
+	}
+	return &LeasedSnowflakeNode{lease: lease, node: node}, nil
+}
+
+// NextID 生成ID，租约无效时返回 ErrWorkerLeaseLost
+func (n *LeasedSnowflakeNode) NextID() (int64, error) {
+	if err := n.lease.Err(); err != nil {
+		return 0, err
+	}
+	return n.node.NextID()
+}
+
+// Lease 返回节点使用的租约
+func (n *LeasedSnowflakeNode) Lease() *WorkerLease {
+	return n.lease
+}
@@ -1,30 +1,97 @@
 package id
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/sony/sonyflake"
 )
 
 var (
-	sf   *sonyflake.Sonyflake
-	sfMu sync.Mutex
+	sf      *sonyflake.Sonyflake
+	sfLease *WorkerLease
+	sfMu    sync.Mutex
 )
 
+// NewSonyflakeID 生成索尼雪花ID，成功时不会返回 0。时钟回拨时经过时间保持递增，不会产生重复ID
 func NewSonyflakeID() (uint64, error) {
-	// 64 位 ID = 39 位时间戳 + 8 位机器 ID + 16 位序列号
+	// 64 位 ID = 39 位时间戳 + 8 位序列号 + 16 位机器 ID
 
 	sfMu.Lock()
 	defer sfMu.Unlock()
 
+	if sfLease != nil {
+		if err := sfLease.Err(); err != nil {
+			return 0, err
+		}
+	}
+
 	if sf == nil {
-		sf = sonyflake.NewSonyflake(sonyflake.Settings{})
+		var err error
+		if sf, err = sonyflake.New(sonyflake.Settings{}); err != nil {
+			sf = nil
+			return 0, err
+		}
 	}
 
 	return sf.NextID()
 }
 
+// GenerateSonyflakeID 生成索尼雪花ID，失败时返回 0，需要处理错误时使用 NewSonyflakeID
 func GenerateSonyflakeID() uint64 {
 	id, _ := NewSonyflakeID()
 	return id
 }
+
+// SetSonyflakeLease 使用租约分配的机器ID替换默认的私有IP机器ID，NewSonyflakeID 在租约丢失后返回错误
+func SetSonyflakeLease(lease *WorkerLease) error {
+	node, err := NewLeasedSonyflake(lease)
+	if err != nil {
+		return err
+	}
+
+	sfMu.Lock()
+	defer sfMu.Unlock()
+
+	sf = node.sf
+	sfLease = lease
+
+	return nil
+}
+
+// LeasedSonyflake 使用租约分配的机器ID生成索尼雪花ID，租约丢失后停止生成
+type LeasedSonyflake struct {
+	lease *WorkerLease
+	sf    *sonyflake.Sonyflake
+}
+
+// NewLeasedSonyflake 使用租约创建索尼雪花生成器，租约需通过 AcquireWorkerLease(ctx, allocator, MaxSonyflakeMachineID, ttl) 获取
+func NewLeasedSonyflake(lease *WorkerLease) (*LeasedSonyflake, error) {
+	if lease.WorkerID() > MaxSonyflakeMachineID {
+		return nil, errors.New("sonyflake machine id out of range")
+	}
+
+	s, err := sonyflake.New(sonyflake.Settings{
+		MachineID: func() (uint16, error) {
+			return uint16(lease.WorkerID()), nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &LeasedSonyflake{lease: lease, sf: s}, nil
+}
+
+// NextID 生成ID，租约无效时返回 ErrWorkerLeaseLost
+func (s *LeasedSonyflake) NextID() (uint64, error) {
+	if err := s.lease.Err(); err != nil {
+		return 0, err
+	}
+	return s.sf.NextID()
+}
+
+// Lease 返回生成器使用的租约
+func (s *LeasedSonyflake) Lease() *WorkerLease {
+	return s.lease
+}
@@ -0,0 +1,136 @@
+package id
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// NewUUIDv7 生成 UUIDv7，前 48 位为毫秒时间戳，同一进程内同一毫秒生成的ID保持递增
+func NewUUIDv7() (uuid.UUID, error) {
+	return uuid.NewV7()
+}
+
+// GenerateUUIDv7 生成 UUIDv7，失败时 panic（与 uuid.New 一致），可直接用作 ent 字段的默认值
+func GenerateUUIDv7() uuid.UUID {
+	return uuid.Must(NewUUIDv7())
+}
+
+// ParseUUIDv7 解析 UUIDv7 字符串，版本不是 7 时返回错误
+func ParseUUIDv7(s string) (uuid.UUID, error) {
+	u, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if u.Version() != 7 {
+		return uuid.Nil, fmt.Errorf("invalid uuid version %d, expect 7", u.Version())
+	}
+	return u, nil
+}
+
+// UUIDv7Time 返回 UUIDv7 中的时间戳
+func UUIDv7Time(u uuid.UUID) (time.Time, error) {
+	if u.Version() != 7 {
+		return time.Time{}, fmt.Errorf("invalid uuid version %d, expect 7", u.Version())
+	}
+	sec, nsec := u.Time().UnixTime()
+	return time.Unix(sec, nsec).Truncate(time.Millisecond), nil
+}
+
+// BinaryUUID 以 16 字节二进制存储的 UUID，用于 MySQL 的 binary(16) 列，
+// 相比 char(36) 节省空间，配合 UUIDv7 可以保持索引有序。
+type BinaryUUID uuid.UUID
+
+// GenerateBinaryUUIDv7 生成二进制存储的 UUIDv7
+func GenerateBinaryUUIDv7() BinaryUUID {
+	return BinaryUUID(GenerateUUIDv7())
+}
+
+// UUID 转换为 uuid.UUID
+func (u BinaryUUID) UUID() uuid.UUID {
+	return uuid.UUID(u)
+}
+
+func (u BinaryUUID) String() string {
+	return uuid.UUID(u).String()
+}
+
+// Value 实现 driver.Valuer 接口，返回 16 字节的二进制
+func (u BinaryUUID) Value() (driver.Value, error) {
+	return u[:], nil
+}
+
+// Scan 实现 sql.Scanner 接口，支持 16 字节二进制和字符串
+func (u *BinaryUUID) Scan(src any) error {
+	return (*uuid.UUID)(u).Scan(src)
+}
+
+func (u BinaryUUID) MarshalText() ([]byte, error) {
+	return uuid.UUID(u).MarshalText()
+}
+
+func (u *BinaryUUID) UnmarshalText(data []byte) error {
+	return (*uuid.UUID)(u).UnmarshalText(data)
+}
+
+var (
+	ulidMu      sync.Mutex
+	ulidLastMs  uint64
+	ulidEntropy = ulid.Monotonic(rand.Reader, 0)
+)
+
+// NewULID 生成 ULID，同一进程内严格递增：同一毫秒内递增随机部分，时钟回拨时沿用上一次的时间戳
+func NewULID() (ulid.ULID, error) {
+	ulidMu.Lock()
+	defer ulidMu.Unlock()
+
+	ms := ulid.Now()
+	if ms < ulidLastMs {
+		ms = ulidLastMs
+	}
+
+	for {
+		id, err := ulid.New(ms, ulidEntropy)
+		if errors.Is(err, ulid.ErrMonotonicOverflow) {
+			// 同一毫秒内的随机部分已用尽，借用下一毫秒
+			ms++
+			continue
+		}
+		if err != nil {
+			return ulid.ULID{}, err
+		}
+
+		ulidLastMs = ms
+		return id, nil
+	}
+}
+
+// GenerateULID 生成 ULID，失败时 panic，可直接用作 ent 字段的默认值
+func GenerateULID() ulid.ULID {
+	id, err := NewULID()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// NewULIDString 生成 26 位的 ULID 字符串
+func NewULIDString() string {
+	return GenerateULID().String()
+}
+
+// ParseULID 解析 ULID 字符串
+func ParseULID(s string) (ulid.ULID, error) {
+	return ulid.ParseStrict(s)
+}
+
+// ULIDTime 返回 ULID 中的时间戳
+func ULIDTime(id ulid.ULID) time.Time {
+	return ulid.Time(id.Time())
+}
@@ -0,0 +1,73 @@
+package id
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUUIDv7(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+
+	prev := GenerateUUIDv7()
+	for i := 0; i < 10000; i++ {
+		u := GenerateUUIDv7()
+		assert.Equal(t, 1, bytes.Compare(u[:], prev[:]), "UUIDv7 应严格递增")
+		prev = u
+	}
+
+	parsed, err := ParseUUIDv7(prev.String())
+	assert.NoError(t, err)
+	assert.Equal(t, prev, parsed)
+
+	tm, err := UUIDv7Time(parsed)
+	assert.NoError(t, err)
+	assert.False(t, tm.Before(before))
+	assert.False(t, tm.After(time.Now()))
+
+	_, err = ParseUUIDv7(uuid.NewString())
+	assert.Error(t, err)
+}
+
+func TestBinaryUUID(t *testing.T) {
+	u := GenerateBinaryUUIDv7()
+
+	v, err := u.Value()
+	assert.NoError(t, err)
+	assert.Len(t, v, 16)
+
+	var scanned BinaryUUID
+	assert.NoError(t, scanned.Scan(v))
+	assert.Equal(t, u, scanned)
+
+	assert.NoError(t, scanned.Scan(u.String()))
+	assert.Equal(t, u, scanned)
+}
+
+func TestULID(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+
+	prev := GenerateULID()
+	for i := 0; i < 10000; i++ {
+		id := GenerateULID()
+		assert.Equal(t, 1, id.Compare(prev), "ULID 应严格递增")
+		prev = id
+	}
+
+	s := prev.String()
+	assert.Len(t, s, 26)
+
+	parsed, err := ParseULID(s)
+	assert.NoError(t, err)
+	assert.Equal(t, prev, parsed)
+
+	tm := ULIDTime(parsed)
+	assert.False(t, tm.Before(before))
+	assert.False(t, tm.After(time.Now()))
+
+	_, err = ParseULID("not-a-ulid")
+	assert.Error(t, err)
+}
@@ -0,0 +1,311 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	MaxSnowflakeWorkerID  int64 = 1<<10 - 1 // 雪花算法的最大工作节点ID
+	MaxSonyflakeMachineID int64 = 1<<16 - 1 // 索尼雪花算法的最大机器ID
+
+	DefaultWorkerLeaseTTL = 30 * time.Second // 默认的租约有效期
+)
+
+var (
+	ErrNoWorkerIDAvailable = errors.New("no worker id available")
+	ErrWorkerLeaseLost     = errors.New("worker id lease lost")
+)
+
+// WorkerIDAllocator 工作节点ID分配器
+type WorkerIDAllocator interface {
+	// Acquire 在 [0, maxID] 范围内分配一个工作节点ID，租约有效期为 ttl
+	Acquire(ctx context.Context, maxID int64, ttl time.Duration) (int64, error)
+	// Renew 续约，租约已被他人占用时返回 ErrWorkerLeaseLost
+	Renew(ctx context.Context, workerID int64, ttl time.Duration) error
+	// Release 释放工作节点ID
+	Release(ctx context.Context, workerID int64) error
+}
+
+// WorkerLease 工作节点ID的租约，后台按 ttl/3 的间隔心跳续约。
+// 租约过期或被他人占用后，Valid 返回 false，基于该租约的生成器停止生成ID。
+type WorkerLease struct {
+	allocator WorkerIDAllocator
+	workerID  int64
+	ttl       time.Duration
+
+	mu       sync.RWMutex
+	expireAt time.Time
+	err      error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// AcquireWorkerLease 通过分配器获取工作节点ID并开始心跳续约，ttl 小于等于 0 时使用 DefaultWorkerLeaseTTL
+func AcquireWorkerLease(ctx context.Context, allocator WorkerIDAllocator, maxID int64, ttl time.Duration) (*WorkerLease, error) {
+	if ttl <= 0 {
+		ttl = DefaultWorkerLeaseTTL
+	}
+
+	workerID, err := allocator.Acquire(ctx, maxID, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if workerID < 0 || workerID > maxID {
+		_ = allocator.Release(ctx, workerID)
+		return nil, fmt.Errorf("worker id %d out of range [0, %d]", workerID, maxID)
+	}
+
+	heartbeatCtx, cancel := context.WithCancel(context.Background())
+	l := &WorkerLease{
+		allocator: allocator,
+		workerID:  workerID,
+		ttl:       ttl,
+		expireAt:  time.Now().Add(ttl),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	go l.heartbeat(heartbeatCtx)
+
+	return l, nil
+}
+
+// WorkerID 返回租约持有的工作节点ID
+func (l *WorkerLease) WorkerID() int64 {
+	return l.workerID
+}
+
+// Err 租约有效时返回 nil，否则返回 ErrWorkerLeaseLost
+func (l *WorkerLease) Err() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.err != nil {
+		return l.err
+	}
+	if time.Now().After(l.expireAt) {
+		return ErrWorkerLeaseLost
+	}
+	return nil
+}
+
+// Valid 租约是否有效
+func (l *WorkerLease) Valid() bool {
+	return l.Err() == nil
+}
+
+// Done 租约丢失或释放后关闭
+func (l *WorkerLease) Done() <-chan struct{} {
+	return l.done
+}
+
+// Release 停止心跳并释放工作节点ID
+func (l *WorkerLease) Release(ctx context.Context) error {
+	l.cancel()
+	<-l.done
+
+	l.mu.Lock()
+	alreadyLost := l.err != nil
+	if !alreadyLost {
+		l.err = ErrWorkerLeaseLost
+	}
+	l.mu.Unlock()
+
+	if alreadyLost {
+		return nil
+	}
+	return l.allocator.Release(ctx, l.workerID)
+}
+
+func (l *WorkerLease) heartbeat(ctx context.Context) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewCtx, cancel := context.WithTimeout(ctx, l.ttl/3)
+		deadline := time.Now().Add(l.ttl)
+		err := l.allocator.Renew(renewCtx, l.workerID, l.ttl)
+		cancel()
+
+		if ctx.Err() != nil {
+			return
+		}
+		if lost := l.renewed(deadline, err); lost {
+			return
+		}
+	}
+}
+
+// renewed 记录续约结果，返回租约是否已丢失
+func (l *WorkerLease) renewed(deadline time.Time, err error) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case err == nil:
+		l.expireAt = deadline
+		return false
+	case errors.Is(err, ErrWorkerLeaseLost):
+		l.err = err
+		return true
+	case time.Now().After(l.expireAt):
+		// 续约一直失败直到租约过期，此时其他节点可能已经拿到该ID
+		l.err = fmt.Errorf("%w: %v", ErrWorkerLeaseLost, err)
+		return true
+	default:
+		return false
+	}
+}
+
+var podOrdinalRegexp = regexp.MustCompile(`-(\d+)$`)
+
+// HostnameAllocator 从主机名末尾的序号分配工作节点ID，适用于 Kubernetes StatefulSet（例如 order-service-3）。
+// 序号由编排系统保证唯一，因此无需续约。
+type HostnameAllocator struct {
+	Hostname string // 主机名，为空时使用 os.Hostname()
+	Offset   int64  // 序号偏移量，用于多个 StatefulSet 共用ID空间
+}
+
+// Acquire 解析主机名末尾的序号
+func (a HostnameAllocator) Acquire(_ context.Context, maxID int64, _ time.Duration) (int64, error) {
+	hostname := a.Hostname
+	if hostname == "" {
+		var err error
+		if hostname, err = os.Hostname(); err != nil {
+			return 0, err
+		}
+	}
+
+	workerID, err := ParsePodOrdinal(hostname)
+	if err != nil {
+		return 0, err
+	}
+
+	workerID += a.Offset
+	if workerID < 0 || workerID > maxID {
+		return 0, fmt.Errorf("%w: ordinal %d of %q out of range [0, %d]", ErrNoWorkerIDAvailable, workerID, hostname, maxID)
+	}
+	return workerID, nil
+}
+
+// Renew 序号不会变化，无需续约
+func (a HostnameAllocator) Renew(context.Context, int64, time.Duration) error {
+	return nil
+}
+
+// Release 无需释放
+func (a HostnameAllocator) Release(context.Context, int64) error {
+	return nil
+}
+
+// ParsePodOrdinal 解析主机名末尾的序号，例如 order-service-3 返回 3
+func ParsePodOrdinal(hostname string) (int64, error) {
+	m := podOrdinalRegexp.FindStringSubmatch(hostname)
+	if m == nil {
+		return 0, fmt.Errorf("hostname %q has no ordinal suffix", hostname)
+	}
+	return strconv.ParseInt(m[1], 10, 64)
+}
+
+// FileLockAllocator 通过文件锁分配工作节点ID，适用于同一主机上的多个进程。
+// 每个ID对应目录下的一个锁文件，进程退出时锁自动释放。
+type FileLockAllocator struct {
+	Dir string // 锁文件目录，为空时使用 os.TempDir()
+
+	mu    sync.Mutex
+	files map[int64]*os.File
+}
+
+// NewFileLockAllocator 创建文件锁分配器
+func NewFileLockAllocator(dir string) *FileLockAllocator {
+	return &FileLockAllocator{Dir: dir}
+}
+
+func (a *FileLockAllocator) lockPath(workerID int64) string {
+	dir := a.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return fmt.Sprintf("%s%cworker-id-%d.lock", dir, os.PathSeparator, workerID)
+}
+
+// Acquire 依次尝试锁定 [0, maxID] 对应的锁文件
+func (a *FileLockAllocator) Acquire(ctx context.Context, maxID int64, _ time.Duration) (int64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.files == nil {
+		a.files = make(map[int64]*os.File)
+	}
+
+	for workerID := int64(0); workerID <= maxID; workerID++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if _, ok := a.files[workerID]; ok {
+			continue
+		}
+
+		f, err := os.OpenFile(a.lockPath(workerID), os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return 0, err
+		}
+		if err = tryLockFile(f); err != nil {
+			_ = f.Close()
+			if errors.Is(err, errFileLocked) {
+				continue
+			}
+			return 0, err
+		}
+
+		a.files[workerID] = f
+		return workerID, nil
+	}
+
+	return 0, ErrNoWorkerIDAvailable
+}
+
+// Renew 文件锁由本进程持有即有效
+func (a *FileLockAllocator) Renew(_ context.Context, workerID int64, _ time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.files[workerID]; !ok {
+		return ErrWorkerLeaseLost
+	}
+	return nil
+}
+
+// Release 解锁并关闭锁文件
+func (a *FileLockAllocator) Release(_ context.Context, workerID int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, ok := a.files[workerID]
+	if !ok {
+		return nil
+	}
+	delete(a.files, workerID)
+
+	if err := unlockFile(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package id
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+var errFileLocked = errors.New("file is locked by another process")
+
+func tryLockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errFileLocked
+	}
+	return err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
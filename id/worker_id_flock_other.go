@@ -0,0 +1,18 @@
+//go:build !unix
+
+package id
+
+import (
+	"errors"
+	"os"
+)
+
+var errFileLocked = errors.New("file is locked by another process")
+
+func tryLockFile(*os.File) error {
+	return errors.New("file lock is not supported on this platform")
+}
+
+func unlockFile(*os.File) error {
+	return nil
+}
@@ -0,0 +1,145 @@
+package id
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePodOrdinal(t *testing.T) {
+	ordinal, err := ParsePodOrdinal("order-service-12")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), ordinal)
+
+	_, err = ParsePodOrdinal("order-service")
+	assert.Error(t, err)
+
+	workerID, err := HostnameAllocator{Hostname: "order-service-3", Offset: 100}.Acquire(context.Background(), MaxSnowflakeWorkerID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(103), workerID)
+
+	_, err = HostnameAllocator{Hostname: "order-service-3"}.Acquire(context.Background(), 2, 0)
+	assert.ErrorIs(t, err, ErrNoWorkerIDAvailable)
+}
+
+func TestFileLockAllocator(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	a1 := NewFileLockAllocator(dir)
+	a2 := NewFileLockAllocator(dir)
+
+	id1, err := a1.Acquire(ctx, 1, 0)
+	assert.NoError(t, err)
+	id2, err := a2.Acquire(ctx, 1, 0)
+	assert.NoError(t, err)
+	assert.NotEqual(t, id1, id2)
+
+	_, err = a2.Acquire(ctx, 1, 0)
+	assert.ErrorIs(t, err, ErrNoWorkerIDAvailable)
+
+	assert.NoError(t, a1.Release(ctx, id1))
+	assert.ErrorIs(t, a1.Renew(ctx, id1, 0), ErrWorkerLeaseLost)
+
+	id3, err := a2.Acquire(ctx, 1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, id1, id3)
+}
+
+type fakeAllocator struct {
+	renewErr atomic.Value
+	renewals atomic.Int32
+	released atomic.Bool
+}
+
+func (a *fakeAllocator) Acquire(context.Context, int64, time.Duration) (int64, error) {
+	return 7, nil
+}
+
+func (a *fakeAllocator) Renew(context.Context, int64, time.Duration) error {
+	a.renewals.Add(1)
+	if err, ok := a.renewErr.Load().(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (a *fakeAllocator) Release(context.Context, int64) error {
+	a.released.Store(true)
+	return nil
+}
+
+func TestWorkerLease(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Renew", func(t *testing.T) {
+		allocator := &fakeAllocator{}
+		lease, err := AcquireWorkerLease(ctx, allocator, MaxSnowflakeWorkerID, 30*time.Millisecond)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), lease.WorkerID())
+
+		time.Sleep(100 * time.Millisecond)
+		assert.True(t, lease.Valid())
+		assert.Greater(t, allocator.renewals.Load(), int32(1))
+
+		node, err := NewLeasedSnowflakeNode(lease)
+		assert.NoError(t, err)
+		id, err := node.NextID()
+		assert.NoError(t, err)
+		assert.NotZero(t, id)
+
+		assert.NoError(t, lease.Release(ctx))
+		assert.True(t, allocator.released.Load())
+		_, err = node.NextID()
+		assert.ErrorIs(t, err, ErrWorkerLeaseLost)
+	})
+
+	t.Run("Lost", func(t *testing.T) {
+		allocator := &fakeAllocator{}
+		allocator.renewErr.Store(ErrWorkerLeaseLost)
+		lease, err := AcquireWorkerLease(ctx, allocator, MaxSnowflakeWorkerID, 30*time.Millisecond)
+		assert.NoError(t, err)
+
+		select {
+		case <-lease.Done():
+		case <-time.After(time.Second):
+			t.Fatal("lease not lost")
+		}
+		assert.ErrorIs(t, lease.Err(), ErrWorkerLeaseLost)
+
+		node, err := NewLeasedSnowflakeNode(lease)
+		assert.NoError(t, err)
+		_, err = node.NextID()
+		assert.ErrorIs(t, err, ErrWorkerLeaseLost)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		allocator := &fakeAllocator{}
+		allocator.renewErr.Store(errors.New("db unavailable"))
+		lease, err := AcquireWorkerLease(ctx, allocator, MaxSnowflakeWorkerID, 30*time.Millisecond)
+		assert.NoError(t, err)
+
+		select {
+		case <-lease.Done():
+		case <-time.After(time.Second):
+			t.Fatal("lease not expired")
+		}
+		assert.ErrorIs(t, lease.Err(), ErrWorkerLeaseLost)
+	})
+
+	t.Run("Sonyflake", func(t *testing.T) {
+		lease, err := AcquireWorkerLease(ctx, &fakeAllocator{}, MaxSonyflakeMachineID, time.Minute)
+		assert.NoError(t, err)
+		defer lease.Release(ctx)
+
+		s, err := NewLeasedSonyflake(lease)
+		assert.NoError(t, err)
+		id, err := s.NextID()
+		assert.NoError(t, err)
+		assert.NotZero(t, id)
+	})
+}